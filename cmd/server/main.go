@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,7 +16,7 @@ import (
 func main() {
 	log := config.NewLogrus()
 	viperConfig := config.NewViper(log)
-	app := config.NewGin(viperConfig)
+	app := config.NewGin(viperConfig, log)
 
 	db, err := config.NewGorm(viperConfig, log)
 	if err != nil {
@@ -36,19 +37,29 @@ func main() {
 	asynqClient := config.NewAsynqClient(asynqConfig, log)
 	defer asynqClient.Close()
 
+	redisClient := config.NewRedis(viperConfig, log)
+	defer redisClient.Close()
+
 	config.Bootstrap(&config.BootstrapConfig{
 		Log:         log,
 		App:         app,
 		Config:      viperConfig,
 		DB:          db,
 		AsynqClient: asynqClient,
+		Redis:       redisClient,
 	})
 
+	// cancelled on shutdown so long lived streams end instead of holding it up
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
 	port := viperConfig.GetInt("app.port")
 	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: app,
+		Addr:        fmt.Sprintf(":%d", port),
+		Handler:     app,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
 	}
+	srv.RegisterOnShutdown(cancelBase)
 
 	go func() {
 		log.Infof("Server starting on port %d", port)
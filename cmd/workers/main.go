@@ -4,9 +4,12 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/febry3/gamingin/internal/config"
+	"github.com/febry3/gamingin/internal/infra/mailer"
 	"github.com/febry3/gamingin/internal/infra/payment"
+	"github.com/febry3/gamingin/internal/infra/realtime"
 	"github.com/febry3/gamingin/internal/repository/pg"
 	"github.com/febry3/gamingin/internal/usecase"
 	"github.com/febry3/gamingin/internal/worker"
@@ -17,7 +20,11 @@ import (
 func main() {
 	log := config.NewLogrus()
 	viperConfig := config.NewViper(log)
-	email := config.NewEmail(viperConfig)
+	emailSender := config.NewMailer(viperConfig, log)
+	emailRenderer, err := mailer.NewRenderer()
+	if err != nil {
+		log.Fatalf("failed to load email templates: %v", err)
+	}
 
 	db, err := config.NewGorm(viperConfig, log)
 	if err != nil {
@@ -31,13 +38,20 @@ func main() {
 	buyerGroupSessionRepo := pg.NewBuyerGroupBuySessionRepositoryPg(db)
 	addressRepo := pg.NewAddressRepositoryPg(db)
 	buyerGroupMemberRepo := pg.NewBuyerGroupMemberRepositoryPg(db)
+	groupBuyScheduleRepo := pg.NewGroupBuyScheduleRepositoryPg(db)
+	groupBuyReminderRepo := pg.NewGroupBuyReminderRepositoryPg(db)
 	txManager := pg.NewTxManager(db)
 
 	orderRepo := pg.NewOrderRepositoryPg(db)
 	paymentRepo := pg.NewPaymentRepositoryPg(db)
 	shippingRepo := pg.NewOrderShippingDetailRepositoryPg(db)
+	discrepancyRepo := pg.NewPaymentDiscrepancyRepositoryPg(db)
+	paymentEventRepo := pg.NewPaymentEventRepositoryPg(db)
 	stockRepo := pg.NewProductVariantStockRepositoryPg(db)
 	userWalletRepo := pg.NewUserWalletRepositoryPg(db)
+	userRepo := pg.NewUserRepositoryPg(db, log)
+	notificationRepo := pg.NewNotificationRepositoryPg(db)
+	tokenRepo := pg.NewTokenRepositoryPg(db, log)
 
 	asynqConfig := config.NewAsynqConfig(viperConfig)
 	asynqClient := config.NewAsynqClient(asynqConfig, log)
@@ -47,19 +61,11 @@ func main() {
 	serverKey := viperConfig.GetString("midtrans.server_key")
 	paymentGateway := payment.NewMidtransGateway(*midtransCoreClient, serverKey, log)
 
-	userWalletUsecase := usecase.NewUserWalletUsecase(userWalletRepo)
-	groupBuyUsecase := usecase.NewGroupBuyUsecase(
-		addressRepo,
-		groupBuySessionRepo,
-		groupBuyTierRepo,
-		productRepo,
-		productVariantRepo,
-		buyerGroupSessionRepo,
-		buyerGroupMemberRepo,
-		txManager,
-		log,
-		nil,
-	)
+	redisClient := config.NewRedis(viperConfig, log)
+	defer redisClient.Close()
+
+	notificationUsecase := usecase.NewNotificationUsecase(userRepo, notificationRepo, asynqClient, log)
+	groupBuyEventUsecase := usecase.NewGroupBuyEventUsecase(realtime.NewRedisBroker(redisClient), log)
 
 	orderUsecase := usecase.NewOrderUsecase(
 		orderRepo,
@@ -69,32 +75,123 @@ func main() {
 		productVariantRepo,
 		stockRepo,
 		buyerGroupSessionRepo,
+		buyerGroupMemberRepo,
+		userRepo,
+		discrepancyRepo,
+		paymentEventRepo,
+		pg.NewOrderAdjustmentRepositoryPg(db),
+		groupBuyTierRepo,
+		userWalletRepo,
+		notificationUsecase,
+		groupBuyEventUsecase,
 		paymentGateway,
 		txManager,
 		asynqClient,
 		log,
 	)
 
-	groupBuyHandler := worker.NewGroupBuySessionHandler(groupBuyUsecase, asynqClient, email, log)
-	orderHandler := worker.NewOrderHandler(orderUsecase, groupBuyUsecase, userWalletUsecase, log)
+	groupBuyUsecase := usecase.NewGroupBuyUsecase(
+		addressRepo,
+		groupBuySessionRepo,
+		groupBuyTierRepo,
+		productRepo,
+		productVariantRepo,
+		buyerGroupSessionRepo,
+		buyerGroupMemberRepo,
+		orderRepo,
+		stockRepo,
+		groupBuyScheduleRepo,
+		groupBuyReminderRepo,
+		txManager,
+		log,
+		asynqClient,
+		notificationUsecase,
+		groupBuyEventUsecase,
+		orderUsecase,
+		config.NewInviteSigner(viperConfig, log),
+	)
+
+	groupBuyHandler := worker.NewGroupBuySessionHandler(groupBuyUsecase, orderUsecase, log)
+	emailHandler := worker.NewEmailHandler(emailSender, emailRenderer, log)
+	orderHandler := worker.NewOrderHandler(orderUsecase, log)
+
+	refreshTTL, err := time.ParseDuration(viperConfig.GetString("jwt.refresh_ttl"))
+	if err != nil {
+		log.Fatalf("unable to parse refresh_ttl: %v", err.Error())
+	}
+	tokenHandler := worker.NewTokenHandler(usecase.NewTokenUsecase(tokenRepo, refreshTTL, log), log)
 
 	srv := config.NewAsynqServer(asynqConfig, log)
 	mux := asynq.NewServeMux()
 
-	mux.HandleFunc(tasks.TypeEmailDelivery, tasks.HandleEmailDeliveryTask)
-	mux.HandleFunc(tasks.TypeWelcomeEmail, tasks.HandleWelcomeEmailTask)
+	mux.HandleFunc(tasks.TypeEmailDelivery, emailHandler.HandleEmailDelivery)
 
 	mux.HandleFunc(tasks.TypeGroupBuySessionEnd, groupBuyHandler.HandleSessionEnd)
-	mux.HandleFunc(tasks.TypeGroupBuySessionEndMail, groupBuyHandler.HandleSessionEndMail)
 	mux.HandleFunc(tasks.TypeBuyerGroupBuySessionEnd, groupBuyHandler.HandleBuyerSessionEnd)
+	mux.HandleFunc(tasks.TypeGroupBuyMilestone, groupBuyHandler.HandleMilestone)
+	mux.HandleFunc(tasks.TypeGroupBuyActivation, groupBuyHandler.HandleActivation)
+	mux.HandleFunc(tasks.TypeGroupBuyScheduleSweep, groupBuyHandler.HandleScheduleSweep)
 
 	mux.HandleFunc(tasks.TypeOrderExpiration, orderHandler.HandleOrderExpiration)
+	mux.HandleFunc(tasks.TypePaymentEvent, orderHandler.HandlePaymentEvent)
+	mux.HandleFunc(tasks.TypePaymentReconciliation, orderHandler.HandlePaymentReconciliation)
+
+	mux.HandleFunc(tasks.TypeRefreshTokenPurge, tokenHandler.HandleRefreshTokenPurge)
+
+	scheduler := config.NewAsynqScheduler(asynqConfig, log)
+
+	reconcileInterval := viperConfig.GetString("asynq.reconcile_interval")
+	if reconcileInterval == "" {
+		reconcileInterval = "@every 5m"
+	}
+
+	reconcileTask, err := tasks.NewPaymentReconciliationTask()
+	if err != nil {
+		log.Fatalf("failed to create payment reconciliation task: %v", err)
+	}
+
+	if _, err := scheduler.Register(reconcileInterval, reconcileTask, asynq.Queue("default"), asynq.MaxRetry(0), asynq.Unique(time.Minute)); err != nil {
+		log.Fatalf("failed to register payment reconciliation: %v", err)
+	}
+
+	scheduleSweepInterval := viperConfig.GetString("asynq.schedule_sweep_interval")
+	if scheduleSweepInterval == "" {
+		scheduleSweepInterval = "@every 5m"
+	}
+
+	scheduleSweepTask, err := tasks.NewGroupBuyScheduleSweepTask()
+	if err != nil {
+		log.Fatalf("failed to create group buy schedule sweep task: %v", err)
+	}
+
+	if _, err := scheduler.Register(scheduleSweepInterval, scheduleSweepTask, asynq.Queue("default"), asynq.MaxRetry(0), asynq.Unique(time.Minute)); err != nil {
+		log.Fatalf("failed to register group buy schedule sweep: %v", err)
+	}
+
+	tokenPurgeInterval := viperConfig.GetString("asynq.token_purge_interval")
+	if tokenPurgeInterval == "" {
+		tokenPurgeInterval = "@every 1h"
+	}
+
+	tokenPurgeTask, err := tasks.NewRefreshTokenPurgeTask()
+	if err != nil {
+		log.Fatalf("failed to create refresh token purge task: %v", err)
+	}
+
+	if _, err := scheduler.Register(tokenPurgeInterval, tokenPurgeTask, asynq.Queue("default"), asynq.MaxRetry(0), asynq.Unique(time.Minute)); err != nil {
+		log.Fatalf("failed to register refresh token purge: %v", err)
+	}
+
+	if err := scheduler.Start(); err != nil {
+		log.Fatalf("Could not start Asynq scheduler: %v", err)
+	}
 
 	go func() {
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
 		log.Info("Shutting down worker...")
+		scheduler.Shutdown()
 		srv.Shutdown()
 	}()
 
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_payment_discrepancies_unresolved;
+DROP INDEX IF EXISTS idx_payment_discrepancies_order_id;
+DROP TABLE IF EXISTS payment_discrepancies;
@@ -0,0 +1,20 @@
+-- Migration: Create payment discrepancies table for reconciliation reports
+-- Created: 2026-10-19
+
+CREATE TABLE IF NOT EXISTS payment_discrepancies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_status TEXT NOT NULL,
+    local_status TEXT NOT NULL,
+    gateway_status TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+
+    -- One report per payment and gateway status, the reconciler runs repeatedly
+    CONSTRAINT ux_payment_discrepancies_payment_status UNIQUE (payment_id, gateway_status)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_discrepancies_order_id ON payment_discrepancies(order_id);
+CREATE INDEX IF NOT EXISTS idx_payment_discrepancies_unresolved ON payment_discrepancies(created_at) WHERE resolved_at IS NULL;
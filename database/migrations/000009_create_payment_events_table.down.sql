@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_payment_events_status;
+DROP INDEX IF EXISTS idx_payment_events_order_number;
+DROP TABLE IF EXISTS payment_events;
@@ -0,0 +1,23 @@
+-- Migration: Create payment events table for durable webhook processing
+-- Created: 2026-10-19
+
+CREATE TABLE IF NOT EXISTS payment_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id TEXT NOT NULL,
+    transaction_status TEXT NOT NULL,
+    order_number TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'received',
+    error TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    processed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    -- Midtrans retries notifications, each transaction status is stored once
+    CONSTRAINT ux_payment_events_transaction_status UNIQUE (transaction_id, transaction_status),
+    CONSTRAINT chk_payment_events_status CHECK (status IN ('received', 'processed', 'ignored', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_events_order_number ON payment_events(order_number);
+CREATE INDEX IF NOT EXISTS idx_payment_events_status ON payment_events(status);
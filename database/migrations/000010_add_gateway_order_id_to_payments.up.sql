@@ -0,0 +1,12 @@
+-- Migration: Track the order id sent to the payment gateway per charge
+-- Created: 2026-10-19
+
+-- A regenerated VA needs a fresh gateway order id, Midtrans rejects reusing one
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS gateway_order_id TEXT;
+
+UPDATE payments p
+SET gateway_order_id = o.order_number
+FROM orders o
+WHERE p.order_id = o.id AND p.gateway_order_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_payments_gateway_order_id ON payments(gateway_order_id);
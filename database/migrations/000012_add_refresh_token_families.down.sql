@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS ux_refresh_tokens_user_device;
+DELETE FROM refresh_tokens WHERE is_revoked = true;
+ALTER TABLE refresh_tokens ADD CONSTRAINT ux_tokens_user_device UNIQUE (user_id, device_info);
+
+DROP INDEX IF EXISTS ix_refresh_tokens_family_id;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS family_id;
@@ -0,0 +1,16 @@
+-- Migration: Rotate refresh tokens within a family
+-- Created: 2026-10-19
+
+-- Every refresh rotates the token, rotated tokens stay as revoked rows so a
+-- replayed one can be recognised and its whole family revoked
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id TEXT;
+UPDATE refresh_tokens SET family_id = token_id::text WHERE family_id IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS ix_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+-- Only the active token of a device has to be unique
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS ux_tokens_user_device;
+CREATE UNIQUE INDEX IF NOT EXISTS ux_refresh_tokens_user_device
+    ON refresh_tokens (user_id, device_info)
+    WHERE is_revoked = false;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS login_histories;
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE users DROP COLUMN IF EXISTS lockout_count;
+ALTER TABLE users DROP COLUMN IF EXISTS failed_login_attempts;
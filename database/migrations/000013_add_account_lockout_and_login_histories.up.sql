@@ -0,0 +1,23 @@
+-- Migration: Account lockout and login history
+-- Created: 2026-10-19
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS lockout_count INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS login_histories (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255),
+    method VARCHAR(20) NOT NULL DEFAULT 'email',
+    ip_address VARCHAR(45),
+    device_info VARCHAR(255),
+    success BOOLEAN NOT NULL,
+    failure_reason VARCHAR(50),
+    new_device BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_histories_user_created ON login_histories(user_id, created_at);
+-- failed attempts per IP are counted over a recent window
+CREATE INDEX IF NOT EXISTS idx_login_histories_ip_created ON login_histories(ip_address, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_adjustments;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_amount;
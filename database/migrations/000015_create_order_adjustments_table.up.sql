@@ -0,0 +1,16 @@
+-- Migration: Group buy tier discounts applied at order time
+-- Created: 2026-10-19
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS order_adjustments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    description TEXT NOT NULL,
+    amount DECIMAL(15, 2) NOT NULL,
+    source_type TEXT NOT NULL CHECK (source_type IN ('coupon', 'group_buy')),
+    source_id UUID NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_adjustments_order_id ON order_adjustments(order_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_buyer_group_members_referred_by_user_id;
+ALTER TABLE buyer_group_members DROP COLUMN IF EXISTS referred_by_user_id;
@@ -0,0 +1,6 @@
+-- Migration: Invite link attribution for buyer session members
+-- Created: 2026-10-19
+
+ALTER TABLE buyer_group_members ADD COLUMN IF NOT EXISTS referred_by_user_id BIGINT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_buyer_group_members_referred_by_user_id ON buyer_group_members(referred_by_user_id);
@@ -0,0 +1 @@
+ALTER TABLE group_buy_sessions DROP COLUMN IF EXISTS max_quantity_per_member;
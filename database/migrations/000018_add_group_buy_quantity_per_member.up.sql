@@ -0,0 +1,4 @@
+-- Migration: Units per member in group buys
+-- Created: 2026-10-19
+
+ALTER TABLE group_buy_sessions ADD COLUMN IF NOT EXISTS max_quantity_per_member INT NOT NULL DEFAULT 1;
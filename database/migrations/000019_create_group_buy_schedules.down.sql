@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS group_buy_reminders;
+DROP INDEX IF EXISTS idx_group_buy_sessions_schedule_starts;
+DROP INDEX IF EXISTS idx_group_buy_sessions_schedule_id;
+ALTER TABLE group_buy_sessions DROP COLUMN IF EXISTS schedule_id;
+ALTER TABLE group_buy_sessions DROP COLUMN IF EXISTS starts_at;
+DROP TABLE IF EXISTS group_buy_schedules;
+ALTER TABLE group_buy_sessions DROP CONSTRAINT IF EXISTS chk_group_buy_sessions_status;
+ALTER TABLE group_buy_sessions ADD CONSTRAINT chk_group_buy_sessions_status CHECK (status IN ('active', 'completed', 'cancelled'));
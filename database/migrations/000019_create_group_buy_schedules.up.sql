@@ -0,0 +1,39 @@
+-- Migration: Scheduled and recurring group buy campaigns
+-- Created: 2026-10-19
+
+ALTER TABLE group_buy_sessions DROP CONSTRAINT IF EXISTS chk_group_buy_sessions_status;
+ALTER TABLE group_buy_sessions ADD CONSTRAINT chk_group_buy_sessions_status CHECK (status IN ('upcoming', 'active', 'completed', 'cancelled'));
+
+CREATE TABLE IF NOT EXISTS group_buy_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    seller_id BIGINT NOT NULL REFERENCES sellers(id) ON DELETE CASCADE,
+    product_variant_id UUID NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    min_participants INT NOT NULL,
+    max_participants INT NOT NULL,
+    max_quantity INT NOT NULL,
+    max_quantity_per_member INT NOT NULL DEFAULT 1,
+    tiers JSONB NOT NULL,
+    recurrence TEXT NOT NULL,
+    timezone TEXT NOT NULL,
+    duration_minutes INT NOT NULL CHECK (duration_minutes > 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_buy_schedules_seller_id ON group_buy_schedules(seller_id);
+
+ALTER TABLE group_buy_sessions ADD COLUMN IF NOT EXISTS starts_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE group_buy_sessions ADD COLUMN IF NOT EXISTS schedule_id UUID REFERENCES group_buy_schedules(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_group_buy_sessions_schedule_id ON group_buy_sessions(schedule_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_buy_sessions_schedule_starts ON group_buy_sessions(schedule_id, starts_at) WHERE schedule_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS group_buy_reminders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_buy_session_id UUID NOT NULL REFERENCES group_buy_sessions(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_buy_reminders_session_user ON group_buy_reminders(group_buy_session_id, user_id);
@@ -0,0 +1,2 @@
+-- the backfilled rows cannot be told apart from members marked paid on payment
+SELECT 1;
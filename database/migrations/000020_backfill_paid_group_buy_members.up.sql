@@ -0,0 +1,12 @@
+-- Migration: Mark group buy members with a paid order as paid
+-- Created: 2026-10-19
+
+UPDATE buyer_group_members m
+SET status = 'paid'
+WHERE m.status = 'joined'
+  AND EXISTS (
+    SELECT 1 FROM orders o
+    WHERE o.buyer_group_session_id = m.session_id
+      AND o.user_id = m.user_id
+      AND o.status IN ('paid', 'processing', 'shipped', 'delivered')
+  );
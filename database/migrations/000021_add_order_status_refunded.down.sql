@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE orders ADD CONSTRAINT orders_status_check CHECK (status IN (
+    'pending_payment', 'paid', 'processing', 'shipped', 'delivered', 'cancelled', 'expired'
+));
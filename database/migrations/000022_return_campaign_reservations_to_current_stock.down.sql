@@ -0,0 +1,11 @@
+UPDATE product_variant_stocks s
+SET current_stock = s.current_stock - c.quantity,
+    version = s.version + 1,
+    last_updated = NOW()
+FROM (
+    SELECT product_variant_id, SUM(max_quantity) AS quantity
+    FROM group_buy_sessions
+    WHERE status = 'active'
+    GROUP BY product_variant_id
+) c
+WHERE s.product_variant_id = c.product_variant_id;
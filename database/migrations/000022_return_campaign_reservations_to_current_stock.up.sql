@@ -0,0 +1,18 @@
+-- Migration: Give back the campaign quantity taken off current stock
+-- Created: 2026-10-19
+
+-- Campaigns used to take max_quantity off current_stock as well as reserving it. A
+-- reservation now only counts in reserved_stock and a payment takes its units off
+-- current_stock, so campaigns still active keep their reservation and get the
+-- quantity back. Paid units already came off current_stock on payment and stay off.
+UPDATE product_variant_stocks s
+SET current_stock = s.current_stock + c.quantity,
+    version = s.version + 1,
+    last_updated = NOW()
+FROM (
+    SELECT product_variant_id, SUM(max_quantity) AS quantity
+    FROM group_buy_sessions
+    WHERE status = 'active'
+    GROUP BY product_variant_id
+) c
+WHERE s.product_variant_id = c.product_variant_id;
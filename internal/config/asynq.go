@@ -58,6 +58,20 @@ func NewAsynqServer(config *AsynqConfig, log *logrus.Logger) *asynq.Server {
 	return srv
 }
 
+func NewAsynqScheduler(config *AsynqConfig, log *logrus.Logger) *asynq.Scheduler {
+	scheduler := asynq.NewScheduler(
+		config.GetRedisClientOpt(),
+		&asynq.SchedulerOpts{
+			Logger: log,
+			EnqueueErrorHandler: func(task *asynq.Task, opts []asynq.Option, err error) {
+				log.Errorf("Error enqueuing scheduled task %s: %v", task.Type(), err)
+			},
+		},
+	)
+	log.Info("Asynq scheduler initialized")
+	return scheduler
+}
+
 func NewAsynqInspector(config *AsynqConfig) *asynq.Inspector {
 	return asynq.NewInspector(config.GetRedisClientOpt())
 }
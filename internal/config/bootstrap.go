@@ -6,11 +6,15 @@ import (
 	"github.com/febry3/gamingin/internal/delivery/http"
 	"github.com/febry3/gamingin/internal/helpers"
 	"github.com/febry3/gamingin/internal/infra/payment"
+	"github.com/febry3/gamingin/internal/infra/ratelimit"
+	"github.com/febry3/gamingin/internal/infra/realtime"
+	"github.com/febry3/gamingin/internal/infra/session"
 	"github.com/febry3/gamingin/internal/infra/storage"
 	"github.com/febry3/gamingin/internal/repository/pg"
 	"github.com/febry3/gamingin/internal/usecase"
 	"github.com/gin-gonic/gin"
 	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"gorm.io/gorm"
@@ -22,6 +26,7 @@ type BootstrapConfig struct {
 	Log         *logrus.Logger
 	Config      *viper.Viper
 	AsynqClient *asynq.Client
+	Redis       *redis.Client
 }
 
 func Bootstrap(config *BootstrapConfig) {
@@ -34,16 +39,22 @@ func Bootstrap(config *BootstrapConfig) {
 		config.Log.Fatalf("unable to parse refresh_ttl: %v", err.Error())
 	}
 
-	jwt := helpers.NewJwtService(helpers.JwtConfig{
-		Secret:     config.Config.GetString("jwt.secret_key"),
+	jwtKeys, activeKid := NewJwtKeys(config.Config, config.Log)
+	jwt, err := helpers.NewJwtService(helpers.JwtConfig{
+		Keys:       jwtKeys,
+		ActiveKID:  activeKid,
 		AccessTTL:  accessTtl,
 		RefreshTTL: refreshTtl,
 	}, config.Log)
+	if err != nil {
+		config.Log.Fatalf("unable to create jwt service: %v", err.Error())
+	}
 
 	// service
 	gauth := NewGoogleAuth(config.Config)
 	supabaseConfig := NewSupabaseConfig(config.Config)
 	storage := storage.NewSupabaseHttpRepo(supabaseConfig)
+	sessionDenylist := session.NewRedisDenylist(config.Redis)
 
 	// Midtrans payment gateway
 	midtransConfig := NewMidtransConfig(config.Config)
@@ -65,20 +76,38 @@ func Bootstrap(config *BootstrapConfig) {
 	groupBuyTierRepository := pg.NewGroupBuyTierRepositoryPg(config.DB)
 	buyerGroupSessionRepository := pg.NewBuyerGroupBuySessionRepositoryPg(config.DB)
 	buyerGroupMemberRepository := pg.NewBuyerGroupMemberRepositoryPg(config.DB)
+	groupBuyScheduleRepository := pg.NewGroupBuyScheduleRepositoryPg(config.DB)
+	groupBuyReminderRepository := pg.NewGroupBuyReminderRepositoryPg(config.DB)
 	txManager := pg.NewTxManager(config.DB)
 
 	// Order repositories
 	orderRepository := pg.NewOrderRepositoryPg(config.DB)
 	paymentRepository := pg.NewPaymentRepositoryPg(config.DB)
 	orderShippingRepository := pg.NewOrderShippingDetailRepositoryPg(config.DB)
+	paymentDiscrepancyRepository := pg.NewPaymentDiscrepancyRepositoryPg(config.DB)
+	paymentEventRepository := pg.NewPaymentEventRepositoryPg(config.DB)
+	userTokenRepository := pg.NewUserTokenRepositoryPg(config.DB)
+	loginHistoryRepository := pg.NewLoginHistoryRepositoryPg(config.DB)
+	twoFactorRepository := pg.NewTwoFactorRepositoryPg(config.DB)
+	orderAdjustmentRepository := pg.NewOrderAdjustmentRepositoryPg(config.DB)
+	userWalletRepository := pg.NewUserWalletRepositoryPg(config.DB)
+	notificationRepository := pg.NewNotificationRepositoryPg(config.DB)
+
+	// links in emails point to the frontend
+	frontendURL := config.Config.GetString("app.frontend_url")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:3000"
+	}
 
 	// setup usecase
-	authUsecase := usecase.NewAuthUsecase(userRepository, config.Log, *jwt, tokenRepository, authProviderRepository, sellerRepository)
+	notificationUsecase := usecase.NewNotificationUsecase(userRepository, notificationRepository, config.AsynqClient, config.Log)
+	groupBuyEventUsecase := usecase.NewGroupBuyEventUsecase(realtime.NewRedisBroker(config.Redis), config.Log)
+	twoFactorUsecase := usecase.NewTwoFactorUsecase(twoFactorRepository, userRepository, txManager, notificationUsecase, config.Log, NewTwoFactorKey(config.Config, config.Log))
+	authUsecase := usecase.NewAuthUsecase(userRepository, config.Log, *jwt, tokenRepository, authProviderRepository, sellerRepository, userTokenRepository, loginHistoryRepository, txManager, notificationUsecase, twoFactorUsecase, sessionDenylist, frontendURL)
 	userUsecase := usecase.NewUserUsecase(userRepository, config.Log, storage, sellerRepository)
 	addressUsecase := usecase.NewAddressUsecase(addressRepository, userRepository, config.Log)
 	sellerUsecase := usecase.NewSellerUsecase(sellerRepository, userRepository, txManager, config.Log, storage)
 	productUsecase := usecase.NewProductUsecase(productRepository, variantRepository, stockRepository, sellerRepository, categoryRepository, productImageRepository, storage, txManager, config.Log)
-	groupBuyUsecase := usecase.NewGroupBuyUsecase(addressRepository, groupBuySessionRepository, groupBuyTierRepository, productRepository, variantRepository, buyerGroupSessionRepository, buyerGroupMemberRepository, txManager, config.Log, config.AsynqClient)
 	orderUsecase := usecase.NewOrderUsecase(
 		orderRepository,
 		paymentRepository,
@@ -87,11 +116,21 @@ func Bootstrap(config *BootstrapConfig) {
 		variantRepository,
 		stockRepository,
 		buyerGroupSessionRepository,
+		buyerGroupMemberRepository,
+		userRepository,
+		paymentDiscrepancyRepository,
+		paymentEventRepository,
+		orderAdjustmentRepository,
+		groupBuyTierRepository,
+		userWalletRepository,
+		notificationUsecase,
+		groupBuyEventUsecase,
 		paymentGateway,
 		txManager,
 		config.AsynqClient,
 		config.Log,
 	)
+	groupBuyUsecase := usecase.NewGroupBuyUsecase(addressRepository, groupBuySessionRepository, groupBuyTierRepository, productRepository, variantRepository, buyerGroupSessionRepository, buyerGroupMemberRepository, orderRepository, stockRepository, groupBuyScheduleRepository, groupBuyReminderRepository, txManager, config.Log, config.AsynqClient, notificationUsecase, groupBuyEventUsecase, orderUsecase, NewInviteSigner(config.Config, config.Log))
 
 	// setup handler
 	authHandler := http.NewAuthHandler(authUsecase, config.Log, gauth)
@@ -101,16 +140,25 @@ func Bootstrap(config *BootstrapConfig) {
 	productHandler := http.NewProductHandler(productUsecase, config.Log)
 	groupBuyHandler := http.NewGroupBuyHandler(groupBuyUsecase, config.Log)
 	orderHandler := http.NewOrderHandler(orderUsecase, config.Log)
+	twoFactorHandler := http.NewTwoFactorHandler(twoFactorUsecase, config.Log)
+	notificationHandler := http.NewNotificationHandler(notificationUsecase, config.Log)
 
 	routeConfig := http.RouteConfig{
-		App:      config.App,
-		Auth:     *authHandler,
-		User:     *userHandler,
-		Address:  *addressHandler,
-		Seller:   *sellerHandler,
-		Product:  *productHandler,
-		GroupBuy: *groupBuyHandler,
-		Order:    *orderHandler,
+		App:          config.App,
+		Auth:         *authHandler,
+		User:         *userHandler,
+		Address:      *addressHandler,
+		Seller:       *sellerHandler,
+		Product:      *productHandler,
+		GroupBuy:     *groupBuyHandler,
+		Order:        *orderHandler,
+		TwoFactor:    *twoFactorHandler,
+		Notification: *notificationHandler,
+		Denylist:     sessionDenylist,
+		Jwks:         http.NewJwksHandler(jwt),
+
+		RateLimiter:       ratelimit.NewRedisLimiter(config.Redis),
+		RateLimitPolicies: NewRateLimitPolicies(config.Config),
 	}
 
 	routeConfig.Init(jwt)
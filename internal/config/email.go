@@ -1,6 +1,8 @@
 package config
 
 import (
+	"github.com/febry3/gamingin/internal/infra/mailer"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"gopkg.in/mail.v2"
 )
@@ -13,3 +15,19 @@ func NewEmail(viper *viper.Viper) *mail.Dialer {
 
 	return mail.NewDialer(mailHost, mailPort, mailUsername, mailPassword)
 }
+
+// NewMailer picks the sender from mail.driver: "log" only logs emails for local
+// development, anything else sends through SMTP.
+func NewMailer(viper *viper.Viper, log *logrus.Logger) mailer.Mailer {
+	if viper.GetString("mail.driver") == "log" {
+		log.Info("Mailer initialized with log driver")
+		return mailer.NewLogMailer(log)
+	}
+
+	from := viper.GetString("mail.from")
+	if from == "" {
+		from = viper.GetString("mail.username")
+	}
+
+	return mailer.NewSMTPMailer(NewEmail(viper), from)
+}
@@ -5,10 +5,15 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
-func NewGin(config *viper.Viper) *gin.Engine {
+// NewGin builds the engine. Only the proxies listed in app.trusted_proxies, comma
+// separated IPs or CIDRs, may set X-Forwarded-For. Without them ClientIP is the address
+// of the connection, so clients cannot pick their own IP for rate limits and login
+// history.
+func NewGin(config *viper.Viper, log *logrus.Logger) *gin.Engine {
 	switch strings.ToLower(config.GetString("app.mode")) {
 	case "debug":
 		gin.SetMode(gin.DebugMode)
@@ -19,6 +24,15 @@ func NewGin(config *viper.Viper) *gin.Engine {
 	}
 
 	r := gin.New()
+	trustedProxies := strings.FieldsFunc(config.GetString("app.trusted_proxies"), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(trustedProxies) == 0 {
+		trustedProxies = nil
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("invalid app.trusted_proxies: %v", err)
+	}
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
 	r.Use(ErrorHandler())
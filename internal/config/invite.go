@@ -0,0 +1,30 @@
+package config
+
+import (
+	"crypto/rand"
+
+	"github.com/febry3/gamingin/internal/infra/invite"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// NewInviteSigner signs buyer session share links with group_buy.invite_secret. Without
+// it an in-memory key is generated and links shared before a restart stop working,
+// which is only fine for local development.
+func NewInviteSigner(config *viper.Viper, log *logrus.Logger) *invite.Signer {
+	key := []byte(config.GetString("group_buy.invite_secret"))
+	if len(key) == 0 {
+		log.Warn("group_buy.invite_secret is not set, signing invite links with an ephemeral key")
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("unable to generate invite key: %v", err.Error())
+		}
+	}
+
+	frontendURL := config.GetString("app.frontend_url")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:3000"
+	}
+
+	return invite.NewSigner(key, frontendURL)
+}
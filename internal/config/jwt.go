@@ -0,0 +1,32 @@
+package config
+
+import (
+	"github.com/febry3/gamingin/internal/helpers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// NewJwtKeys loads the signing keys from jwt.keys_dir, jwt.active_kid names the key
+// new tokens are signed with. Old keys stay in the directory until every token they
+// signed has expired. Without a directory an in-memory key is generated, tokens
+// then do not survive a restart, which is only fine for local development.
+func NewJwtKeys(config *viper.Viper, log *logrus.Logger) (map[string]*helpers.JwtKey, string) {
+	keysDir := config.GetString("jwt.keys_dir")
+	if keysDir == "" {
+		log.Warn("jwt.keys_dir is not set, signing tokens with an ephemeral key")
+		key, err := helpers.GenerateJwtKey("ephemeral")
+		if err != nil {
+			log.Fatalf("unable to generate jwt key: %v", err.Error())
+		}
+		return map[string]*helpers.JwtKey{key.KID: key}, key.KID
+	}
+
+	keys, err := helpers.LoadJwtKeys(keysDir)
+	if err != nil {
+		log.Fatalf("unable to load jwt keys: %v", err.Error())
+	}
+
+	activeKid := config.GetString("jwt.active_kid")
+	log.Infof("Loaded %d jwt keys, signing with %s", len(keys), activeKid)
+	return keys, activeKid
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"time"
+
+	"github.com/febry3/gamingin/internal/infra/ratelimit"
+	"github.com/spf13/viper"
+)
+
+// defaultRateLimitPolicies apply unless ratelimit.<name>.limit, ratelimit.<name>.window
+// or ratelimit.<name>.key_by are set
+var defaultRateLimitPolicies = []ratelimit.Policy{
+	{Name: "login", Limit: 10, Window: time.Minute, KeyBy: ratelimit.KeyByIP},
+	{Name: "register", Limit: 5, Window: time.Hour, KeyBy: ratelimit.KeyByIP},
+	{Name: "google", Limit: 10, Window: time.Minute, KeyBy: ratelimit.KeyByIP},
+	{Name: "two_factor", Limit: 10, Window: time.Minute, KeyBy: ratelimit.KeyByIP},
+	{Name: "group_buy_join", Limit: 20, Window: time.Minute, KeyBy: ratelimit.KeyByUser},
+	{Name: "payment_webhook", Limit: 300, Window: time.Minute, KeyBy: ratelimit.KeyByRoute},
+}
+
+func NewRateLimitPolicies(config *viper.Viper) map[string]ratelimit.Policy {
+	policies := make(map[string]ratelimit.Policy, len(defaultRateLimitPolicies))
+	for _, policy := range defaultRateLimitPolicies {
+		prefix := "ratelimit." + policy.Name + "."
+		if limit := config.GetInt(prefix + "limit"); limit > 0 {
+			policy.Limit = limit
+		}
+		if window := config.GetDuration(prefix + "window"); window > 0 {
+			policy.Window = window
+		}
+		if keyBy := config.GetString(prefix + "key_by"); keyBy != "" {
+			policy.KeyBy = keyBy
+		}
+		policies[policy.Name] = policy
+	}
+	return policies
+}
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// NewRedis connects to the same Redis instance asynq uses
+func NewRedis(config *viper.Viper, log *logrus.Logger) *redis.Client {
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.GetString("redis.addr"),
+		Password: config.GetString("redis.password"),
+		DB:       config.GetInt("redis.db"),
+	})
+	log.Info("Redis client initialized")
+	return client
+}
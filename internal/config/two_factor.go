@@ -0,0 +1,31 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// NewTwoFactorKey returns the AES key that encrypts TOTP secrets, two_factor.secret_key
+// holds 32 bytes encoded as base64. Without it an in-memory key is generated and
+// enrolled authenticators stop working after a restart, which is only fine for
+// local development.
+func NewTwoFactorKey(config *viper.Viper, log *logrus.Logger) []byte {
+	encoded := config.GetString("two_factor.secret_key")
+	if encoded == "" {
+		log.Warn("two_factor.secret_key is not set, encrypting 2fa secrets with an ephemeral key")
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("unable to generate 2fa key: %v", err.Error())
+		}
+		return key
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		log.Fatalf("two_factor.secret_key must be 32 bytes encoded as base64")
+	}
+	return key
+}
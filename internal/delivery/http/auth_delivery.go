@@ -3,6 +3,7 @@ package http
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/febry3/gamingin/internal/errorx"
 	"github.com/febry3/gamingin/internal/helpers"
@@ -38,17 +39,25 @@ func (a *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 	loginRequest.DeviceInfo = deviceInfo
+	loginRequest.IPAddress = c.ClientIP()
 	userResponse, refreshToken, err := a.uc.Login(c.Request.Context(), loginRequest)
 	if err != nil {
 		a.log.Errorf("[AuthDelivery] Login Error: %s", err.Error())
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+		status := http.StatusBadRequest
+		if errors.Is(err, errorx.ErrAccountLocked) || errors.Is(err, errorx.ErrTooManyLoginAttempts) {
+			status = http.StatusTooManyRequests
+		}
+		c.AbortWithStatusJSON(status, gin.H{
 			"message": "invalid request",
 			"error":   err.Error(),
 		})
 		return
 	}
 
-	c.SetCookie("refresh_token", refreshToken, 7*24*60*60, "*", "localhost", false, true)
+	// a 2FA challenge carries no tokens yet
+	if refreshToken != "" {
+		c.SetCookie("refresh_token", refreshToken, 7*24*60*60, "*", "localhost", false, true)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
@@ -93,7 +102,7 @@ func (a *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	newAccessToken, err := a.uc.RefreshAccessToken(c.Request.Context(), refreshToken)
+	newAccessToken, newRefreshToken, err := a.uc.RefreshAccessToken(c.Request.Context(), refreshToken)
 	if err != nil {
 		if errors.Is(err, errorx.ErrTokenExpired) {
 			a.log.Errorf("[AuthDelivery] Token Expired: %s", err.Error())
@@ -103,6 +112,15 @@ func (a *AuthHandler) RefreshToken(c *gin.Context) {
 			})
 			return
 		}
+		if errors.Is(err, errorx.ErrTokenReused) || errors.Is(err, errorx.ErrTokenInvalid) {
+			a.log.Errorf("[AuthDelivery] Token Rejected: %s", err.Error())
+			c.SetCookie("refresh_token", "", -1, "*", "localhost", false, true)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"message": "session is no longer valid, please login again",
+				"error":   err.Error(),
+			})
+			return
+		}
 		a.log.Errorf("[AuthDelivery] Refresh Token Error: %s", err.Error())
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
 			"message": "invalid request",
@@ -111,6 +129,8 @@ func (a *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	c.SetCookie("refresh_token", newRefreshToken, 7*24*60*60, "*", "localhost", false, true)
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":       true,
 		"message":      "token refreshed successfully",
@@ -149,6 +169,7 @@ func (a *AuthHandler) LoginOrRegisterWithGoogle(c *gin.Context) {
 	}
 
 	userInfo.DeviceInfo = c.Request.Header.Get("User-Agent")
+	userInfo.IPAddress = c.ClientIP()
 
 	userResponse, refreshToken, err := a.uc.LoginOrRegisterWithGoogle(c.Request.Context(), userInfo)
 	if err != nil {
@@ -160,7 +181,10 @@ func (a *AuthHandler) LoginOrRegisterWithGoogle(c *gin.Context) {
 		return
 	}
 
-	c.SetCookie("refresh_token", refreshToken, 7*24*60*60, "*", "localhost", false, true)
+	// a 2FA challenge carries no tokens yet
+	if refreshToken != "" {
+		c.SetCookie("refresh_token", refreshToken, 7*24*60*60, "*", "localhost", false, true)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  true,
@@ -198,3 +222,460 @@ func (a *AuthHandler) Logout(c *gin.Context) {
 		"message": "logout success",
 	})
 }
+
+func (a *AuthHandler) VerifyEmail(c *gin.Context) {
+	var request dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		a.log.Errorf("[AuthDelivery] Bind Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := a.uc.VerifyEmail(c.Request.Context(), request); err != nil {
+		a.log.Errorf("[AuthDelivery] Verify Email Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "failed to verify email",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "email verified successfully",
+	})
+}
+
+func (a *AuthHandler) ResendVerificationEmail(c *gin.Context) {
+	v, ok := c.Get("user")
+	if !ok {
+		a.log.Error("[AuthDelivery] No User in Context")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+	jwt := v.(*dto.JwtPayload)
+
+	if err := a.uc.ResendVerificationEmail(c.Request.Context(), jwt.ID); err != nil {
+		a.log.Errorf("[AuthDelivery] Resend Verification Email Error: %s", err.Error())
+		status := http.StatusBadRequest
+		if errors.Is(err, errorx.ErrVerificationRateLimited) {
+			status = http.StatusTooManyRequests
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"message": "failed to send verification email",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "verification email sent",
+	})
+}
+
+func (a *AuthHandler) ForgotPassword(c *gin.Context) {
+	var request dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		a.log.Errorf("[AuthDelivery] Bind Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := a.uc.ForgotPassword(c.Request.Context(), request); err != nil {
+		a.log.Errorf("[AuthDelivery] Forgot Password Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "failed to request password reset",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "if the email is registered, a reset link has been sent",
+	})
+}
+
+func (a *AuthHandler) ResetPassword(c *gin.Context) {
+	var request dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		a.log.Errorf("[AuthDelivery] Bind Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := a.uc.ResetPassword(c.Request.Context(), request); err != nil {
+		a.log.Errorf("[AuthDelivery] Reset Password Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "failed to reset password",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "password reset successfully",
+	})
+}
+
+func (a *AuthHandler) ChangePassword(c *gin.Context) {
+	v, ok := c.Get("user")
+	if !ok {
+		a.log.Error("[AuthDelivery] No User in Context")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+	jwt := v.(*dto.JwtPayload)
+
+	var request dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		a.log.Errorf("[AuthDelivery] Bind Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// keep the device changing the password signed in
+	if err := a.uc.ChangePassword(c.Request.Context(), jwt.ID, request, jwt.SessionID); err != nil {
+		a.log.Errorf("[AuthDelivery] Change Password Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "failed to change password",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "password changed successfully",
+	})
+}
+
+func (a *AuthHandler) GetSessions(c *gin.Context) {
+	v, ok := c.Get("user")
+	if !ok {
+		a.log.Error("[AuthDelivery] No User in Context")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+	jwt := v.(*dto.JwtPayload)
+
+	sessions, err := a.uc.GetSessions(c.Request.Context(), jwt.ID, jwt.SessionID)
+	if err != nil {
+		a.log.Errorf("[AuthDelivery] Get Sessions Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get sessions",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "successfully get sessions",
+		"data":    sessions,
+	})
+}
+
+func (a *AuthHandler) RevokeSession(c *gin.Context) {
+	v, ok := c.Get("user")
+	if !ok {
+		a.log.Error("[AuthDelivery] No User in Context")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+	jwt := v.(*dto.JwtPayload)
+
+	if err := a.uc.RevokeSession(c.Request.Context(), jwt.ID, c.Param("id")); err != nil {
+		a.log.Errorf("[AuthDelivery] Revoke Session Error: %s", err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, errorx.ErrSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"message": "failed to revoke session",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "session revoked successfully",
+	})
+}
+
+func (a *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	v, ok := c.Get("user")
+	if !ok {
+		a.log.Error("[AuthDelivery] No User in Context")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+	jwt := v.(*dto.JwtPayload)
+
+	revoked, err := a.uc.RevokeOtherSessions(c.Request.Context(), jwt.ID, jwt.SessionID)
+	if err != nil {
+		a.log.Errorf("[AuthDelivery] Revoke Other Sessions Error: %s", err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, errorx.ErrSessionNotFound) {
+			status = http.StatusBadRequest
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"message": "failed to revoke sessions",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "other sessions revoked successfully",
+		"data":    gin.H{"revoked": revoked},
+	})
+}
+
+func (a *AuthHandler) GetLoginHistory(c *gin.Context) {
+	v, ok := c.Get("user")
+	if !ok {
+		a.log.Error("[AuthDelivery] No User in Context")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+	jwt := v.(*dto.JwtPayload)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	history, err := a.uc.GetLoginHistory(c.Request.Context(), jwt.ID, page, limit)
+	if err != nil {
+		a.log.Errorf("[AuthDelivery] Get Login History Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get login history",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "successfully get login history",
+		"data":    history,
+	})
+}
+
+func (a *AuthHandler) GetAuthProviders(c *gin.Context) {
+	v, ok := c.Get("user")
+	if !ok {
+		a.log.Error("[AuthDelivery] No User in Context")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+	jwt := v.(*dto.JwtPayload)
+
+	providers, err := a.uc.GetAuthProviders(c.Request.Context(), jwt.ID)
+	if err != nil {
+		a.log.Errorf("[AuthDelivery] Get Auth Providers Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get sign-in methods",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "successfully get sign-in methods",
+		"data":    providers,
+	})
+}
+
+func (a *AuthHandler) LinkProvider(c *gin.Context) {
+	v, ok := c.Get("user")
+	if !ok {
+		a.log.Error("[AuthDelivery] No User in Context")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+	jwt := v.(*dto.JwtPayload)
+
+	var request dto.LinkProviderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		a.log.Errorf("[AuthDelivery] Bind Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var err error
+	switch c.Param("provider") {
+	case "google":
+		var userInfo dto.LoginWithGoogleData
+		userInfo, err = helpers.VerifyGoogleIDToken(c.Request.Context(), request.IDToken, a.gauth.ClientID)
+		if err != nil {
+			a.log.Errorf("[AuthDelivery] Verify Google ID Token Error: %s", err.Error())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"message": "failed to verify google account",
+				"error":   err.Error(),
+			})
+			return
+		}
+		err = a.uc.LinkGoogleProvider(c.Request.Context(), jwt.ID, userInfo)
+	case "email":
+		err = a.uc.LinkEmailProvider(c.Request.Context(), jwt.ID, dto.SetPasswordRequest{Password: request.Password})
+	default:
+		err = errorx.ErrProviderNotSupported
+	}
+	if err != nil {
+		a.log.Errorf("[AuthDelivery] Link Provider Error: %s", err.Error())
+		status := http.StatusBadRequest
+		if errors.Is(err, errorx.ErrProviderAlreadyLinked) || errors.Is(err, errorx.ErrProviderLinkedToAnother) {
+			status = http.StatusConflict
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"message": "failed to link sign-in method",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "sign-in method linked successfully",
+	})
+}
+
+func (a *AuthHandler) UnlinkProvider(c *gin.Context) {
+	v, ok := c.Get("user")
+	if !ok {
+		a.log.Error("[AuthDelivery] No User in Context")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+	jwt := v.(*dto.JwtPayload)
+
+	if err := a.uc.UnlinkProvider(c.Request.Context(), jwt.ID, c.Param("provider")); err != nil {
+		a.log.Errorf("[AuthDelivery] Unlink Provider Error: %s", err.Error())
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, errorx.ErrProviderNotLinked):
+			status = http.StatusNotFound
+		case errors.Is(err, errorx.ErrLastProvider):
+			status = http.StatusConflict
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"message": "failed to unlink sign-in method",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "sign-in method unlinked successfully",
+	})
+}
+
+func (a *AuthHandler) StartTwoFactorSetup(c *gin.Context) {
+	var request dto.TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		a.log.Errorf("[AuthDelivery] Bind Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	enrollment, err := a.uc.StartTwoFactorSetup(c.Request.Context(), request)
+	if err != nil {
+		a.log.Errorf("[AuthDelivery] Start Two Factor Setup Error: %s", err.Error())
+		status := http.StatusBadRequest
+		if errors.Is(err, errorx.ErrTwoFactorChallengeInvalid) {
+			status = http.StatusUnauthorized
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"message": "failed to start two-factor setup",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "scan the code with your authenticator app",
+		"data":    enrollment,
+	})
+}
+
+func (a *AuthHandler) VerifyTwoFactorLogin(c *gin.Context) {
+	var request dto.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		a.log.Errorf("[AuthDelivery] Bind Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+	request.DeviceInfo = c.Request.Header.Get("User-Agent")
+	request.IPAddress = c.ClientIP()
+
+	userResponse, refreshToken, err := a.uc.VerifyTwoFactorLogin(c.Request.Context(), request)
+	if err != nil {
+		a.log.Errorf("[AuthDelivery] Verify Two Factor Error: %s", err.Error())
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, errorx.ErrTwoFactorCodeInvalid), errors.Is(err, errorx.ErrTwoFactorChallengeInvalid):
+			status = http.StatusUnauthorized
+		case errors.Is(err, errorx.ErrAccountLocked):
+			status = http.StatusTooManyRequests
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"message": "failed to verify two-factor code",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.SetCookie("refresh_token", refreshToken, 7*24*60*60, "*", "localhost", false, true)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "login success",
+		"data":    userResponse,
+	})
+}
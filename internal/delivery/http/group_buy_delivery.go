@@ -1,9 +1,17 @@
 package http
 
 import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/febry3/gamingin/internal/dto"
+	"github.com/febry3/gamingin/internal/errorx"
+	"github.com/febry3/gamingin/internal/infra/realtime"
 	"github.com/febry3/gamingin/internal/usecase"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -18,6 +26,8 @@ func NewGroupBuyHandler(pu usecase.GroupBuyUsecaseContract, log *logrus.Logger)
 	return &GroupBuyHandler{pu: pu, log: log}
 }
 
+// ChangeGroupBuySessionStatus handles PATCH /seller/group-buy/status, a campaign can only
+// be completed or cancelled ahead of its end
 func (gh *GroupBuyHandler) ChangeGroupBuySessionStatus(c *gin.Context) {
 	v, ok := c.Get("user")
 	if !ok {
@@ -33,17 +43,17 @@ func (gh *GroupBuyHandler) ChangeGroupBuySessionStatus(c *gin.Context) {
 	var req dto.ChangeStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		gh.log.Error("[ProductDelivery] ChangeGroupBuySessionStatus failed: ", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusBadRequest, gin.H{
 			"message": "failed to change group buy session status",
 			"error":   err.Error(),
 		})
 		return
 	}
 
-	err := gh.pu.ChangeGroupBuySessionStatus(c.Request.Context(), req.SessionID, req.Status, jwt.SellerID)
+	err := gh.pu.ChangeGroupBuySessionStatus(c.Request.Context(), req.SessionID, req.Status, req.Reason, jwt.SellerID)
 	if err != nil {
 		gh.log.Error("[ProductDelivery] ChangeGroupBuySessionStatus failed: ", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(groupBuyErrorStatus(err), gin.H{
 			"message": "failed to change group buy session status",
 			"error":   err.Error(),
 		})
@@ -83,8 +93,110 @@ func (gh *GroupBuyHandler) GetAllGroupBuySessionForSeller(c *gin.Context) {
 	})
 }
 
+// GetCampaignAnalytics handles GET /seller/group-buy/analytics
+func (gh *GroupBuyHandler) GetCampaignAnalytics(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	analytics, err := gh.pu.GetCampaignAnalytics(c.Request.Context(), claims.SellerID)
+	if err != nil {
+		gh.log.Error("[GroupBuyDelivery] GetCampaignAnalytics failed: ", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get group buy analytics",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "success",
+		"data":    analytics,
+	})
+}
+
+var campaignAnalyticsCSVHeader = []string{
+	"group_buy_session_id", "product_title", "variant_name", "status", "starts_at", "expires_at",
+	"buyer_sessions", "members", "paid_members", "units_sold", "max_quantity", "revenue",
+	"tier_threshold", "tier_discount", "funnel_joined", "funnel_ordered", "funnel_paid",
+	"order_rate", "payment_rate",
+}
+
+// ExportCampaignAnalytics handles GET /seller/group-buy/analytics/export - the analytics as a CSV file
+func (gh *GroupBuyHandler) ExportCampaignAnalytics(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	analytics, err := gh.pu.GetCampaignAnalytics(c.Request.Context(), claims.SellerID)
+	if err != nil {
+		gh.log.Error("[GroupBuyDelivery] ExportCampaignAnalytics failed: ", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to export group buy analytics",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="group-buy-analytics-%s.csv"`, time.Now().Format("20060102")))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(campaignAnalyticsCSVHeader)
+	for _, item := range analytics {
+		_ = w.Write([]string{
+			item.GroupBuySessionID,
+			item.ProductTitle,
+			item.VariantName,
+			item.Status,
+			item.StartsAt.Format(time.RFC3339),
+			item.ExpiresAt.Format(time.RFC3339),
+			strconv.FormatInt(item.BuyerSessions, 10),
+			strconv.FormatInt(item.Members, 10),
+			strconv.FormatInt(item.PaidMembers, 10),
+			strconv.FormatInt(item.UnitsSold, 10),
+			strconv.FormatInt(item.MaxQuantity, 10),
+			strconv.FormatFloat(item.Revenue, 'f', 2, 64),
+			strconv.Itoa(item.TierThreshold),
+			strconv.FormatFloat(item.TierDiscount, 'f', 2, 64),
+			strconv.FormatInt(item.Funnel.Joined, 10),
+			strconv.FormatInt(item.Funnel.Ordered, 10),
+			strconv.FormatInt(item.Funnel.Paid, 10),
+			strconv.FormatFloat(item.Funnel.OrderRate, 'f', 4, 64),
+			strconv.FormatFloat(item.Funnel.PaymentRate, 'f', 4, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		gh.log.Error("[GroupBuyDelivery] ExportCampaignAnalytics failed to write csv: ", err)
+	}
+}
+
+// GetAllGroupBuySessionForBuyer handles GET /group-buy - the public campaign feed
 func (gh *GroupBuyHandler) GetAllGroupBuySessionForBuyer(c *gin.Context) {
-	groupBuySessions, err := gh.pu.GetAllGroupBuySessionForBuyer(c)
+	categoryID, _ := strconv.ParseInt(c.Query("category_id"), 10, 64)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	groupBuySessions, err := gh.pu.GetAllGroupBuySessionForBuyer(c.Request.Context(), dto.GroupBuyFeedFilter{
+		Upcoming:   c.Query("status") == "upcoming",
+		CategoryID: categoryID,
+		Sort:       c.Query("sort"),
+		Page:       page,
+		Limit:      limit,
+	})
 	if err != nil {
 		gh.log.Error("[ProductDelivery] GetAllGroupBuySessionForBuyer failed: ", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -125,7 +237,7 @@ func (gh *GroupBuyHandler) CreateGroupBuySession(c *gin.Context) {
 	response, err := gh.pu.CreateGroupBuySession(c.Request.Context(), req, jwt.SellerID)
 	if err != nil {
 		gh.log.Error("[ProductDelivery] CreateGroupBuySession failed: ", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(groupBuyErrorStatus(err), gin.H{
 			"message": "failed to create group buy session",
 			"error":   err.Error(),
 		})
@@ -165,7 +277,7 @@ func (gh *GroupBuyHandler) CreateBuyerSession(c *gin.Context) {
 	sessCode, err := gh.pu.CreateBuyerSession(c.Request.Context(), &req)
 	if err != nil {
 		gh.log.Error("[ProductDelivery] CreateBuyerSession failed: ", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(groupBuyErrorStatus(err), gin.H{
 			"message": "failed to create buyer session",
 			"error":   err.Error(),
 		})
@@ -210,6 +322,7 @@ func (gh *GroupBuyHandler) GetSessionForBuyerByCode(c *gin.Context) {
 	})
 }
 
+// JoinSession handles POST /group-buy/:sessionId/join, ?invite= carries the token of a share link
 func (gh *GroupBuyHandler) JoinSession(c *gin.Context) {
 	v, ok := c.Get("user")
 	if !ok {
@@ -222,9 +335,20 @@ func (gh *GroupBuyHandler) JoinSession(c *gin.Context) {
 	}
 	jwt := v.(*dto.JwtPayload)
 
-	if err := gh.pu.JoinSession(c.Request.Context(), c.Param("sessionId"), jwt.ID); err != nil {
+	var req dto.JoinBuyerGroupSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "invalid request",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := gh.pu.JoinSession(c.Request.Context(), c.Param("sessionId"), jwt.ID, req.Quantity, c.Query("invite")); err != nil {
 		gh.log.Error("[ProductDelivery] JoinSession failed: ", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(groupBuyErrorStatus(err), gin.H{
 			"message": "failed to join session",
 			"error":   err.Error(),
 		})
@@ -236,3 +360,398 @@ func (gh *GroupBuyHandler) JoinSession(c *gin.Context) {
 		"message": "session joined successfully",
 	})
 }
+
+// CreateInvite handles GET /group-buy/:sessionId/invite
+func (gh *GroupBuyHandler) CreateInvite(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	response, err := gh.pu.CreateInvite(c.Request.Context(), c.Param("sessionId"), claims.ID)
+	if err != nil {
+		gh.log.Error("[GroupBuyDelivery] CreateInvite failed: ", err)
+		c.AbortWithStatusJSON(groupBuyErrorStatus(err), gin.H{
+			"message": "failed to create invite link",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "invite link created successfully",
+		"data":    response,
+	})
+}
+
+// ChangeQuantity handles PATCH /group-buy/:sessionId/quantity
+func (gh *GroupBuyHandler) ChangeQuantity(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	var req dto.GroupBuyQuantityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := gh.pu.ChangeQuantity(c.Request.Context(), c.Param("sessionId"), claims.ID, req.Quantity); err != nil {
+		gh.log.Error("[GroupBuyDelivery] ChangeQuantity failed: ", err)
+		c.AbortWithStatusJSON(groupBuyErrorStatus(err), gin.H{
+			"message": "failed to change quantity",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "quantity changed successfully",
+	})
+}
+
+// LeaveSession handles POST /group-buy/:sessionId/leave
+func (gh *GroupBuyHandler) LeaveSession(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	if err := gh.pu.LeaveSession(c.Request.Context(), c.Param("sessionId"), claims.ID); err != nil {
+		gh.log.Error("[GroupBuyDelivery] LeaveSession failed: ", err)
+		c.AbortWithStatusJSON(groupBuyErrorStatus(err), gin.H{
+			"message": "failed to leave session",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "left session successfully",
+	})
+}
+
+// RemoveMember handles DELETE /group-buy/:sessionId/members/:userId
+func (gh *GroupBuyHandler) RemoveMember(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	memberID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid user id",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := gh.pu.RemoveMember(c.Request.Context(), c.Param("sessionId"), claims.ID, memberID); err != nil {
+		gh.log.Error("[GroupBuyDelivery] RemoveMember failed: ", err)
+		c.AbortWithStatusJSON(groupBuyErrorStatus(err), gin.H{
+			"message": "failed to remove member",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "member removed successfully",
+	})
+}
+
+// CancelGroupBuySession handles POST /seller/group-buy/:id/cancel
+func (gh *GroupBuyHandler) CancelGroupBuySession(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	var req dto.CancelGroupBuyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := gh.pu.CancelSession(c.Request.Context(), c.Param("id"), claims.SellerID, req.Reason); err != nil {
+		gh.log.Error("[GroupBuyDelivery] CancelGroupBuySession failed: ", err)
+		c.AbortWithStatusJSON(groupBuyErrorStatus(err), gin.H{
+			"message": "failed to cancel group buy session",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "group buy session cancelled successfully",
+	})
+}
+
+// CreateSchedule handles POST /seller/group-buy/schedules
+func (gh *GroupBuyHandler) CreateSchedule(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	var req dto.GroupBuyScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	schedule, err := gh.pu.CreateSchedule(c.Request.Context(), &req, claims.SellerID)
+	if err != nil {
+		gh.log.Error("[GroupBuyDelivery] CreateSchedule failed: ", err)
+		c.AbortWithStatusJSON(groupBuyErrorStatus(err), gin.H{
+			"message": "failed to create group buy schedule",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  true,
+		"message": "group buy schedule created successfully",
+		"data":    schedule,
+	})
+}
+
+// GetSchedules handles GET /seller/group-buy/schedules
+func (gh *GroupBuyHandler) GetSchedules(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	schedules, err := gh.pu.GetSchedules(c.Request.Context(), claims.SellerID)
+	if err != nil {
+		gh.log.Error("[GroupBuyDelivery] GetSchedules failed: ", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get group buy schedules",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "success",
+		"data":    schedules,
+	})
+}
+
+// StopSchedule handles DELETE /seller/group-buy/schedules/:id
+func (gh *GroupBuyHandler) StopSchedule(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	if err := gh.pu.StopSchedule(c.Request.Context(), c.Param("id"), claims.SellerID); err != nil {
+		gh.log.Error("[GroupBuyDelivery] StopSchedule failed: ", err)
+		c.AbortWithStatusJSON(groupBuyErrorStatus(err), gin.H{
+			"message": "failed to stop group buy schedule",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "group buy schedule stopped successfully",
+	})
+}
+
+// SubscribeReminder handles POST /group-buy/campaigns/:id/reminder
+func (gh *GroupBuyHandler) SubscribeReminder(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	if err := gh.pu.SubscribeReminder(c.Request.Context(), c.Param("id"), claims.ID); err != nil {
+		gh.log.Error("[GroupBuyDelivery] SubscribeReminder failed: ", err)
+		c.AbortWithStatusJSON(groupBuyErrorStatus(err), gin.H{
+			"message": "failed to set reminder",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "reminder set successfully",
+	})
+}
+
+// UnsubscribeReminder handles DELETE /group-buy/campaigns/:id/reminder
+func (gh *GroupBuyHandler) UnsubscribeReminder(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	if err := gh.pu.UnsubscribeReminder(c.Request.Context(), c.Param("id"), claims.ID); err != nil {
+		gh.log.Error("[GroupBuyDelivery] UnsubscribeReminder failed: ", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to remove reminder",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "reminder removed successfully",
+	})
+}
+
+// sseHeartbeat keeps idle streams from being closed by proxies
+const sseHeartbeat = 25 * time.Second
+
+// StreamSessionEvents handles GET /group-buy/:sessionId/events - live updates of a buyer session
+func (gh *GroupBuyHandler) StreamSessionEvents(c *gin.Context) {
+	events, unsubscribe, err := gh.pu.SubscribeSessionEvents(c.Request.Context(), c.Param("sessionId"))
+	if err != nil {
+		gh.log.Error("[GroupBuyDelivery] StreamSessionEvents failed: ", err)
+		c.AbortWithStatusJSON(groupBuyErrorStatus(err), gin.H{
+			"message": "failed to subscribe to session",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer unsubscribe()
+
+	streamEvents(c, events)
+}
+
+// StreamCampaignEvents handles GET /seller/group-buy/:id/events - live updates of a campaign
+func (gh *GroupBuyHandler) StreamCampaignEvents(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "unauthorized",
+			"error":   "unauthorized user",
+		})
+		return
+	}
+
+	events, unsubscribe, err := gh.pu.SubscribeCampaignEvents(c.Request.Context(), c.Param("id"), claims.SellerID)
+	if err != nil {
+		gh.log.Error("[GroupBuyDelivery] StreamCampaignEvents failed: ", err)
+		c.AbortWithStatusJSON(groupBuyErrorStatus(err), gin.H{
+			"message": "failed to subscribe to group buy session",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer unsubscribe()
+
+	streamEvents(c, events)
+}
+
+// streamEvents writes events as Server-Sent Events until the client goes away
+func streamEvents(c *gin.Context, events <-chan realtime.Event) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"at": time.Now()})
+			return true
+		}
+	})
+}
+
+func groupBuyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errorx.ErrSessionNotFound), errors.Is(err, errorx.ErrNotSessionMember),
+		errors.Is(err, errorx.ErrGroupBuySessionNotFound), errors.Is(err, errorx.ErrScheduleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errorx.ErrNotSessionOrganizer), errors.Is(err, errorx.ErrNotCampaignOwner):
+		return http.StatusForbidden
+	case errors.Is(err, errorx.ErrSessionClosed), errors.Is(err, errorx.ErrMemberAlreadyPaid),
+		errors.Is(err, errorx.ErrMemberHasOrder), errors.Is(err, errorx.ErrQuantityUnavailable),
+		errors.Is(err, errorx.ErrSessionFull),
+		errors.Is(err, errorx.ErrSessionAlreadyStarted), errors.Is(err, errorx.ErrCampaignNotUpcoming),
+		errors.Is(err, errorx.ErrCampaignNotCancellable):
+		return http.StatusConflict
+	case errors.Is(err, errorx.ErrCannotRemoveSelf), errors.Is(err, errorx.ErrInvalidInvite),
+		errors.Is(err, errorx.ErrQuantityPerMember), errors.Is(err, errorx.ErrInvalidCampaignWindow),
+		errors.Is(err, errorx.ErrInvalidRecurrence), errors.Is(err, errorx.ErrInvalidCampaignStatus):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
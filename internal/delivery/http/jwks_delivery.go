@@ -0,0 +1,23 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/febry3/gamingin/internal/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+type JwksHandler struct {
+	jwt *helpers.JwtService
+}
+
+func NewJwksHandler(jwt *helpers.JwtService) *JwksHandler {
+	return &JwksHandler{jwt: jwt}
+}
+
+// GetJwks handles GET /.well-known/jwks.json
+func (h *JwksHandler) GetJwks(c *gin.Context) {
+	// verifiers may cache the set, a rotated key is published before it signs anything
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": h.jwt.Jwks()})
+}
@@ -5,10 +5,11 @@ import (
 	"strings"
 
 	"github.com/febry3/gamingin/internal/helpers"
+	"github.com/febry3/gamingin/internal/infra/session"
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(jwt *helpers.JwtService) gin.HandlerFunc {
+func AuthMiddleware(jwt *helpers.JwtService, denylist session.Denylist) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
@@ -29,6 +30,18 @@ func AuthMiddleware(jwt *helpers.JwtService) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "invalid or expired token"})
 			return
 		}
+
+		if user.SessionID != "" {
+			// a Redis outage should not sign everyone out, the token still expires on its own
+			revoked, err := denylist.IsRevoked(c.Request.Context(), user.SessionID)
+			if err != nil {
+				_ = c.Error(err)
+			} else if revoked {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "session has been revoked"})
+				return
+			}
+		}
+
 		c.Set("user", user)
 		c.Next()
 	}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/febry3/gamingin/internal/dto"
+	"github.com/febry3/gamingin/internal/infra/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitMiddleware throttles requests with the policy. Keying by user needs the
+// auth middleware to run first, anonymous requests fall back to the client IP.
+func RateLimitMiddleware(limiter ratelimit.Limiter, policy ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ratelimit:" + policy.Name + ":" + rateLimitKey(c, policy.KeyBy)
+
+		result, err := limiter.Allow(c.Request.Context(), key, policy.Limit, policy.Window)
+		if err != nil {
+			// a Redis outage should not take the endpoints down with it
+			_ = c.Error(err)
+			c.Next()
+			return
+		}
+
+		resetSeconds := strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", resetSeconds)
+
+		if !result.Allowed {
+			c.Header("Retry-After", resetSeconds)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"status":  false,
+				"message": "too many requests, please try again later",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func rateLimitKey(c *gin.Context, keyBy string) string {
+	switch keyBy {
+	case ratelimit.KeyByRoute:
+		return "route"
+	case ratelimit.KeyByUser:
+		if v, ok := c.Get("user"); ok {
+			if claims, ok := v.(*dto.JwtPayload); ok {
+				return "user:" + strconv.FormatInt(claims.ID, 10)
+			}
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
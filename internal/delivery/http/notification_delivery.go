@@ -0,0 +1,105 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/febry3/gamingin/internal/errorx"
+	"github.com/febry3/gamingin/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type NotificationHandler struct {
+	uc  usecase.NotificationUsecaseContract
+	log *logrus.Logger
+}
+
+func NewNotificationHandler(uc usecase.NotificationUsecaseContract, log *logrus.Logger) *NotificationHandler {
+	return &NotificationHandler{uc: uc, log: log}
+}
+
+// GetNotifications handles GET /user/notifications
+func (n *NotificationHandler) GetNotifications(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	inbox, err := n.uc.GetInbox(c.Request.Context(), claims.ID, page, limit)
+	if err != nil {
+		n.log.Errorf("[NotificationDelivery] Get Notifications Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get notifications",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "successfully get notifications",
+		"data":    inbox,
+	})
+}
+
+// MarkRead handles PATCH /user/notifications/:id/read
+func (n *NotificationHandler) MarkRead(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+
+	if err := n.uc.MarkRead(c.Request.Context(), claims.ID, c.Param("id")); err != nil {
+		n.log.Errorf("[NotificationDelivery] Mark Read Error: %s", err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, errorx.ErrNotificationNotFound) {
+			status = http.StatusNotFound
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"message": "failed to mark notification as read",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "notification marked as read",
+	})
+}
+
+// MarkAllRead handles POST /user/notifications/read-all
+func (n *NotificationHandler) MarkAllRead(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+
+	if err := n.uc.MarkAllRead(c.Request.Context(), claims.ID); err != nil {
+		n.log.Errorf("[NotificationDelivery] Mark All Read Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to mark notifications as read",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "all notifications marked as read",
+	})
+}
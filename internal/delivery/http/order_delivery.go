@@ -124,6 +124,70 @@ func (h *OrderHandler) GetOrderByID(c *gin.Context) {
 	})
 }
 
+// RegeneratePayment handles POST /user/orders/:id/payment - new VA for a pending order
+func (h *OrderHandler) RegeneratePayment(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, errorResponse("Unauthorized"))
+		return
+	}
+
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("Order ID is required"))
+		return
+	}
+
+	var request dto.RegeneratePaymentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	order, err := h.orderUsecase.RegeneratePayment(c.Request.Context(), claims.ID, orderID, &request)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment created successfully",
+		"data":    order,
+	})
+}
+
+// UpdateOrderStatus handles PATCH /seller/orders/:id/status - shipped or delivered
+func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, errorResponse("Unauthorized"))
+		return
+	}
+
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("Order ID is required"))
+		return
+	}
+
+	var request dto.UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+
+	order, err := h.orderUsecase.UpdateOrderStatusBySeller(c.Request.Context(), claims.SellerID, orderID, &request)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order status updated successfully",
+		"data":    order,
+	})
+}
+
 // HandlePaymentNotification handles POST /payments/webhook - Midtrans callback
 func (h *OrderHandler) HandlePaymentNotification(c *gin.Context) {
 	var notification dto.MidtransNotification
@@ -141,10 +205,59 @@ func (h *OrderHandler) HandlePaymentNotification(c *gin.Context) {
 		return
 	}
 
-	// Midtrans expects 200 OK to acknowledge receipt
+	// Midtrans expects 200 OK to acknowledge receipt, the event is processed by the worker
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// GetPaymentDiscrepancies handles GET /admin/payments/discrepancies
+func (h *OrderHandler) GetPaymentDiscrepancies(c *gin.Context) {
+	discrepancies, err := h.orderUsecase.GetPaymentDiscrepancies(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment discrepancies retrieved successfully",
+		"data":    discrepancies,
+	})
+}
+
+// GetPaymentEvents handles GET /admin/payments/events
+func (h *OrderHandler) GetPaymentEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	events, err := h.orderUsecase.GetPaymentEvents(c.Request.Context(), c.Query("status"), page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment events retrieved successfully",
+		"data":    events,
+	})
+}
+
+// ReplayPaymentEvent handles POST /admin/payments/events/:id/replay
+func (h *OrderHandler) ReplayPaymentEvent(c *gin.Context) {
+	eventID := c.Param("id")
+	if eventID == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("Event ID is required"))
+		return
+	}
+
+	if err := h.orderUsecase.ReplayPaymentEvent(c.Request.Context(), eventID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Payment event queued for replay",
+	})
+}
+
 // Helper functions
 
 func errorResponse(message string) gin.H {
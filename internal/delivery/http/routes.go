@@ -7,22 +7,36 @@ import (
 	"github.com/febry3/gamingin/internal/delivery/http/middleware"
 	"github.com/febry3/gamingin/internal/dto"
 	"github.com/febry3/gamingin/internal/helpers"
+	"github.com/febry3/gamingin/internal/infra/ratelimit"
+	"github.com/febry3/gamingin/internal/infra/session"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 type RouteConfig struct {
-	App      *gin.Engine
-	Auth     AuthHandler
-	User     UserHandler
-	Address  AddressHandler
-	Seller   SellerHandler
-	Product  ProductHandler
-	GroupBuy GroupBuyHandler
-	Order    OrderHandler
+	App          *gin.Engine
+	Auth         AuthHandler
+	User         UserHandler
+	Address      AddressHandler
+	Seller       SellerHandler
+	Product      ProductHandler
+	GroupBuy     GroupBuyHandler
+	Order        OrderHandler
+	TwoFactor    TwoFactorHandler
+	Notification NotificationHandler
+	Denylist     session.Denylist
+	Jwks         *JwksHandler
+
+	RateLimiter       ratelimit.Limiter
+	RateLimitPolicies map[string]ratelimit.Policy
 }
 
 func (routeConfig *RouteConfig) Init(jwt *helpers.JwtService) {
+	authMiddleware := middleware.AuthMiddleware(jwt, routeConfig.Denylist)
+	rateLimit := func(policy string) gin.HandlerFunc {
+		return middleware.RateLimitMiddleware(routeConfig.RateLimiter, routeConfig.RateLimitPolicies[policy])
+	}
+
 	corsConf := cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
@@ -33,14 +47,22 @@ func (routeConfig *RouteConfig) Init(jwt *helpers.JwtService) {
 	}
 	routeConfig.App.Use(cors.New(corsConf))
 
+	routeConfig.App.GET("/.well-known/jwks.json", routeConfig.Jwks.GetJwks)
+
 	v1 := routeConfig.App.Group("/v1/api")
 
 	auth := v1.Group("/auth")
-	auth.POST("/login", routeConfig.Auth.Login)
-	auth.POST("/register", routeConfig.Auth.Register)
+	auth.POST("/login", rateLimit("login"), routeConfig.Auth.Login)
+	auth.POST("/register", rateLimit("register"), routeConfig.Auth.Register)
 	auth.POST("/logout", routeConfig.Auth.Logout)
 	auth.POST("/refresh", routeConfig.Auth.RefreshToken)
-	auth.POST("/google", routeConfig.Auth.LoginOrRegisterWithGoogle)
+	auth.POST("/google", rateLimit("google"), routeConfig.Auth.LoginOrRegisterWithGoogle)
+	auth.POST("/verify-email", routeConfig.Auth.VerifyEmail)
+	auth.POST("/verify-email/resend", authMiddleware, routeConfig.Auth.ResendVerificationEmail)
+	auth.POST("/password/forgot", routeConfig.Auth.ForgotPassword)
+	auth.POST("/password/reset", routeConfig.Auth.ResetPassword)
+	auth.POST("/2fa/enroll", rateLimit("two_factor"), routeConfig.Auth.StartTwoFactorSetup)
+	auth.POST("/2fa/verify", rateLimit("two_factor"), routeConfig.Auth.VerifyTwoFactorLogin)
 
 	product := v1.Group("/product")
 	{
@@ -50,19 +72,44 @@ func (routeConfig *RouteConfig) Init(jwt *helpers.JwtService) {
 		product.GET("/variants/:id", routeConfig.Product.GetProductVariantByID)
 	}
 
-	protected := v1.Group("", middleware.AuthMiddleware(jwt))
+	v1.GET("/group-buy", routeConfig.GroupBuy.GetAllGroupBuySessionForBuyer)
+
+	protected := v1.Group("", authMiddleware)
 	{
 		protected.POST("/group-buy", routeConfig.GroupBuy.CreateBuyerSession)
 		protected.GET("/group-buy/:sessionId", routeConfig.GroupBuy.GetSessionForBuyerByCode)
-		protected.POST("/group-buy/:sessionId/join", routeConfig.GroupBuy.JoinSession)
+		protected.GET("/group-buy/:sessionId/events", routeConfig.GroupBuy.StreamSessionEvents)
+		protected.GET("/group-buy/:sessionId/invite", routeConfig.GroupBuy.CreateInvite)
+		protected.POST("/group-buy/:sessionId/join", rateLimit("group_buy_join"), routeConfig.GroupBuy.JoinSession)
+		protected.PATCH("/group-buy/:sessionId/quantity", routeConfig.GroupBuy.ChangeQuantity)
+		protected.POST("/group-buy/:sessionId/leave", routeConfig.GroupBuy.LeaveSession)
+		protected.DELETE("/group-buy/:sessionId/members/:userId", routeConfig.GroupBuy.RemoveMember)
+		protected.POST("/group-buy/campaigns/:id/reminder", routeConfig.GroupBuy.SubscribeReminder)
+		protected.DELETE("/group-buy/campaigns/:id/reminder", routeConfig.GroupBuy.UnsubscribeReminder)
 	}
 
-	protectedUser := v1.Group("/user", middleware.AuthMiddleware(jwt))
+	protectedUser := v1.Group("/user", authMiddleware)
 	{
 		protectedUser.GET("/test", testUserInline)
 		protectedUser.PUT("", routeConfig.User.UpdateUserProfile)
 		protectedUser.GET("", routeConfig.User.GetUserProfile)
 		protectedUser.POST("/avatar", routeConfig.User.UpdateUserAvatar)
+		protectedUser.PUT("/password", routeConfig.Auth.ChangePassword)
+		protectedUser.GET("/sessions", routeConfig.Auth.GetSessions)
+		protectedUser.POST("/sessions/revoke-others", routeConfig.Auth.RevokeOtherSessions)
+		protectedUser.DELETE("/sessions/:id", routeConfig.Auth.RevokeSession)
+		protectedUser.GET("/login-history", routeConfig.Auth.GetLoginHistory)
+		protectedUser.GET("/providers", routeConfig.Auth.GetAuthProviders)
+		protectedUser.POST("/providers/:provider", routeConfig.Auth.LinkProvider)
+		protectedUser.DELETE("/providers/:provider", routeConfig.Auth.UnlinkProvider)
+		protectedUser.GET("/2fa", routeConfig.TwoFactor.GetStatus)
+		protectedUser.POST("/2fa/enroll", routeConfig.TwoFactor.Enroll)
+		protectedUser.POST("/2fa/enable", routeConfig.TwoFactor.Enable)
+		protectedUser.POST("/2fa/disable", routeConfig.TwoFactor.Disable)
+		protectedUser.POST("/2fa/recovery-codes", routeConfig.TwoFactor.RegenerateRecoveryCodes)
+		protectedUser.GET("/notifications", routeConfig.Notification.GetNotifications)
+		protectedUser.PATCH("/notifications/:id/read", routeConfig.Notification.MarkRead)
+		protectedUser.POST("/notifications/read-all", routeConfig.Notification.MarkAllRead)
 		protectedUser.GET("/address", routeConfig.Address.GetAll)
 		protectedUser.POST("/address", routeConfig.Address.Create)
 		protectedUser.PUT("/address/:id", routeConfig.Address.Update)
@@ -73,12 +120,22 @@ func (routeConfig *RouteConfig) Init(jwt *helpers.JwtService) {
 		protectedUser.POST("/orders/group-buy", routeConfig.Order.CreateGroupBuyOrder)
 		protectedUser.GET("/orders", routeConfig.Order.GetOrders)
 		protectedUser.GET("/orders/:id", routeConfig.Order.GetOrderByID)
+		protectedUser.POST("/orders/:id/payment", routeConfig.Order.RegeneratePayment)
 	}
 
 	// Public webhook endpoint (Midtrans will call this)
-	v1.POST("/payments/webhook", routeConfig.Order.HandlePaymentNotification)
+	v1.POST("/payments/webhook", rateLimit("payment_webhook"), routeConfig.Order.HandlePaymentNotification)
 
-	protectedSeller := v1.Group("/seller", middleware.AuthMiddleware(jwt))
+	admin := v1.Group("/admin", authMiddleware, middleware.RoleMiddleware("admin"))
+	{
+		admin.GET("/payments/discrepancies", routeConfig.Order.GetPaymentDiscrepancies)
+		admin.GET("/payments/events", routeConfig.Order.GetPaymentEvents)
+		admin.POST("/payments/events/:id/replay", routeConfig.Order.ReplayPaymentEvent)
+		admin.GET("/2fa/policies", routeConfig.TwoFactor.GetPolicies)
+		admin.PUT("/2fa/policies/:role", routeConfig.TwoFactor.SetPolicy)
+	}
+
+	protectedSeller := v1.Group("/seller", authMiddleware)
 	{
 		protectedSeller.POST("", routeConfig.Seller.RegisterSeller)
 
@@ -99,7 +156,17 @@ func (routeConfig *RouteConfig) Init(jwt *helpers.JwtService) {
 			// Group Buy
 			sellerRole.POST("/group-buy", routeConfig.GroupBuy.CreateGroupBuySession)
 			sellerRole.GET("/group-buy", routeConfig.GroupBuy.GetAllGroupBuySessionForSeller)
+			sellerRole.GET("/group-buy/analytics", routeConfig.GroupBuy.GetCampaignAnalytics)
+			sellerRole.GET("/group-buy/analytics/export", routeConfig.GroupBuy.ExportCampaignAnalytics)
+			sellerRole.GET("/group-buy/:id/events", routeConfig.GroupBuy.StreamCampaignEvents)
 			sellerRole.PATCH("/group-buy/status", routeConfig.GroupBuy.ChangeGroupBuySessionStatus)
+			sellerRole.POST("/group-buy/:id/cancel", routeConfig.GroupBuy.CancelGroupBuySession)
+			sellerRole.POST("/group-buy/schedules", routeConfig.GroupBuy.CreateSchedule)
+			sellerRole.GET("/group-buy/schedules", routeConfig.GroupBuy.GetSchedules)
+			sellerRole.DELETE("/group-buy/schedules/:id", routeConfig.GroupBuy.StopSchedule)
+
+			// Orders
+			sellerRole.PATCH("/orders/:id/status", routeConfig.Order.UpdateOrderStatus)
 
 		}
 
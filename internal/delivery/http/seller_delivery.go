@@ -1,9 +1,11 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/febry3/gamingin/internal/dto"
+	"github.com/febry3/gamingin/internal/errorx"
 	"github.com/febry3/gamingin/internal/helpers"
 	"github.com/febry3/gamingin/internal/usecase"
 	"github.com/gin-gonic/gin"
@@ -49,7 +51,11 @@ func (sd *SellerHandler) RegisterSeller(c *gin.Context) {
 	seller, err := sd.sc.RegisterSeller(c.Request.Context(), req, jwt.ID, fileBytes)
 	if err != nil {
 		sd.log.Errorf("[SellerDelivery] Register Seller Error: %v", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{
+		status := http.StatusInternalServerError
+		if errors.Is(err, errorx.ErrEmailNotVerified) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
 			"message": "failed to register seller",
 			"error":   err.Error(),
 		})
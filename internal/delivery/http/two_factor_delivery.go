@@ -0,0 +1,244 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/febry3/gamingin/internal/dto"
+	"github.com/febry3/gamingin/internal/errorx"
+	"github.com/febry3/gamingin/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+)
+
+type TwoFactorHandler struct {
+	uc  usecase.TwoFactorUsecaseContract
+	log *logrus.Logger
+}
+
+func NewTwoFactorHandler(uc usecase.TwoFactorUsecaseContract, log *logrus.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{uc: uc, log: log}
+}
+
+func (t *TwoFactorHandler) GetStatus(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+
+	status, err := t.uc.GetStatus(c.Request.Context(), claims.ID, claims.Role)
+	if err != nil {
+		t.log.Errorf("[TwoFactorDelivery] Get Status Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get two-factor status",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "successfully get two-factor status",
+		"data":    status,
+	})
+}
+
+func (t *TwoFactorHandler) Enroll(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+
+	enrollment, err := t.uc.Enroll(c.Request.Context(), claims.ID)
+	if err != nil {
+		t.log.Errorf("[TwoFactorDelivery] Enroll Error: %s", err.Error())
+		c.AbortWithStatusJSON(twoFactorErrorStatus(err), gin.H{
+			"message": "failed to start two-factor setup",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "scan the code with your authenticator app",
+		"data":    enrollment,
+	})
+}
+
+func (t *TwoFactorHandler) Enable(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+
+	var request dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	codes, err := t.uc.Enable(c.Request.Context(), claims.ID, request)
+	if err != nil {
+		t.log.Errorf("[TwoFactorDelivery] Enable Error: %s", err.Error())
+		c.AbortWithStatusJSON(twoFactorErrorStatus(err), gin.H{
+			"message": "failed to enable two-factor authentication",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "two-factor authentication enabled, store the recovery codes somewhere safe",
+		"data":    codes,
+	})
+}
+
+func (t *TwoFactorHandler) Disable(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+
+	var request dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := t.uc.Disable(c.Request.Context(), claims.ID, claims.Role, request); err != nil {
+		t.log.Errorf("[TwoFactorDelivery] Disable Error: %s", err.Error())
+		c.AbortWithStatusJSON(twoFactorErrorStatus(err), gin.H{
+			"message": "failed to disable two-factor authentication",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "two-factor authentication disabled",
+	})
+}
+
+func (t *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	claims, err := getUserClaims(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "no user in context",
+		})
+		return
+	}
+
+	var request dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	codes, err := t.uc.RegenerateRecoveryCodes(c.Request.Context(), claims.ID, request)
+	if err != nil {
+		t.log.Errorf("[TwoFactorDelivery] Regenerate Recovery Codes Error: %s", err.Error())
+		c.AbortWithStatusJSON(twoFactorErrorStatus(err), gin.H{
+			"message": "failed to regenerate recovery codes",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "recovery codes regenerated, the old ones no longer work",
+		"data":    codes,
+	})
+}
+
+// GetPolicies handles GET /admin/2fa/policies
+func (t *TwoFactorHandler) GetPolicies(c *gin.Context) {
+	policies, err := t.uc.GetPolicies(c.Request.Context())
+	if err != nil {
+		t.log.Errorf("[TwoFactorDelivery] Get Policies Error: %s", err.Error())
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get two-factor policies",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "successfully get two-factor policies",
+		"data":    policies,
+	})
+}
+
+// SetPolicy handles PUT /admin/2fa/policies/:role
+func (t *TwoFactorHandler) SetPolicy(c *gin.Context) {
+	var request dto.TwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid request",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	policy, err := t.uc.SetPolicy(c.Request.Context(), c.Param("role"), request)
+	if err != nil {
+		t.log.Errorf("[TwoFactorDelivery] Set Policy Error: %s", err.Error())
+		c.AbortWithStatusJSON(twoFactorErrorStatus(err), gin.H{
+			"message": "failed to update two-factor policy",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "two-factor policy updated",
+		"data":    policy,
+	})
+}
+
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errorx.ErrTwoFactorCodeInvalid):
+		return http.StatusUnauthorized
+	case errors.Is(err, errorx.ErrTwoFactorRequired):
+		return http.StatusForbidden
+	case errors.Is(err, errorx.ErrTwoFactorAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, errorx.ErrTwoFactorNotEnrolled), errors.Is(err, errorx.ErrTwoFactorNotEnabled),
+		errors.Is(err, errorx.ErrRoleInvalid):
+		return http.StatusBadRequest
+	default:
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return http.StatusBadRequest
+		}
+		return http.StatusInternalServerError
+	}
+}
@@ -1,5 +1,11 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
+
 type RegisterRequest struct {
 	Username    string `json:"username" validate:"required"`
 	FirstName   string `json:"first_name" validate:"min=3,max=30"`
@@ -10,31 +16,41 @@ type RegisterRequest struct {
 }
 
 type RegisterResponse struct {
-	ID          int64  `json:"id" `
-	Username    string `json:"username"`
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	PhoneNumber string `json:"phone_number"`
-	Email       string `json:"email"`
+	ID            int64  `json:"id" `
+	Username      string `json:"username"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	PhoneNumber   string `json:"phone_number"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 type LoginRequest struct {
 	Email      string `json:"email" validate:"required,email"`
 	Password   string `json:"password" validate:"required,min=6"`
 	DeviceInfo string `json:"device_info"`
+	IPAddress  string `json:"-"`
 }
 
 type LoginResponse struct {
-	ID          int64  `json:"id" `
-	Username    string `json:"username"`
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	PhoneNumber string `json:"phone_number"`
-	Email       string `json:"email"`
-	Role        string `json:"role"`
-	AccessToken string `json:"access_token"`
-	ProfileUrl  string `json:"profile_url"`
-	SellerID    int64  `json:"seller_id"`
+	ID            int64  `json:"id" `
+	Username      string `json:"username"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	PhoneNumber   string `json:"phone_number"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	AccessToken   string `json:"access_token"`
+	ProfileUrl    string `json:"profile_url"`
+	SellerID      int64  `json:"seller_id"`
+	EmailVerified bool   `json:"email_verified"`
+
+	// set instead of the tokens when the account needs a second factor, the
+	// challenge token is exchanged at /auth/2fa/verify
+	TwoFactorRequired      bool     `json:"two_factor_required,omitempty"`
+	TwoFactorSetupRequired bool     `json:"two_factor_setup_required,omitempty"`
+	ChallengeToken         string   `json:"challenge_token,omitempty"`
+	RecoveryCodes          []string `json:"recovery_codes,omitempty"`
 }
 
 type LoginWithGoogleRequest struct {
@@ -48,4 +64,52 @@ type LoginWithGoogleData struct {
 	FirstName     string `json:"name"`
 	PictureUrl    string `json:"picture"`
 	DeviceInfo    string `json:"device_info"`
+	IPAddress     string `json:"-"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6,nefield=CurrentPassword"`
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceInfo string    `json:"device_info"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type LoginHistoryListResponse struct {
+	Histories  []entity.LoginHistory `json:"histories"`
+	TotalCount int64                 `json:"total_count"`
+	Page       int                   `json:"page"`
+	Limit      int                   `json:"limit"`
+}
+
+type AuthProviderResponse struct {
+	Provider string `json:"provider"`
+}
+
+type LinkProviderRequest struct {
+	// IDToken is the Google id token, Password sets a password for the email provider
+	IDToken  string `json:"id_token"`
+	Password string `json:"password"`
+}
+
+type SetPasswordRequest struct {
+	Password string `json:"password" validate:"required,min=6"`
 }
@@ -6,13 +6,32 @@ import (
 	"github.com/febry3/gamingin/internal/entity"
 )
 
+// GroupBuySessionRequest creates a campaign. MaxQuantityPerMember defaults to 1, a
+// campaign without StartsAt or with one in the past opens right away.
 type GroupBuySessionRequest struct {
-	ProductVariantID string                `json:"product_variant_id" binding:"required"`
-	MinParticipants  int                   `json:"min_participants" binding:"required,min=1"`
-	MaxParticipants  int                   `json:"max_participants" binding:"required,min=1"`
-	MaxQuantity      int                   `json:"max_quantity" binding:"required,min=1"`
-	ExpiresAt        time.Time             `json:"expires_at" binding:"required"`
-	Tiers            []GroupBuyTierRequest `json:"tiers" binding:"required,min=1,dive"`
+	ProductVariantID     string                `json:"product_variant_id" binding:"required"`
+	MinParticipants      int                   `json:"min_participants" binding:"required,min=1"`
+	MaxParticipants      int                   `json:"max_participants" binding:"required,min=1"`
+	MaxQuantity          int                   `json:"max_quantity" binding:"required,min=1"`
+	MaxQuantityPerMember int                   `json:"max_quantity_per_member" binding:"omitempty,min=1,ltefield=MaxQuantity"`
+	StartsAt             time.Time             `json:"starts_at"`
+	ExpiresAt            time.Time             `json:"expires_at" binding:"required"`
+	Tiers                []GroupBuyTierRequest `json:"tiers" binding:"required,min=1,dive"`
+}
+
+// GroupBuyScheduleRequest creates a recurring campaign. Recurrence is a 5 field cron
+// expression read in Timezone (Asia/Jakarta when omitted), "0 20 * * FRI" with a
+// DurationMinutes of 120 runs every Friday from 20:00 to 22:00.
+type GroupBuyScheduleRequest struct {
+	ProductVariantID     string                `json:"product_variant_id" binding:"required"`
+	MinParticipants      int                   `json:"min_participants" binding:"required,min=1"`
+	MaxParticipants      int                   `json:"max_participants" binding:"required,min=1"`
+	MaxQuantity          int                   `json:"max_quantity" binding:"required,min=1"`
+	MaxQuantityPerMember int                   `json:"max_quantity_per_member" binding:"omitempty,min=1,ltefield=MaxQuantity"`
+	Recurrence           string                `json:"recurrence" binding:"required"`
+	Timezone             string                `json:"timezone"`
+	DurationMinutes      int                   `json:"duration_minutes" binding:"required,min=1"`
+	Tiers                []GroupBuyTierRequest `json:"tiers" binding:"required,min=1,dive"`
 }
 
 type GroupBuyTierRequest struct {
@@ -29,6 +48,7 @@ type GroupBuySessionResponse struct {
 	MaxParticipants  int                    `json:"max_participants"`
 	Status           string                 `json:"status"`
 	MaxQuantity      int64                  `json:"max_quantity"`
+	StartsAt         time.Time              `json:"starts_at"`
 	ExpiresAt        time.Time              `json:"expires_at"`
 	CreatedAt        time.Time              `json:"created_at"`
 	UpdatedAt        time.Time              `json:"updated_at"`
@@ -36,15 +56,111 @@ type GroupBuySessionResponse struct {
 	Tiers            []entity.GroupBuyTier  `json:"tiers"`
 }
 
+// GroupBuyFeedFilter narrows the public list of campaigns
+type GroupBuyFeedFilter struct {
+	// Upcoming lists the announced campaigns that have not started instead of the open ones
+	Upcoming   bool
+	CategoryID int64
+	Sort       string
+	Page       int
+	Limit      int
+}
+
+// GroupBuyFeedItem is one active campaign in the public feed. Tiers apply per buyer
+// session, so the next tier is counted from the largest group still taking members.
+type GroupBuyFeedItem struct {
+	ID                  string                `json:"id"`
+	ProductID           string                `json:"product_id"`
+	ProductTitle        string                `json:"product_title"`
+	ProductSlug         string                `json:"product_slug"`
+	CategoryID          int64                 `json:"category_id"`
+	ImageURL            string                `json:"image_url,omitempty"`
+	ProductVariantID    string                `json:"product_variant_id"`
+	Status              string                `json:"status"`
+	VariantName         string                `json:"variant_name"`
+	Price               float64               `json:"price"`
+	Tiers               []entity.GroupBuyTier `json:"tiers"`
+	MaxDiscount         float64               `json:"max_discount"`
+	NextTierThreshold   int                   `json:"next_tier_threshold,omitempty"`
+	MinParticipants     int                   `json:"min_participants"`
+	MaxParticipants     int                   `json:"max_participants"`
+	CurrentParticipants int                   `json:"current_participants"`
+	RemainingQuantity   int64                 `json:"remaining_quantity"`
+	StartsAt            time.Time             `json:"starts_at"`
+	ExpiresAt           time.Time             `json:"expires_at"`
+	TimeLeftSeconds     int64                 `json:"time_left_seconds"`
+	OpenSessions        []GroupBuyFeedSession `json:"open_sessions"`
+}
+
+// GroupBuyFeedSession is a buyer session that still takes members
+type GroupBuyFeedSession struct {
+	SessionCode         string    `json:"session_code"`
+	Title               string    `json:"title"`
+	CurrentParticipants int       `json:"current_participants"`
+	SpotsLeft           int       `json:"spots_left"`
+	CurrentDiscount     float64   `json:"current_discount"`
+	NextTierThreshold   int       `json:"next_tier_threshold,omitempty"`
+	ExpiresAt           time.Time `json:"expires_at"`
+}
+
+type GroupBuyFeedResponse struct {
+	Campaigns  []GroupBuyFeedItem `json:"campaigns"`
+	TotalCount int64              `json:"total_count"`
+	Page       int                `json:"page"`
+	Limit      int                `json:"limit"`
+}
+
+// ChangeStatusRequest ends a campaign now, a cancellation tells the participants why
 type ChangeStatusRequest struct {
 	SessionID string `json:"session_id" binding:"required"`
-	Status    string `json:"status" binding:"required"`
+	Status    string `json:"status" binding:"required,oneof=completed cancelled"`
+	Reason    string `json:"reason" binding:"required_if=Status cancelled,max=500"`
+}
+
+type CancelGroupBuyRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
 }
 
 type CreateBuyerGroupSessionRequest struct {
 	OrganizerUserID  int64  `json:"organizer_user_id" binding:"required"`
 	ProductVariantID string `json:"product_variant_id" binding:"required"`
 	Title            string `json:"title" binding:"required"`
+	// Quantity is how many units the organizer claims, 1 when omitted
+	Quantity int `json:"quantity" binding:"omitempty,min=1"`
+}
+
+// JoinBuyerGroupSessionRequest is optional, a member joins with 1 unit by default
+type JoinBuyerGroupSessionRequest struct {
+	Quantity int `json:"quantity" binding:"omitempty,min=1"`
+}
+
+// GroupBuyQuantityRequest is the number of units a member claims in a buyer session
+type GroupBuyQuantityRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
+
+// GroupBuyInviteResponse is a member's share link for a buyer session, QRCode is a
+// base64 PNG of the same link
+type GroupBuyInviteResponse struct {
+	SessionCode string            `json:"session_code"`
+	URL         string            `json:"url"`
+	Token       string            `json:"token"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+	QRCode      string            `json:"qr_code"`
+	OpenGraph   OpenGraphMetadata `json:"open_graph"`
+}
+
+// OpenGraphMetadata is what chat apps render as the preview of a shared link
+type OpenGraphMetadata struct {
+	Title           string                `json:"og:title"`
+	Description     string                `json:"og:description"`
+	Image           string                `json:"og:image,omitempty"`
+	URL             string                `json:"og:url"`
+	Type            string                `json:"og:type"`
+	PriceAmount     float64               `json:"product:price:amount"`
+	PriceCurrency   string                `json:"product:price:currency"`
+	CurrentDiscount float64               `json:"current_discount"`
+	Tiers           []entity.GroupBuyTier `json:"tiers"`
 }
 
 type GetBuyerGroupSessionResponse struct {
@@ -53,3 +169,33 @@ type GetBuyerGroupSessionResponse struct {
 	ProductVariant *entity.ProductVariant    `json:"product_variant"`
 	ProductSession *entity.GroupBuySession   `json:"product_session"`
 }
+
+// GroupBuyFunnel follows the members of a campaign from joining to paying, the rates
+// are shares of the step before
+type GroupBuyFunnel struct {
+	Joined      int64   `json:"joined"`
+	Ordered     int64   `json:"ordered"`
+	Paid        int64   `json:"paid"`
+	OrderRate   float64 `json:"order_rate"`
+	PaymentRate float64 `json:"payment_rate"`
+}
+
+// GroupBuyCampaignAnalytics is the performance of one campaign, the tier reached is the
+// best one any buyer session got to
+type GroupBuyCampaignAnalytics struct {
+	GroupBuySessionID string         `json:"group_buy_session_id"`
+	ProductTitle      string         `json:"product_title"`
+	VariantName       string         `json:"variant_name"`
+	Status            string         `json:"status"`
+	StartsAt          time.Time      `json:"starts_at"`
+	ExpiresAt         time.Time      `json:"expires_at"`
+	BuyerSessions     int64          `json:"buyer_sessions"`
+	Members           int64          `json:"members"`
+	PaidMembers       int64          `json:"paid_members"`
+	UnitsSold         int64          `json:"units_sold"`
+	MaxQuantity       int64          `json:"max_quantity"`
+	Revenue           float64        `json:"revenue"`
+	TierThreshold     int            `json:"tier_threshold,omitempty"`
+	TierDiscount      float64        `json:"tier_discount,omitempty"`
+	Funnel            GroupBuyFunnel `json:"funnel"`
+}
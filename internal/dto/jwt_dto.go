@@ -8,5 +8,7 @@ type JwtPayload struct {
 	Email    string `json:"email"`
 	Role     string `json:"role"`
 	SellerID int64  `json:"seller_id"`
+	// SessionID is the refresh token the access token was issued from
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
@@ -0,0 +1,11 @@
+package dto
+
+import "github.com/febry3/gamingin/internal/entity"
+
+type NotificationListResponse struct {
+	Notifications []entity.Notification `json:"notifications"`
+	UnreadCount   int64                 `json:"unread_count"`
+	TotalCount    int64                 `json:"total_count"`
+	Page          int                   `json:"page"`
+	Limit         int                   `json:"limit"`
+}
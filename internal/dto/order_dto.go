@@ -1,6 +1,10 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
 
 // ========================================
 // Request DTOs
@@ -19,8 +23,16 @@ type CreateGroupBuyOrderRequest struct {
 	BuyerGroupSessionID string `json:"buyer_group_session_id" validate:"required,uuid"`
 	AddressID           string `json:"address_id" validate:"required,uuid"`
 	BankCode            string `json:"bank_code" validate:"required,oneof=bca bni bri mandiri permata cimb"`
-	CashBack            int64  `json:"cash_back" validate:""`
-	GroupBuyTierID      string `json:"product_group_buy_tier_id" validate:"required,uuid"`
+}
+
+// RegeneratePaymentRequest for issuing a new VA on a pending order
+type RegeneratePaymentRequest struct {
+	BankCode string `json:"bank_code" validate:"required,oneof=bca bni bri mandiri permata cimb"`
+}
+
+// UpdateOrderStatusRequest for the seller moving an order through shipping
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=shipped delivered"`
 }
 
 // ========================================
@@ -29,19 +41,21 @@ type CreateGroupBuyOrderRequest struct {
 
 // OrderResponse is the main order response
 type OrderResponse struct {
-	ID             string                  `json:"id"`
-	OrderNumber    string                  `json:"order_number"`
-	Status         string                  `json:"status"`
-	Quantity       int                     `json:"quantity"`
-	PriceAtOrder   float64                 `json:"price_at_order"`
-	Subtotal       float64                 `json:"subtotal"`
-	DeliveryCharge float64                 `json:"delivery_charge"`
-	TotalAmount    float64                 `json:"total_amount"`
-	Payment        *PaymentDetailResponse  `json:"payment,omitempty"`
-	Product        *OrderProductResponse   `json:"product,omitempty"`
-	ShippingDetail *ShippingDetailResponse `json:"shipping_detail,omitempty"`
-	Seller         *OrderSellerResponse    `json:"seller,omitempty"`
-	CreatedAt      time.Time               `json:"created_at"`
+	ID             string                   `json:"id"`
+	OrderNumber    string                   `json:"order_number"`
+	Status         string                   `json:"status"`
+	Quantity       int                      `json:"quantity"`
+	PriceAtOrder   float64                  `json:"price_at_order"`
+	Subtotal       float64                  `json:"subtotal"`
+	DiscountAmount float64                  `json:"discount_amount"`
+	Adjustments    []entity.OrderAdjustment `json:"adjustments,omitempty"`
+	DeliveryCharge float64                  `json:"delivery_charge"`
+	TotalAmount    float64                  `json:"total_amount"`
+	Payment        *PaymentDetailResponse   `json:"payment,omitempty"`
+	Product        *OrderProductResponse    `json:"product,omitempty"`
+	ShippingDetail *ShippingDetailResponse  `json:"shipping_detail,omitempty"`
+	Seller         *OrderSellerResponse     `json:"seller,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
 }
 
 // PaymentDetailResponse contains VA payment info
@@ -92,6 +106,14 @@ type OrderListResponse struct {
 	Limit      int             `json:"limit"`
 }
 
+// PaymentEventListResponse for paginated payment event list
+type PaymentEventListResponse struct {
+	Events     []entity.PaymentEvent `json:"events"`
+	TotalCount int64                 `json:"total_count"`
+	Page       int                   `json:"page"`
+	Limit      int                   `json:"limit"`
+}
+
 // ========================================
 // Midtrans Webhook DTOs
 // ========================================
@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+type TwoFactorStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RemainingRecoveryCodes int64      `json:"remaining_recovery_codes"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret string `json:"secret"`
+	// ProvisioningURI is shown to the user as a QR code for their authenticator app
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,number"`
+}
+
+type TwoFactorRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	// Code is a TOTP code, RecoveryCode one of the codes given at enrolment
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code"`
+	DeviceInfo   string `json:"-"`
+	IPAddress    string `json:"-"`
+}
+
+type TwoFactorPolicyRequest struct {
+	Required *bool `json:"required" validate:"required"`
+}
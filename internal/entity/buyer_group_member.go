@@ -4,12 +4,14 @@ import "time"
 
 type BuyerGroupMember struct {
 	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	SessionID string    `json:"session_id" gorm:"type:uuid;not null;index"`
-	UserID    int64     `json:"user_id" gorm:"not null;index"`
+	SessionID string    `json:"session_id" gorm:"type:uuid;not null;uniqueIndex:idx_buyer_group_members_session_user"`
+	UserID    int64     `json:"user_id" gorm:"not null;index;uniqueIndex:idx_buyer_group_members_session_user"`
 	OrderID   *string   `json:"order_id" gorm:"type:uuid;default:null"`
 	Quantity  int       `json:"quantity" gorm:"not null;default:1"`
 	Status    string    `json:"status" gorm:"default:joined;check:status IN ('joined','paid','cancelled')"`
 	JoinedAt  time.Time `json:"joined_at" gorm:"autoCreateTime;type:timestamptz"`
+	// ReferredByUserID is the member whose invite link brought this one in
+	ReferredByUserID *int64 `json:"referred_by_user_id,omitempty" gorm:"default:null;index"`
 
 	// Relationships
 	Session *BuyerGroupSession `json:"session,omitempty" gorm:"foreignKey:SessionID;references:ID;constraint:-;"`
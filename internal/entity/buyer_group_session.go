@@ -28,3 +28,18 @@ func (bgs *BuyerGroupSession) TableName() string {
 func (bgs *BuyerGroupSession) IsExpired() bool {
 	return bgs.ExpiresAt.Before(time.Now())
 }
+
+// HasMember reports whether the user already holds a membership, a user joins a session once
+func (bgs *BuyerGroupSession) HasMember(userID int64) bool {
+	for _, member := range bgs.Members {
+		if member.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFull reports whether the session reached the campaign's participant limit and takes no more joins
+func (bgs *BuyerGroupSession) IsFull(maxParticipants int) bool {
+	return bgs.CurrentParticipants >= maxParticipants
+}
@@ -0,0 +1,16 @@
+package entity
+
+// GroupBuyCampaignStats sums up the buyer sessions, members and orders of one campaign.
+// OrderedMembers placed an order, PaidMembers paid for it even if it was refunded later,
+// UnitsSold and Revenue only count orders that are still sold.
+type GroupBuyCampaignStats struct {
+	GroupBuySessionID string
+	BuyerSessions     int64
+	Members           int64
+	OrderedMembers    int64
+	PaidMembers       int64
+	UnitsSold         int64
+	Revenue           float64
+	// TopParticipants is the member count of the biggest buyer session that was not cancelled
+	TopParticipants int
+}
@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// GroupBuySchedule is the template of a recurring campaign. Each occurrence is
+// announced as an upcoming campaign, starts when Recurrence next matches in Timezone
+// and runs for DurationMinutes. Tiers holds the tiers copied to every occurrence.
+type GroupBuySchedule struct {
+	ID                   string         `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SellerID             int64          `json:"seller_id" gorm:"not null;index"`
+	ProductVariantID     string         `json:"product_variant_id" gorm:"type:uuid;not null"`
+	MinParticipants      int            `json:"min_participants" gorm:"not null"`
+	MaxParticipants      int            `json:"max_participants" gorm:"not null"`
+	MaxQuantity          int            `json:"max_quantity" gorm:"not null"`
+	MaxQuantityPerMember int            `json:"max_quantity_per_member" gorm:"not null;default:1"`
+	Tiers                datatypes.JSON `json:"tiers" gorm:"type:jsonb;not null"`
+	Recurrence           string         `json:"recurrence" gorm:"not null"`
+	Timezone             string         `json:"timezone" gorm:"not null"`
+	DurationMinutes      int            `json:"duration_minutes" gorm:"not null"`
+	IsActive             bool           `json:"is_active" gorm:"not null;default:true"`
+	CreatedAt            time.Time      `json:"created_at" gorm:"autoCreateTime;type:timestamptz"`
+	UpdatedAt            time.Time      `json:"updated_at" gorm:"autoUpdateTime;type:timestamptz"`
+}
+
+func (s *GroupBuySchedule) TableName() string {
+	return "group_buy_schedules"
+}
+
+// GroupBuyReminder asks for a notification when an upcoming campaign starts
+type GroupBuyReminder struct {
+	ID                string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	GroupBuySessionID string    `json:"group_buy_session_id" gorm:"type:uuid;not null;uniqueIndex:idx_group_buy_reminders_session_user"`
+	UserID            int64     `json:"user_id" gorm:"not null;uniqueIndex:idx_group_buy_reminders_session_user"`
+	CreatedAt         time.Time `json:"created_at" gorm:"autoCreateTime;type:timestamptz"`
+}
+
+func (r *GroupBuyReminder) TableName() string {
+	return "group_buy_reminders"
+}
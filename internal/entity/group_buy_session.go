@@ -4,18 +4,25 @@ import (
 	"time"
 )
 
+// GroupBuySession is a seller's campaign. An upcoming campaign reserves its stock and
+// opens at StartsAt, MaxQuantityPerMember caps the units one member claims and
+// MaxQuantity all claims together. ScheduleID is set on campaigns generated from a
+// recurring schedule.
 type GroupBuySession struct {
-	ID               string          `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	ProductVariantID string          `json:"product_variant_id" gorm:"type:uuid;not null"`
-	SellerID         int64           `json:"seller_id" gorm:"not null"`
-	MinParticipants  int             `json:"min_participants,omitempty" gorm:"not null"`
-	MaxParticipants  int             `json:"max_participants,omitempty" gorm:"not null"`
-	Status           string          `json:"status,omitempty" gorm:"default:active;check:status IN ('active','completed','cancelled')"`
-	MaxQuantity      int64           `json:"max_quantity,omitempty" gorm:"not null"`
-	ExpiresAt        time.Time       `json:"expires_at,omitempty" gorm:"not null;type:timestamptz"`
-	CreatedAt        time.Time       `json:"-" gorm:"autoCreateTime;type:timestamptz"`
-	UpdatedAt        time.Time       `json:"-" gorm:"autoUpdateTime;type:timestamptz"`
-	ProductVariant   *ProductVariant `json:"product_variant,omitempty" gorm:"foreignKey:ProductVariantID"`
+	ID                   string          `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ProductVariantID     string          `json:"product_variant_id" gorm:"type:uuid;not null"`
+	SellerID             int64           `json:"seller_id" gorm:"not null"`
+	MinParticipants      int             `json:"min_participants,omitempty" gorm:"not null"`
+	MaxParticipants      int             `json:"max_participants,omitempty" gorm:"not null"`
+	Status               string          `json:"status,omitempty" gorm:"default:active;check:status IN ('upcoming','active','completed','cancelled')"`
+	MaxQuantity          int64           `json:"max_quantity,omitempty" gorm:"not null"`
+	MaxQuantityPerMember int             `json:"max_quantity_per_member,omitempty" gorm:"not null;default:1"`
+	StartsAt             time.Time       `json:"starts_at,omitempty" gorm:"not null;default:now();type:timestamptz"`
+	ExpiresAt            time.Time       `json:"expires_at,omitempty" gorm:"not null;type:timestamptz"`
+	ScheduleID           *string         `json:"schedule_id,omitempty" gorm:"type:uuid;default:null;index"`
+	CreatedAt            time.Time       `json:"-" gorm:"autoCreateTime;type:timestamptz"`
+	UpdatedAt            time.Time       `json:"-" gorm:"autoUpdateTime;type:timestamptz"`
+	ProductVariant       *ProductVariant `json:"product_variant,omitempty" gorm:"foreignKey:ProductVariantID"`
 	// Seller           Seller         `json:"seller" gorm:"foreignKey:SellerID"`
 	GroupBuyTiers       []GroupBuyTier `json:"group_buy_tiers" gorm:"foreignKey:GroupBuySessionID"`
 	CurrentParticipants int64          `json:"current_participants"`
@@ -6,3 +6,33 @@ type GroupBuyTier struct {
 	ParticipantThreshold int     `json:"participant_threshold" gorm:"not null"` // e.g., 10, 50
 	DiscountPercentage   float64 `json:"discount_percentage" gorm:"type:decimal(5,2);not null"`
 }
+
+// ApplicableTier returns the tier with the highest threshold the participant count
+// has reached, or nil when no tier is reached yet
+func ApplicableTier(tiers []GroupBuyTier, participants int) *GroupBuyTier {
+	var best *GroupBuyTier
+	for i := range tiers {
+		if tiers[i].ParticipantThreshold > participants {
+			continue
+		}
+		if best == nil || tiers[i].ParticipantThreshold > best.ParticipantThreshold {
+			best = &tiers[i]
+		}
+	}
+	return best
+}
+
+// NextTier returns the tier with the lowest threshold the participant count has not
+// reached, or nil when the last tier is reached
+func NextTier(tiers []GroupBuyTier, participants int) *GroupBuyTier {
+	var next *GroupBuyTier
+	for i := range tiers {
+		if tiers[i].ParticipantThreshold <= participants {
+			continue
+		}
+		if next == nil || tiers[i].ParticipantThreshold < next.ParticipantThreshold {
+			next = &tiers[i]
+		}
+	}
+	return next
+}
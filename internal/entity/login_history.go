@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// LoginHistory is one sign in attempt. Failed attempts against unknown emails have
+// no user and only count towards the per IP limit.
+type LoginHistory struct {
+	ID            int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID        *int64    `json:"user_id,omitempty" gorm:"index:idx_login_histories_user_created"`
+	Email         string    `json:"-" gorm:"size:255"`
+	Method        string    `json:"method" gorm:"not null;size:20;default:email"`
+	IPAddress     string    `json:"ip_address" gorm:"size:45;index:idx_login_histories_ip_created"`
+	DeviceInfo    string    `json:"device_info" gorm:"size:255"`
+	Success       bool      `json:"success" gorm:"not null"`
+	FailureReason string    `json:"failure_reason,omitempty" gorm:"size:50"`
+	NewDevice     bool      `json:"new_device" gorm:"not null;default:false"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime;type:timestamptz;index:idx_login_histories_user_created;index:idx_login_histories_ip_created"`
+}
+
+func (l *LoginHistory) TableName() string {
+	return "login_histories"
+}
+
+// Login failure reason constants
+const (
+	LoginFailureUnknownEmail  = "unknown_email"
+	LoginFailureWrongPassword = "wrong_password"
+	LoginFailureLocked        = "locked"
+	LoginFailureIPBlocked     = "ip_blocked"
+	LoginFailureWrongCode     = "wrong_2fa_code"
+)
@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Notification is one entry of a user's in-app inbox. DedupeKey is unique per user,
+// an alert that was already delivered is not stored or emailed again.
+type Notification struct {
+	ID        string         `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID    int64          `json:"-" gorm:"not null;uniqueIndex:idx_notifications_user_dedupe;index:idx_notifications_user_created"`
+	Type      string         `json:"type" gorm:"not null;size:50"`
+	Title     string         `json:"title" gorm:"not null"`
+	Body      string         `json:"body" gorm:"not null"`
+	Data      datatypes.JSON `json:"data,omitempty" gorm:"type:jsonb"`
+	DedupeKey string         `json:"-" gorm:"not null;uniqueIndex:idx_notifications_user_dedupe"`
+	ReadAt    *time.Time     `json:"read_at" gorm:"type:timestamptz"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime;type:timestamptz;index:idx_notifications_user_created"`
+}
+
+func (n *Notification) TableName() string {
+	return "notifications"
+}
+
+// Notification type constants
+const (
+	NotificationGroupBuyTierReached = "group_buy_tier_reached"
+	NotificationGroupBuyTierNear    = "group_buy_tier_near"
+	NotificationGroupBuyStarted     = "group_buy_started"
+	NotificationGroupBuyCancelled   = "group_buy_cancelled"
+)
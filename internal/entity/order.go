@@ -12,6 +12,7 @@ type Order struct {
 	Quantity            int       `json:"quantity" gorm:"not null;default:1"`
 	PriceAtOrder        float64   `json:"price_at_order" gorm:"not null"`
 	Subtotal            float64   `json:"subtotal" gorm:"not null"`
+	DiscountAmount      float64   `json:"discount_amount" gorm:"default:0"`
 	DeliveryCharge      float64   `json:"delivery_charge" gorm:"default:0"`
 	TotalAmount         float64   `json:"total_amount" gorm:"not null"`
 	Status              string    `json:"status" gorm:"default:pending_payment"`
@@ -26,6 +27,7 @@ type Order struct {
 	BuyerGroupSession *BuyerGroupSession   `json:"buyer_group_session,omitempty" gorm:"foreignKey:BuyerGroupSessionID;references:ID"`
 	Payment           *Payment             `json:"payment,omitempty" gorm:"foreignKey:OrderID;references:ID"`
 	ShippingDetail    *OrderShippingDetail `json:"shipping_detail,omitempty" gorm:"foreignKey:OrderID;references:ID"`
+	Adjustments       []OrderAdjustment    `json:"adjustments,omitempty" gorm:"foreignKey:OrderID;references:ID"`
 }
 
 func (o *Order) TableName() string {
@@ -41,4 +43,5 @@ const (
 	OrderStatusDelivered      = "delivered"
 	OrderStatusCancelled      = "cancelled"
 	OrderStatusExpired        = "expired"
+	OrderStatusRefunded       = "refunded"
 )
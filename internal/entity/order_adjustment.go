@@ -2,11 +2,14 @@ package entity
 
 import "time"
 
+// OrderAdjustment is a discount on an order, the amount is negative. Group buy
+// adjustments are written when the order is placed and again when the session
+// closes on a better tier, the later ones are paid out to the buyer's wallet.
 type OrderAdjustment struct {
 	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	OrderID     string    `json:"order_id" gorm:"type:uuid;not null"`
+	OrderID     string    `json:"order_id" gorm:"type:uuid;not null;index"`
 	Description string    `json:"description" gorm:"not null"`
-	Amount      float64   `json:"amount" gorm:"not null"`
+	Amount      float64   `json:"amount" gorm:"type:decimal(15,2);not null"`
 	SourceType  string    `json:"source_type" gorm:"not null;check:source_type IN ('coupon','group_buy')"`
 	SourceID    string    `json:"source_id" gorm:"type:uuid;not null"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime;type:timestamptz"`
@@ -15,3 +18,9 @@ type OrderAdjustment struct {
 func (oa *OrderAdjustment) TableName() string {
 	return "order_adjustments"
 }
+
+// Order adjustment source type constants
+const (
+	AdjustmentSourceCoupon   = "coupon"
+	AdjustmentSourceGroupBuy = "group_buy"
+)
@@ -13,6 +13,7 @@ type Payment struct {
 	BillKey              string     `json:"bill_key,omitempty"`    // For Mandiri Bill Payment
 	BillerCode           string     `json:"biller_code,omitempty"` // For Mandiri Bill Payment
 	GatewayTransactionID string     `json:"gateway_transaction_id,omitempty"`
+	GatewayOrderID       string     `json:"gateway_order_id,omitempty" gorm:"index"` // order_id sent to the gateway, changes when the VA is regenerated
 	ExpiredAt            time.Time  `json:"expired_at" gorm:"not null;type:timestamptz"`
 	PaidAt               *time.Time `json:"paid_at,omitempty" gorm:"type:timestamptz"`
 	CreatedAt            time.Time  `json:"created_at" gorm:"autoCreateTime;type:timestamptz"`
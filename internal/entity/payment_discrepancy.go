@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// PaymentDiscrepancy records a payment whose gateway state disagrees with ours
+// in a way that cannot be fixed automatically and needs manual review.
+type PaymentDiscrepancy struct {
+	ID            string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	PaymentID     string     `json:"payment_id" gorm:"type:uuid;not null;uniqueIndex:ux_payment_discrepancies_payment_status"`
+	OrderID       string     `json:"order_id" gorm:"type:uuid;not null;index"`
+	OrderStatus   string     `json:"order_status" gorm:"not null"`
+	LocalStatus   string     `json:"local_status" gorm:"not null"`
+	GatewayStatus string     `json:"gateway_status" gorm:"not null;uniqueIndex:ux_payment_discrepancies_payment_status"`
+	Reason        string     `json:"reason" gorm:"type:text;not null"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty" gorm:"type:timestamptz"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime;type:timestamptz"`
+
+	// Relationships
+	Payment *Payment `json:"payment,omitempty" gorm:"foreignKey:PaymentID;references:ID"`
+	Order   *Order   `json:"order,omitempty" gorm:"foreignKey:OrderID;references:ID"`
+}
+
+func (pd *PaymentDiscrepancy) TableName() string {
+	return "payment_discrepancies"
+}
@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// PaymentEvent is a verified gateway notification stored as received. Each
+// transaction status is stored once and applied to the order by a worker task.
+type PaymentEvent struct {
+	ID                string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	TransactionID     string     `json:"transaction_id" gorm:"not null;uniqueIndex:ux_payment_events_transaction_status"`
+	TransactionStatus string     `json:"transaction_status" gorm:"not null;uniqueIndex:ux_payment_events_transaction_status"`
+	OrderNumber       string     `json:"order_number" gorm:"not null;index"` // gateway order id of the charge
+	Payload           string     `json:"payload" gorm:"type:jsonb;not null"`
+	Status            string     `json:"status" gorm:"not null;default:received"`
+	Error             string     `json:"error,omitempty" gorm:"type:text"`
+	Attempts          int        `json:"attempts" gorm:"not null;default:0"`
+	ProcessedAt       *time.Time `json:"processed_at,omitempty" gorm:"type:timestamptz"`
+	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime;type:timestamptz"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"autoUpdateTime;type:timestamptz"`
+}
+
+func (pe *PaymentEvent) TableName() string {
+	return "payment_events"
+}
+
+// Payment event status constants
+const (
+	PaymentEventStatusReceived  = "received"
+	PaymentEventStatusProcessed = "processed"
+	PaymentEventStatusIgnored   = "ignored"
+	PaymentEventStatusFailed    = "failed"
+)
@@ -5,12 +5,14 @@ import (
 )
 
 type RefreshToken struct {
-	TokenId    string    `json:"token_id" gorm:"primaryKey;"`
-	UserId     int64     `json:"user_id" gorm:"not null;uniqueIndex:ux_refresh_tokens_user_device"`
+	TokenId string `json:"token_id" gorm:"primaryKey;"`
+	// FamilyID is shared by every token rotated from the same login, it identifies the session
+	FamilyID   string    `json:"family_id" gorm:"not null;index"`
+	UserId     int64     `json:"user_id" gorm:"not null;uniqueIndex:ux_refresh_tokens_user_device,where:is_revoked = false"`
 	TokenHash  string    `json:"token_hash" gorm:"not null;size:255;uniqueIndex"`
 	Role       string    `json:"role" gorm:"not null;default:user;check:role IN ('user','seller','admin')"`
 	IsRevoked  bool      `json:"is_revoked" gorm:"not null;default:false;index"`
-	DeviceInfo string    `json:"device_info" gorm:"size:255;uniqueIndex:ux_refresh_tokens_user_device"`
+	DeviceInfo string    `json:"device_info" gorm:"size:255;uniqueIndex:ux_refresh_tokens_user_device,where:is_revoked = false"`
 	ExpiresAt  time.Time `json:"expired_at" gorm:"not null;index;type:timestamptz"`
 	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime;type:timestamptz"`
 }
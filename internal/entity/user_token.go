@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// UserToken is a single-use token sent to the user by email, or handed to the
+// client between the two steps of a 2FA login. Only the sha256 hash of the token
+// is stored, the plain value lives in the link we send.
+type UserToken struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID    int64      `json:"user_id" gorm:"not null;index:idx_user_tokens_user_purpose"`
+	Purpose   string     `json:"purpose" gorm:"not null;size:50;index:idx_user_tokens_user_purpose"`
+	TokenHash string     `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null;type:timestamptz"`
+	UsedAt    *time.Time `json:"used_at,omitempty" gorm:"type:timestamptz"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime;type:timestamptz"`
+}
+
+func (t *UserToken) TableName() string {
+	return "user_tokens"
+}
+
+func (t *UserToken) IsExpired() bool {
+	return t.ExpiresAt.Before(time.Now())
+}
+
+// User token purpose constants
+const (
+	UserTokenPurposeEmailVerification = "email_verification"
+	UserTokenPurposePasswordReset     = "password_reset"
+	UserTokenPurposeTwoFactorLogin    = "two_factor_login"
+)
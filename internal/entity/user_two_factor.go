@@ -0,0 +1,49 @@
+package entity
+
+import "time"
+
+// UserTwoFactor holds the TOTP secret of a user, encrypted with the server's 2FA
+// key. The row exists from enrolment, 2FA is only on once EnabledAt is set.
+type UserTwoFactor struct {
+	UserID    int64      `json:"user_id" gorm:"primaryKey"`
+	Secret    string     `json:"-" gorm:"not null;type:text"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty" gorm:"type:timestamptz"`
+	// LastUsedStep is the TOTP time step of the last accepted code, codes from
+	// the same or an earlier step are refused so a code works only once
+	LastUsedStep int64     `json:"-" gorm:"not null;default:0"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime;type:timestamptz"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime;type:timestamptz"`
+}
+
+func (t *UserTwoFactor) TableName() string {
+	return "user_two_factors"
+}
+
+func (t *UserTwoFactor) IsEnabled() bool {
+	return t.EnabledAt != nil
+}
+
+// TwoFactorRecoveryCode is a single-use code that replaces a TOTP code when the
+// user has lost their authenticator. Only the sha256 hash is stored.
+type TwoFactorRecoveryCode struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID    int64      `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	UsedAt    *time.Time `json:"used_at,omitempty" gorm:"type:timestamptz"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime;type:timestamptz"`
+}
+
+func (c *TwoFactorRecoveryCode) TableName() string {
+	return "two_factor_recovery_codes"
+}
+
+// TwoFactorPolicy makes 2FA mandatory for every account of a role
+type TwoFactorPolicy struct {
+	Role      string    `json:"role" gorm:"primaryKey;type:text;check:role IN ('user','seller','admin')"`
+	Required  bool      `json:"required" gorm:"not null;default:false"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime;type:timestamptz"`
+}
+
+func (p *TwoFactorPolicy) TableName() string {
+	return "two_factor_policies"
+}
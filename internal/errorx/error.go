@@ -8,16 +8,47 @@ var (
 	ErrInvalidLogin       = errors.New("invalid email or password")
 	ErrUserNotFound       = errors.New("user not found")
 
+	ErrAccountLocked        = errors.New("account is temporarily locked after too many failed logins, please try again later")
+	ErrTooManyLoginAttempts = errors.New("too many failed logins, please try again later")
+
+	ErrEmailNotVerified         = errors.New("email address is not verified")
+	ErrEmailAlreadyVerified     = errors.New("email address is already verified")
+	ErrVerificationTokenInvalid = errors.New("invalid verification token")
+	ErrVerificationTokenExpired = errors.New("verification token expired")
+	ErrVerificationRateLimited  = errors.New("verification email was sent recently, please try again later")
+	ErrResetTokenInvalid        = errors.New("invalid password reset token")
+	ErrResetTokenExpired        = errors.New("password reset token expired")
+	ErrNoPassword               = errors.New("account has no password, sign in with google instead")
+
+	ErrProviderNotSupported    = errors.New("sign-in provider is not supported")
+	ErrProviderNotLinked       = errors.New("sign-in provider is not linked to this account")
+	ErrProviderAlreadyLinked   = errors.New("sign-in provider is already linked to this account")
+	ErrProviderLinkedToAnother = errors.New("this provider account is already linked to another user")
+	ErrLastProvider            = errors.New("cannot remove the last sign-in method of the account")
+
+	ErrTwoFactorNotEnrolled      = errors.New("two-factor authentication is not set up")
+	ErrTwoFactorNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorRequired         = errors.New("two-factor authentication is required for your role")
+	ErrTwoFactorCodeInvalid      = errors.New("invalid two-factor code")
+	ErrTwoFactorChallengeInvalid = errors.New("invalid or expired two-factor challenge")
+	ErrRoleInvalid               = errors.New("role is invalid")
+
 	ErrTokenEmpty   = errors.New("token is empty")
 	ErrTokenInvalid = errors.New("invalid refresh token")
 	ErrTokenRevoked = errors.New("refresh token revoked")
 	ErrTokenExpired = errors.New("refresh token expired")
+	ErrTokenReused  = errors.New("refresh token reuse detected")
 	ErrParseToken   = errors.New("failed to parse token")
 
+	ErrSessionNotFound = errors.New("session not found")
+
 	ErrSellerAlreadyExists = errors.New("seller already exists")
 
 	ErrInsufficientStock = errors.New("product variant stock is not enough")
 
+	ErrNotificationNotFound = errors.New("notification not found")
+
 	// related to group buying feature
 	ErrConflict                = errors.New("failed to purcase")
 	ErrNoStock                 = errors.New("no stock available")
@@ -25,6 +56,21 @@ var (
 	ErrSessionAlreadyStarted   = errors.New("you already started a session")
 	ErrGroupBuySessionNotFound = errors.New("group buy session not found")
 	ErrSessionFull             = errors.New("session is already full")
+	ErrNotSessionMember        = errors.New("user is not a member of this session")
+	ErrNotSessionOrganizer     = errors.New("only the organizer can remove members")
+	ErrMemberAlreadyPaid       = errors.New("member has already paid for this session")
+	ErrMemberHasOrder          = errors.New("member has an order waiting for payment")
+	ErrCannotRemoveSelf        = errors.New("use leave to remove yourself from the session")
+	ErrInvalidInvite           = errors.New("invite link is invalid or expired")
+	ErrQuantityPerMember       = errors.New("quantity is above the limit per member")
+	ErrQuantityUnavailable     = errors.New("not enough units left in this group buy")
+	ErrInvalidCampaignWindow   = errors.New("group buy must end after it starts")
+	ErrInvalidRecurrence       = errors.New("invalid group buy recurrence")
+	ErrScheduleNotFound        = errors.New("group buy schedule not found")
+	ErrCampaignNotUpcoming     = errors.New("group buy has already started")
+	ErrNotCampaignOwner        = errors.New("group buy belongs to another seller")
+	ErrCampaignNotCancellable  = errors.New("group buy has already ended")
+	ErrInvalidCampaignStatus   = errors.New("group buy status must be completed or cancelled")
 )
 
 // Custom error types for HTTP-semantic errors
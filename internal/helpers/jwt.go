@@ -2,7 +2,8 @@ package helpers
 
 import (
 	"errors"
-	"log"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/febry3/gamingin/internal/dto"
@@ -12,7 +13,9 @@ import (
 )
 
 type JwtConfig struct {
-	Secret     string
+	// Keys are every key tokens may be verified with, ActiveKID is the one that signs
+	Keys       map[string]*JwtKey
+	ActiveKID  string
 	AccessTTL  time.Duration
 	RefreshTTL time.Duration
 }
@@ -22,35 +25,52 @@ type JwtService struct {
 	log    *logrus.Logger
 }
 
-func NewJwtService(config JwtConfig, log *logrus.Logger) *JwtService {
-	return &JwtService{Config: config, log: log}
+func NewJwtService(config JwtConfig, log *logrus.Logger) (*JwtService, error) {
+	active, ok := config.Keys[config.ActiveKID]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q not found", config.ActiveKID)
+	}
+	if active.Private == nil {
+		return nil, fmt.Errorf("active jwt key %q has no private key", config.ActiveKID)
+	}
+	return &JwtService{Config: config, log: log}, nil
 }
 
-func (j *JwtService) IssueAccessToken(payload dto.JwtPayload) string {
+func (j *JwtService) IssueAccessToken(payload dto.JwtPayload) (string, error) {
+	key := j.Config.Keys[j.Config.ActiveKID]
+
 	now := time.Now().UTC()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	token := jwt.NewWithClaims(key.Method, jwt.MapClaims{
 		"username":  payload.Username,
 		"email":     payload.Email,
 		"user_id":   payload.ID,
 		"role":      payload.Role,
 		"seller_id": payload.SellerID,
+		"sid":       payload.SessionID,
 		"exp":       jwt.NewNumericDate(now.Add(j.Config.AccessTTL)),
 		"iat":       jwt.NewNumericDate(now),
 	})
+	token.Header["kid"] = key.KID
 
-	signedToken, err := token.SignedString([]byte(j.Config.Secret))
+	signedToken, err := token.SignedString(key.Private)
 	if err != nil {
-		log.Fatalf("signing token error: %v", err)
+		j.log.Errorf("[JwtService] Signing token error: %v", err)
+		return "", err
 	}
-	return signedToken
+	return signedToken, nil
 }
 
 func (j *JwtService) VerifyToken(tokenString string) (*dto.JwtPayload, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &dto.JwtPayload{}, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := j.Config.Keys[kid]
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(j.Config.Secret), nil
+		return key.Public, nil
 	})
 
 	if err != nil {
@@ -67,3 +87,13 @@ func (j *JwtService) VerifyToken(tokenString string) (*dto.JwtPayload, error) {
 
 	return claims, nil
 }
+
+// Jwks returns the public keys other services can verify our tokens with
+func (j *JwtService) Jwks() []Jwk {
+	keys := make([]Jwk, 0, len(j.Config.Keys))
+	for _, key := range j.Config.Keys {
+		keys = append(keys, key.Jwk())
+	}
+	sort.Slice(keys, func(a, b int) bool { return keys[a].Kid < keys[b].Kid })
+	return keys
+}
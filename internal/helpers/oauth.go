@@ -3,11 +3,20 @@ package helpers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
 	"github.com/febry3/gamingin/internal/dto"
 	"golang.org/x/oauth2"
-	"io"
 )
 
+const googleTokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+var ErrInvalidIDToken = errors.New("invalid google id token")
+
 func GetGoogleUserInfo(ctx context.Context, token *oauth2.Token, gauth *oauth2.Config) (dto.LoginWithGoogleData, error) {
 	client := gauth.Client(ctx, token)
 	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
@@ -29,3 +38,53 @@ func GetGoogleUserInfo(ctx context.Context, token *oauth2.Token, gauth *oauth2.C
 
 	return userInfo, nil
 }
+
+// VerifyGoogleIDToken checks the id token with Google and that it was issued to
+// clientID. The signature and expiry are checked by the tokeninfo endpoint.
+func VerifyGoogleIDToken(ctx context.Context, idToken string, clientID string) (dto.LoginWithGoogleData, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, googleTokenInfoURL+"?id_token="+url.QueryEscape(idToken), nil)
+	if err != nil {
+		return dto.LoginWithGoogleData{}, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return dto.LoginWithGoogleData{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return dto.LoginWithGoogleData{}, ErrInvalidIDToken
+	}
+
+	var claims struct {
+		Sub           string `json:"sub"`
+		Aud           string `json:"aud"`
+		Iss           string `json:"iss"`
+		Email         string `json:"email"`
+		EmailVerified string `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return dto.LoginWithGoogleData{}, err
+	}
+
+	if claims.Aud != clientID {
+		return dto.LoginWithGoogleData{}, fmt.Errorf("%w: issued to another client", ErrInvalidIDToken)
+	}
+	if claims.Iss != "accounts.google.com" && claims.Iss != "https://accounts.google.com" {
+		return dto.LoginWithGoogleData{}, fmt.Errorf("%w: unexpected issuer", ErrInvalidIDToken)
+	}
+	if claims.Sub == "" {
+		return dto.LoginWithGoogleData{}, ErrInvalidIDToken
+	}
+
+	return dto.LoginWithGoogleData{
+		ID:            claims.Sub,
+		Email:         claims.Email,
+		VerifiedEmail: claims.EmailVerified == "true",
+		FirstName:     claims.Name,
+		PictureUrl:    claims.Picture,
+	}, nil
+}
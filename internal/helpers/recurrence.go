@@ -0,0 +1,30 @@
+package helpers
+
+import (
+	"fmt"
+	"time"
+	// schedules name IANA zones, the alpine image ships without zoneinfo
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+)
+
+// NextOccurrence returns the first time after after that recurrence matches. The
+// recurrence is a standard 5 field cron expression read in timezone, so
+// "0 20 * * FRI" with "Asia/Jakarta" is every Friday at 20:00 WIB.
+func NextOccurrence(recurrence string, timezone string, after time.Time) (time.Time, error) {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return time.Time{}, fmt.Errorf("unknown timezone %q: %w", timezone, err)
+	}
+
+	schedule, err := cron.ParseStandard(fmt.Sprintf("CRON_TZ=%s %s", timezone, recurrence))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid recurrence %q: %w", recurrence, err)
+	}
+
+	next := schedule.Next(after)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("recurrence %q never matches", recurrence)
+	}
+	return next, nil
+}
@@ -0,0 +1,79 @@
+package invite
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const qrCodeSize = 256
+
+var ErrInvalidToken = errors.New("invite link is invalid or expired")
+
+// Signer builds share links for buyer sessions. The token in a link names the member
+// who shared it and when it stops working, the signature ties both to the session
+// code so neither can be swapped.
+type Signer struct {
+	key     []byte
+	baseURL string
+}
+
+func NewSigner(key []byte, baseURL string) *Signer {
+	return &Signer{key: key, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Link returns the share URL of a session and the token it carries
+func (s *Signer) Link(sessionCode string, referrerUserID int64, expiresAt time.Time) (string, string) {
+	token := s.Token(sessionCode, referrerUserID, expiresAt)
+	link := fmt.Sprintf("%s/group-buy/%s?invite=%s", s.baseURL, url.PathEscape(sessionCode), url.QueryEscape(token))
+	return link, token
+}
+
+// Token is <referrer>.<expiry unix>.<signature>
+func (s *Signer) Token(sessionCode string, referrerUserID int64, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%d.%d", referrerUserID, expiresAt.Unix())
+	return payload + "." + s.sign(sessionCode, payload)
+}
+
+// Verify returns the referrer of a token issued for sessionCode
+func (s *Signer) Verify(sessionCode string, token string, now time.Time) (int64, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidToken
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(sessionCode, payload))) {
+		return 0, ErrInvalidToken
+	}
+
+	referrerUserID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() >= expiresAt {
+		return 0, ErrInvalidToken
+	}
+
+	return referrerUserID, nil
+}
+
+func (s *Signer) sign(sessionCode string, payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(sessionCode + "." + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// QRCode renders content as a PNG QR code
+func QRCode(content string) ([]byte, error) {
+	return qrcode.Encode(content, qrcode.Medium, qrCodeSize)
+}
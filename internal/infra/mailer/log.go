@@ -0,0 +1,21 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogMailer only logs emails, for local development without an SMTP server
+type LogMailer struct {
+	log *logrus.Logger
+}
+
+func NewLogMailer(log *logrus.Logger) Mailer {
+	return &LogMailer{log: log}
+}
+
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	m.log.Infof("[LogMailer] To=%s Subject=%q\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
@@ -0,0 +1,14 @@
+package mailer
+
+import "context"
+
+// Message is a rendered email ready to be sent
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
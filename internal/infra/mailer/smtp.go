@@ -0,0 +1,26 @@
+package mailer
+
+import (
+	"context"
+
+	"gopkg.in/mail.v2"
+)
+
+type SMTPMailer struct {
+	dialer *mail.Dialer
+	from   string
+}
+
+func NewSMTPMailer(dialer *mail.Dialer, from string) Mailer {
+	return &SMTPMailer{dialer: dialer, from: from}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	msg := mail.NewMessage()
+	msg.SetHeader("From", m.from)
+	msg.SetHeader("To", message.To)
+	msg.SetHeader("Subject", message.Subject)
+	msg.SetBody("text/html", message.Body)
+
+	return m.dialer.DialAndSend(msg)
+}
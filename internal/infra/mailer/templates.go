@@ -0,0 +1,86 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// Template IDs, each one is templates/<id>.html
+const (
+	TemplateOrderCreated        = "order_created"
+	TemplateOrderPaid           = "order_paid"
+	TemplateOrderExpired        = "order_expired"
+	TemplateOrderShipped        = "order_shipped"
+	TemplateOrderDelivered      = "order_delivered"
+	TemplateOrderRefunded       = "order_refunded"
+	TemplateGroupBuyJoined      = "group_buy_joined"
+	TemplateGroupBuySucceeded   = "group_buy_succeeded"
+	TemplateGroupBuyFailed      = "group_buy_failed"
+	TemplateGroupBuyRemoved     = "group_buy_removed"
+	TemplateGroupBuyTierReached = "group_buy_tier_reached"
+	TemplateGroupBuyTierNear    = "group_buy_tier_near"
+	TemplateGroupBuyStarted     = "group_buy_started"
+	TemplateGroupBuyCancelled   = "group_buy_cancelled"
+	TemplateVerifyEmail         = "verify_email"
+	TemplateWelcome             = "welcome"
+	TemplatePasswordReset       = "password_reset"
+	TemplatePasswordChanged     = "password_changed"
+	TemplateAccountLocked       = "account_locked"
+	TemplateSignInChanged       = "sign_in_method_changed"
+	TemplateTwoFactorChanged    = "two_factor_changed"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Renderer turns a template ID and its data into a subject and an HTML body.
+// Every template defines a "subject" and a "content" block, the shared layout
+// wraps the content.
+type Renderer struct {
+	templates map[string]*template.Template
+}
+
+func NewRenderer() (*Renderer, error) {
+	entries, err := templateFS.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*template.Template)
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".html")
+		if name == "layout" {
+			continue
+		}
+
+		tmpl, err := template.ParseFS(templateFS, "templates/layout.html", "templates/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		templates[name] = tmpl
+	}
+
+	return &Renderer{templates: templates}, nil
+}
+
+func (r *Renderer) Render(templateID string, data any) (string, string, error) {
+	tmpl, ok := r.templates[templateID]
+	if !ok {
+		return "", "", fmt.Errorf("unknown email template: %s", templateID)
+	}
+
+	var subject bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject of %s: %w", templateID, err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&body, "layout", data); err != nil {
+		return "", "", fmt.Errorf("failed to render body of %s: %w", templateID, err)
+	}
+
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
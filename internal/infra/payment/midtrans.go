@@ -119,6 +119,9 @@ func (m *MidtransGateway) ChargeVA(ctx context.Context, orderID string, amount i
 func (m *MidtransGateway) GetTransactionStatus(ctx context.Context, orderID string) (*PaymentStatusResult, error) {
 	resp, err := m.client.CheckTransaction(orderID)
 	if err != nil {
+		if err.GetStatusCode() == 404 {
+			return nil, ErrTransactionNotFound
+		}
 		m.log.Errorf("Midtrans CheckTransaction error for order %s: %v", orderID, err)
 		return nil, fmt.Errorf("failed to check transaction status: %w", err)
 	}
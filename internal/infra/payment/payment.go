@@ -2,9 +2,14 @@ package payment
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrTransactionNotFound is returned when the gateway has no transaction for the order id,
+// e.g. the charge never reached it
+var ErrTransactionNotFound = errors.New("transaction not found")
+
 // VAPaymentResult represents the result of creating a VA payment
 type VAPaymentResult struct {
 	TransactionID string
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter is the in-process sliding window used in tests and when a single
+// instance runs without Redis.
+type MemoryLimiter struct {
+	mu       sync.Mutex
+	requests map[string][]time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{requests: make(map[string][]time.Time)}
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit int, window time.Duration) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	kept := l.requests[key][:0]
+	for _, at := range l.requests[key] {
+		if now.Sub(at) < window {
+			kept = append(kept, at)
+		}
+	}
+
+	allowed := len(kept) < limit
+	if allowed {
+		kept = append(kept, now)
+	}
+	l.requests[key] = kept
+
+	resetAfter := window
+	if len(kept) > 0 {
+		resetAfter = kept[0].Add(window).Sub(now)
+	}
+
+	return Result{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  max(limit-len(kept), 0),
+		ResetAfter: resetAfter,
+	}, nil
+}
@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Key sources a policy can count requests by
+const (
+	KeyByIP    = "ip"
+	KeyByUser  = "user"
+	KeyByRoute = "route"
+)
+
+// Policy allows Limit requests per key within any sliding Window
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+	KeyBy  string
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is when the oldest counted request leaves the window
+	ResetAfter time.Duration
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
@@ -0,0 +1,23 @@
+package realtime
+
+import (
+	"context"
+	"time"
+)
+
+// Event is one update pushed to live subscribers of a channel
+type Event struct {
+	Type string         `json:"type"`
+	Data map[string]any `json:"data,omitempty"`
+	At   time.Time      `json:"at"`
+}
+
+// Broker fans events out to every subscriber of a channel, whichever process
+// published them. Delivery is best effort, a subscriber that falls behind or
+// is not connected misses events.
+type Broker interface {
+	Publish(ctx context.Context, channel string, event Event) error
+	// Subscribe returns the events of channel until the returned close function is
+	// called, the events channel is closed after that
+	Subscribe(ctx context.Context, channel string) (<-chan Event, func(), error)
+}
@@ -0,0 +1,57 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const channelPrefix = "realtime:"
+
+// subscriberBuffer is how many events a slow subscriber can lag behind before
+// new ones are dropped for it
+const subscriberBuffer = 32
+
+type RedisBroker struct {
+	client *redis.Client
+}
+
+func NewRedisBroker(client *redis.Client) Broker {
+	return &RedisBroker{client: client}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, channel string, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, channelPrefix+channel, payload).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, channel string) (<-chan Event, func(), error) {
+	pubsub := b.client.Subscribe(ctx, channelPrefix+channel)
+
+	// wait for the subscription so no event published after this returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, err
+	}
+
+	events := make(chan Event, subscriberBuffer)
+	go func() {
+		defer close(events)
+		for msg := range pubsub.Channel() {
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			default:
+			}
+		}
+	}()
+
+	return events, func() { pubsub.Close() }, nil
+}
@@ -0,0 +1,13 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// Denylist holds the session IDs whose access tokens must be rejected before they
+// expire. An entry only has to outlive the access tokens issued for the session.
+type Denylist interface {
+	Revoke(ctx context.Context, sessionID string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, sessionID string) (bool, error)
+}
@@ -0,0 +1,30 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const denylistKeyPrefix = "session:revoked:"
+
+type RedisDenylist struct {
+	client *redis.Client
+}
+
+func NewRedisDenylist(client *redis.Client) Denylist {
+	return &RedisDenylist{client: client}
+}
+
+func (d *RedisDenylist) Revoke(ctx context.Context, sessionID string, ttl time.Duration) error {
+	return d.client.Set(ctx, denylistKeyPrefix+sessionID, 1, ttl).Err()
+}
+
+func (d *RedisDenylist) IsRevoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := d.client.Exists(ctx, denylistKeyPrefix+sessionID).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
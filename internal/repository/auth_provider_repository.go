@@ -10,4 +10,10 @@ type AuthProviderRepository interface {
 	Create(ctx context.Context, authProvider *entity.AuthProvider) error
 	FindByUserID(ctx context.Context, userId int64) (entity.AuthProvider, error)
 	FindByProviderId(ctx context.Context, providerId string, provider string) (entity.AuthProvider, error)
+	FindByUserIDAndProvider(ctx context.Context, userId int64, provider string) (entity.AuthProvider, error)
+	UpdatePassword(ctx context.Context, authProviderId int64, hashedPassword string) error
+	FindAllByUserID(ctx context.Context, userId int64) ([]entity.AuthProvider, error)
+	// FindAllByUserIDForUpdate locks the user's providers until the transaction ends
+	FindAllByUserIDForUpdate(ctx context.Context, userId int64) ([]entity.AuthProvider, error)
+	Delete(ctx context.Context, authProviderId int64) error
 }
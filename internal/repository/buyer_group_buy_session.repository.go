@@ -13,5 +13,15 @@ type BuyerGroupBuySessionRepository interface {
 	Delete(ctx context.Context, sessionID string) error
 	AddMember(ctx context.Context, buyer_session *entity.BuyerGroupSession) error
 	ChangeBuyerSessionStatus(ctx context.Context, buyerSessionID string, status string) error
+	// ChangeBuyerSessionStatusFrom only moves a session that is in one of the from statuses
+	// and reports whether it did
+	ChangeBuyerSessionStatusFrom(ctx context.Context, buyerSessionID string, from []string, status string) (bool, error)
 	GetSessionByID(ctx context.Context, buyerSessionID string) (*entity.BuyerGroupSession, error)
+	// GetSessionByIDForUpdate locks the session row until the surrounding transaction ends, members are loaded
+	GetSessionByIDForUpdate(ctx context.Context, buyerSessionID string) (*entity.BuyerGroupSession, error)
+	RemoveMember(ctx context.Context, buyer_session *entity.BuyerGroupSession) error
+	ChangeOrganizer(ctx context.Context, buyerSessionID string, organizerUserID int64) error
+	GetSessionsByGroupBuySessionID(ctx context.Context, groupBuySessionID string) ([]entity.BuyerGroupSession, error)
+	// GetActiveSessionsByGroupBuySessionIDs returns the sessions of several campaigns that were not cancelled
+	GetActiveSessionsByGroupBuySessionIDs(ctx context.Context, groupBuySessionIDs []string) ([]entity.BuyerGroupSession, error)
 }
@@ -10,4 +10,10 @@ type BuyerGroupMemberRepository interface {
 	Create(ctx context.Context, member *entity.BuyerGroupMember) error
 	Delete(ctx context.Context, memberID string) error
 	GetMembersBySessionID(ctx context.Context, sessionID string) ([]entity.BuyerGroupMember, error)
+	UpdateQuantity(ctx context.Context, memberID string, quantity int) error
+	// UpdateStatusByUser sets the status of the user's membership in a buyer session
+	UpdateStatusByUser(ctx context.Context, sessionID string, userID int64, status string) error
+	// SumClaimedQuantity adds up the units members of a campaign hold, leaving out
+	// excludeMemberID when it is set. Unpaid members of expired sessions hold nothing.
+	SumClaimedQuantity(ctx context.Context, groupBuySessionID string, excludeMemberID string) (int, error)
 }
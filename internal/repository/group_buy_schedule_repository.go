@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
+
+type GroupBuyScheduleRepository interface {
+	Create(ctx context.Context, schedule *entity.GroupBuySchedule) error
+	FindByID(ctx context.Context, scheduleID string) (*entity.GroupBuySchedule, error)
+	GetAllForSeller(ctx context.Context, sellerID int64) ([]entity.GroupBuySchedule, error)
+	// GetActive returns every schedule that still generates campaigns
+	GetActive(ctx context.Context) ([]entity.GroupBuySchedule, error)
+	// Deactivate stops a seller's schedule and reports whether it was found
+	Deactivate(ctx context.Context, scheduleID string, sellerID int64) (bool, error)
+}
+
+type GroupBuyReminderRepository interface {
+	// Create is a no-op when the user already asked to be reminded
+	Create(ctx context.Context, reminder *entity.GroupBuyReminder) error
+	Delete(ctx context.Context, groupBuySessionID string, userID int64) error
+	GetUserIDsByGroupBuySessionID(ctx context.Context, groupBuySessionID string) ([]int64, error)
+}
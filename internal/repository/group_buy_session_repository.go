@@ -2,16 +2,37 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/febry3/gamingin/internal/entity"
 )
 
+// Sort orders of the buyer feed, anything else lists the newest campaigns first
+const (
+	GroupBuySortEndingSoon      = "ending_soon"
+	GroupBuySortStartingSoon    = "starting_soon"
+	GroupBuySortBiggestDiscount = "biggest_discount"
+)
+
 type GroupBuySessionRepository interface {
 	Create(ctx context.Context, session *entity.GroupBuySession) error
 	FindByID(ctx context.Context, sessionID string) (*entity.GroupBuySession, error)
+	// FindByIDForUpdate locks the session row until the surrounding transaction ends
+	FindByIDForUpdate(ctx context.Context, sessionID string) (*entity.GroupBuySession, error)
+	// FindByProductVariantID returns the newest campaign of the variant that is open right now
 	FindByProductVariantID(ctx context.Context, productVariantID string) (*entity.GroupBuySession, error)
+	// ExistsForSchedule reports whether the occurrence of a schedule starting at startsAt was generated
+	ExistsForSchedule(ctx context.Context, scheduleID string, startsAt time.Time) (bool, error)
+	// FindUpcomingDue returns the upcoming campaigns whose start time has passed
+	FindUpcomingDue(ctx context.Context, now time.Time) ([]entity.GroupBuySession, error)
+	// CancelUpcomingBySchedule calls off the occurrences of a schedule that have not started
+	CancelUpcomingBySchedule(ctx context.Context, scheduleID string) error
 	Delete(ctx context.Context, sessionID string) error
 	GetAllForSeller(ctx context.Context, sellerID int64) ([]entity.GroupBuySession, error)
-	GetAllForBuyer(ctx context.Context) ([]entity.GroupBuySession, error)
+	// GetStatsForSeller aggregates every campaign of the seller in one query, keyed by campaign.
+	// Orders in soldStatuses count as sold, orders in paidStatuses as paid.
+	GetStatsForSeller(ctx context.Context, sellerID int64, soldStatuses []string, paidStatuses []string) (map[string]entity.GroupBuyCampaignStats, error)
+	// GetAllForBuyer lists the campaigns in status that have not expired, a zero categoryID matches every category
+	GetAllForBuyer(ctx context.Context, status string, categoryID int64, sort string, limit, offset int) ([]entity.GroupBuySession, int64, error)
 	ChangeStatus(ctx context.Context, sessionID string, status string, sellerID int64) error
 }
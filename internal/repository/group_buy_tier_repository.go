@@ -9,4 +9,5 @@ import (
 type GroupBuyTierRepository interface {
 	Create(ctx context.Context, tier *entity.GroupBuyTier) error
 	FindByID(ctx context.Context, tierID string) (*entity.GroupBuyTier, error)
+	FindBySessionID(ctx context.Context, groupBuySessionID string) ([]entity.GroupBuyTier, error)
 }
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
+
+type LoginHistoryRepository interface {
+	Create(ctx context.Context, history *entity.LoginHistory) error
+	CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int64, error)
+	// HasSucceededFromDevice reports whether the user ever signed in from the device
+	HasSucceededFromDevice(ctx context.Context, userID int64, deviceInfo string) (bool, error)
+	FindByUserID(ctx context.Context, userID int64, limit, offset int) ([]entity.LoginHistory, int64, error)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
+
+type NotificationRepository interface {
+	// Create stores the notification unless the user already has one with the same
+	// dedupe key, and reports whether it did
+	Create(ctx context.Context, notification *entity.Notification) (bool, error)
+	FindByUserID(ctx context.Context, userID int64, limit, offset int) ([]entity.Notification, int64, error)
+	CountUnread(ctx context.Context, userID int64) (int64, error)
+	MarkRead(ctx context.Context, userID int64, notificationID string) (bool, error)
+	MarkAllRead(ctx context.Context, userID int64) (int64, error)
+}
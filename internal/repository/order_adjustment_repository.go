@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
+
+type OrderAdjustmentRepository interface {
+	Create(ctx context.Context, adjustment *entity.OrderAdjustment) error
+	FindByOrderID(ctx context.Context, orderID string) ([]entity.OrderAdjustment, error)
+	// SumByOrderID adds up the adjustments of one source type on the order
+	SumByOrderID(ctx context.Context, orderID string, sourceType string) (float64, error)
+}
@@ -1,12 +1,23 @@
 package repository
 
-import "github.com/febry3/gamingin/internal/entity"
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
 
 type OrderRepository interface {
-	Create(order *entity.Order) error
-	FindByID(orderID string) (*entity.Order, error)
-	FindByOrderNumber(orderNumber string) (*entity.Order, error)
-	FindByUserID(userID int64, limit, offset int) ([]entity.Order, int64, error)
-	Update(order *entity.Order) error
-	UpdateStatus(orderID, status string) error
+	Create(ctx context.Context, order *entity.Order) error
+	FindByID(ctx context.Context, orderID string) (*entity.Order, error)
+	// FindByIDForUpdate locks the order row until the surrounding transaction ends
+	FindByIDForUpdate(ctx context.Context, orderID string) (*entity.Order, error)
+	FindByOrderNumber(ctx context.Context, orderNumber string) (*entity.Order, error)
+	FindByUserID(ctx context.Context, userID int64, limit, offset int) ([]entity.Order, int64, error)
+	FindByBuyerGroupSessionID(ctx context.Context, buyerSessionID string) ([]entity.Order, error)
+	// SumGroupBuyQuantity adds up the quantity of the campaign's orders in the given statuses
+	SumGroupBuyQuantity(ctx context.Context, groupBuySessionID string, statuses []string) (int, error)
+	// SumGroupBuyQuantities is SumGroupBuyQuantity for several campaigns, keyed by campaign id
+	SumGroupBuyQuantities(ctx context.Context, groupBuySessionIDs []string, statuses []string) (map[string]int, error)
+	Update(ctx context.Context, order *entity.Order) error
+	UpdateStatus(ctx context.Context, orderID, status string) error
 }
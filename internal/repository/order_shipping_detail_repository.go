@@ -1,8 +1,12 @@
 package repository
 
-import "github.com/febry3/gamingin/internal/entity"
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
 
 type OrderShippingDetailRepository interface {
-	Create(detail *entity.OrderShippingDetail) error
-	FindByOrderID(orderID string) (*entity.OrderShippingDetail, error)
+	Create(ctx context.Context, detail *entity.OrderShippingDetail) error
+	FindByOrderID(ctx context.Context, orderID string) (*entity.OrderShippingDetail, error)
 }
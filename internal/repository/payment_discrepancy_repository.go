@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
+
+type PaymentDiscrepancyRepository interface {
+	// Create stores a discrepancy, ignoring duplicates for the same payment and gateway status
+	Create(ctx context.Context, discrepancy *entity.PaymentDiscrepancy) error
+	FindUnresolved(ctx context.Context) ([]entity.PaymentDiscrepancy, error)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
+
+type PaymentEventRepository interface {
+	// Create stores the event and reports false when the same transaction status was already stored
+	Create(ctx context.Context, event *entity.PaymentEvent) (bool, error)
+	FindByID(ctx context.Context, eventID string) (*entity.PaymentEvent, error)
+	// FindByIDForUpdate locks the event row until the surrounding transaction ends
+	FindByIDForUpdate(ctx context.Context, eventID string) (*entity.PaymentEvent, error)
+	FindByTransaction(ctx context.Context, transactionID, transactionStatus string) (*entity.PaymentEvent, error)
+	FindAll(ctx context.Context, status string, limit, offset int) ([]entity.PaymentEvent, int64, error)
+	Update(ctx context.Context, event *entity.PaymentEvent) error
+}
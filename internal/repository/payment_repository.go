@@ -1,12 +1,23 @@
 package repository
 
-import "github.com/febry3/gamingin/internal/entity"
+import (
+	"context"
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
 
 type PaymentRepository interface {
-	Create(payment *entity.Payment) error
-	FindByOrderID(orderID string) (*entity.Payment, error)
-	FindByGatewayTransactionID(txID string) (*entity.Payment, error)
-	FindExpiredPending() ([]entity.Payment, error)
-	Update(payment *entity.Payment) error
-	UpdateStatus(paymentID, status string) error
+	Create(ctx context.Context, payment *entity.Payment) error
+	FindByOrderID(ctx context.Context, orderID string) (*entity.Payment, error)
+	// FindByOrderIDForUpdate locks the payment row until the surrounding transaction ends
+	FindByOrderIDForUpdate(ctx context.Context, orderID string) (*entity.Payment, error)
+	FindByGatewayOrderID(ctx context.Context, gatewayOrderID string) (*entity.Payment, error)
+	FindByGatewayTransactionID(ctx context.Context, txID string) (*entity.Payment, error)
+	FindExpiredPending(ctx context.Context) ([]entity.Payment, error)
+	FindPending(ctx context.Context) ([]entity.Payment, error)
+	// FindClosedSince returns non-pending payments updated after since, used to catch late gateway changes
+	FindClosedSince(ctx context.Context, since time.Time) ([]entity.Payment, error)
+	Update(ctx context.Context, payment *entity.Payment) error
+	UpdateStatus(ctx context.Context, paymentID, status string) error
 }
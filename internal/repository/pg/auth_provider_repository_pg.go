@@ -7,6 +7,7 @@ import (
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/errorx"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AuthProviderPg struct {
@@ -48,3 +49,53 @@ func (a *AuthProviderPg) FindByProviderId(ctx context.Context, authProviderId st
 	}
 	return authProvider, nil
 }
+
+func (a *AuthProviderPg) FindByUserIDAndProvider(ctx context.Context, userId int64, provider string) (entity.AuthProvider, error) {
+	authProvider := entity.AuthProvider{}
+	db := TxFromContext(ctx, a.db)
+	err := db.WithContext(ctx).First(&authProvider, "user_id = ? AND provider = ?", userId, provider).Error
+	if err != nil {
+		return entity.AuthProvider{}, err
+	}
+	return authProvider, nil
+}
+
+func (a *AuthProviderPg) UpdatePassword(ctx context.Context, authProviderId int64, hashedPassword string) error {
+	db := TxFromContext(ctx, a.db)
+	return db.WithContext(ctx).
+		Model(&entity.AuthProvider{}).
+		Where("auth_provider_id = ?", authProviderId).
+		Update("password", hashedPassword).Error
+}
+
+func (a *AuthProviderPg) FindAllByUserID(ctx context.Context, userId int64) ([]entity.AuthProvider, error) {
+	var authProviders []entity.AuthProvider
+	db := TxFromContext(ctx, a.db)
+	err := db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("auth_provider_id ASC").
+		Find(&authProviders).Error
+	if err != nil {
+		return nil, err
+	}
+	return authProviders, nil
+}
+
+func (a *AuthProviderPg) FindAllByUserIDForUpdate(ctx context.Context, userId int64) ([]entity.AuthProvider, error) {
+	var authProviders []entity.AuthProvider
+	db := TxFromContext(ctx, a.db)
+	err := db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userId).
+		Order("auth_provider_id ASC").
+		Find(&authProviders).Error
+	if err != nil {
+		return nil, err
+	}
+	return authProviders, nil
+}
+
+func (a *AuthProviderPg) Delete(ctx context.Context, authProviderId int64) error {
+	db := TxFromContext(ctx, a.db)
+	return db.WithContext(ctx).Delete(&entity.AuthProvider{}, "auth_provider_id = ?", authProviderId).Error
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BuyerGroupBuySessionRepositoryPg struct {
@@ -37,7 +38,7 @@ func (b *BuyerGroupBuySessionRepositoryPg) GetSessionByCode(ctx context.Context,
 func (b *BuyerGroupBuySessionRepositoryPg) GetSessionByOrganizerUserID(ctx context.Context, organizerUserID int64) (*entity.BuyerGroupSession, error) {
 	var session *entity.BuyerGroupSession
 
-	if err := b.db.WithContext(ctx).Where("organizer_user_id = ? AND status = ?", organizerUserID, "open").First(&session).Error; err != nil {
+	if err := b.db.WithContext(ctx).Where("organizer_user_id = ? AND status = ? AND expires_at > NOW()", organizerUserID, "open").First(&session).Error; err != nil {
 		return nil, err
 	}
 	return session, nil
@@ -51,3 +52,50 @@ func (b *BuyerGroupBuySessionRepositoryPg) GetSessionByID(ctx context.Context, b
 	}
 	return session, nil
 }
+
+func (b *BuyerGroupBuySessionRepositoryPg) GetSessionByIDForUpdate(ctx context.Context, buyerSessionID string) (*entity.BuyerGroupSession, error) {
+	db := TxFromContext(ctx, b.db)
+	var session entity.BuyerGroupSession
+
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", buyerSessionID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("session_id = ?", session.ID).Order("joined_at ASC").Find(&session.Members).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (b *BuyerGroupBuySessionRepositoryPg) RemoveMember(ctx context.Context, buyer_session *entity.BuyerGroupSession) error {
+	db := TxFromContext(ctx, b.db)
+	return db.Model(buyer_session).Update("current_participants", gorm.Expr("GREATEST(current_participants - 1, 0)")).Error
+}
+
+func (b *BuyerGroupBuySessionRepositoryPg) ChangeOrganizer(ctx context.Context, buyerSessionID string, organizerUserID int64) error {
+	db := TxFromContext(ctx, b.db)
+	return db.Model(&entity.BuyerGroupSession{}).Where("id = ?", buyerSessionID).Update("organizer_user_id", organizerUserID).Error
+}
+
+func (b *BuyerGroupBuySessionRepositoryPg) GetActiveSessionsByGroupBuySessionIDs(ctx context.Context, groupBuySessionIDs []string) ([]entity.BuyerGroupSession, error) {
+	var sessions []entity.BuyerGroupSession
+	if len(groupBuySessionIDs) == 0 {
+		return sessions, nil
+	}
+
+	if err := b.db.WithContext(ctx).
+		Where("group_buy_session_id IN ? AND status <> ?", groupBuySessionIDs, "cancelled").
+		Order("current_participants DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (b *BuyerGroupBuySessionRepositoryPg) GetSessionsByGroupBuySessionID(ctx context.Context, groupBuySessionID string) ([]entity.BuyerGroupSession, error) {
+	var sessions []entity.BuyerGroupSession
+
+	if err := b.db.WithContext(ctx).Where("group_buy_session_id = ?", groupBuySessionID).Preload("Members").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -22,7 +22,8 @@ func (b *BuyerGroupMemberRepositoryPg) Create(ctx context.Context, member *entit
 }
 
 func (b *BuyerGroupMemberRepositoryPg) Delete(ctx context.Context, memberID string) error {
-	return b.db.WithContext(ctx).Delete(&entity.BuyerGroupMember{}, memberID).Error
+	db := TxFromContext(ctx, b.db)
+	return db.Where("id = ?", memberID).Delete(&entity.BuyerGroupMember{}).Error
 }
 
 func (b *BuyerGroupMemberRepositoryPg) GetMembersBySessionID(ctx context.Context, sessionID string) ([]entity.BuyerGroupMember, error) {
@@ -33,10 +34,44 @@ func (b *BuyerGroupMemberRepositoryPg) GetMembersBySessionID(ctx context.Context
 	return member, nil
 }
 
+func (b *BuyerGroupMemberRepositoryPg) UpdateQuantity(ctx context.Context, memberID string, quantity int) error {
+	db := TxFromContext(ctx, b.db)
+	return db.Model(&entity.BuyerGroupMember{}).Where("id = ?", memberID).Update("quantity", quantity).Error
+}
+
+func (b *BuyerGroupMemberRepositoryPg) UpdateStatusByUser(ctx context.Context, sessionID string, userID int64, status string) error {
+	db := TxFromContext(ctx, b.db)
+	return db.Model(&entity.BuyerGroupMember{}).Where("session_id = ? AND user_id = ?", sessionID, userID).Update("status", status).Error
+}
+
+func (b *BuyerGroupMemberRepositoryPg) SumClaimedQuantity(ctx context.Context, groupBuySessionID string, excludeMemberID string) (int, error) {
+	db := TxFromContext(ctx, b.db)
+	query := db.Table("buyer_group_members AS m").
+		Joins("JOIN buyer_group_sessions AS s ON s.id = m.session_id").
+		Where("s.group_buy_session_id = ?", groupBuySessionID).
+		Where("s.status <> ? AND m.status <> ?", "cancelled", "cancelled").
+		Where("(s.status <> ? OR m.status = ?)", "expired", "paid")
+	if excludeMemberID != "" {
+		query = query.Where("m.id <> ?", excludeMemberID)
+	}
+
+	var total int
+	err := query.Select("COALESCE(SUM(m.quantity), 0)").Scan(&total).Error
+	return total, err
+}
+
 func (b *BuyerGroupBuySessionRepositoryPg) AddMember(ctx context.Context, buyer_session *entity.BuyerGroupSession) error {
-	return b.db.WithContext(ctx).Model(buyer_session).Update("current_participants", buyer_session.CurrentParticipants+1).Error
+	db := TxFromContext(ctx, b.db)
+	return db.Model(buyer_session).Update("current_participants", gorm.Expr("current_participants + 1")).Error
 }
 
 func (b *BuyerGroupBuySessionRepositoryPg) ChangeBuyerSessionStatus(ctx context.Context, buyerSessionID string, status string) error {
-	return b.db.WithContext(ctx).Model(&entity.BuyerGroupSession{}).Where("id = ?", buyerSessionID).Update("status", status).Error
+	db := TxFromContext(ctx, b.db)
+	return db.Model(&entity.BuyerGroupSession{}).Where("id = ?", buyerSessionID).Update("status", status).Error
+}
+
+func (b *BuyerGroupBuySessionRepositoryPg) ChangeBuyerSessionStatusFrom(ctx context.Context, buyerSessionID string, from []string, status string) (bool, error) {
+	db := TxFromContext(ctx, b.db)
+	result := db.Model(&entity.BuyerGroupSession{}).Where("id = ? AND status IN ?", buyerSessionID, from).Update("status", status)
+	return result.RowsAffected > 0, result.Error
 }
@@ -0,0 +1,84 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GroupBuyScheduleRepositoryPg struct {
+	db *gorm.DB
+}
+
+func NewGroupBuyScheduleRepositoryPg(db *gorm.DB) repository.GroupBuyScheduleRepository {
+	return &GroupBuyScheduleRepositoryPg{db: db}
+}
+
+func (r *GroupBuyScheduleRepositoryPg) Create(ctx context.Context, schedule *entity.GroupBuySchedule) error {
+	return TxFromContext(ctx, r.db).Create(schedule).Error
+}
+
+func (r *GroupBuyScheduleRepositoryPg) FindByID(ctx context.Context, scheduleID string) (*entity.GroupBuySchedule, error) {
+	var schedule entity.GroupBuySchedule
+	if err := TxFromContext(ctx, r.db).Where("id = ?", scheduleID).First(&schedule).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *GroupBuyScheduleRepositoryPg) GetAllForSeller(ctx context.Context, sellerID int64) ([]entity.GroupBuySchedule, error) {
+	var schedules []entity.GroupBuySchedule
+	if err := r.db.WithContext(ctx).Where("seller_id = ?", sellerID).Order("created_at DESC").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *GroupBuyScheduleRepositoryPg) GetActive(ctx context.Context) ([]entity.GroupBuySchedule, error) {
+	var schedules []entity.GroupBuySchedule
+	if err := r.db.WithContext(ctx).Where("is_active = ?", true).Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *GroupBuyScheduleRepositoryPg) Deactivate(ctx context.Context, scheduleID string, sellerID int64) (bool, error) {
+	result := TxFromContext(ctx, r.db).
+		Model(&entity.GroupBuySchedule{}).
+		Where("id = ? AND seller_id = ?", scheduleID, sellerID).
+		Update("is_active", false)
+	return result.RowsAffected > 0, result.Error
+}
+
+type GroupBuyReminderRepositoryPg struct {
+	db *gorm.DB
+}
+
+func NewGroupBuyReminderRepositoryPg(db *gorm.DB) repository.GroupBuyReminderRepository {
+	return &GroupBuyReminderRepositoryPg{db: db}
+}
+
+func (r *GroupBuyReminderRepositoryPg) Create(ctx context.Context, reminder *entity.GroupBuyReminder) error {
+	return TxFromContext(ctx, r.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_buy_session_id"}, {Name: "user_id"}},
+		DoNothing: true,
+	}).Create(reminder).Error
+}
+
+func (r *GroupBuyReminderRepositoryPg) Delete(ctx context.Context, groupBuySessionID string, userID int64) error {
+	return TxFromContext(ctx, r.db).
+		Where("group_buy_session_id = ? AND user_id = ?", groupBuySessionID, userID).
+		Delete(&entity.GroupBuyReminder{}).Error
+}
+
+func (r *GroupBuyReminderRepositoryPg) GetUserIDsByGroupBuySessionID(ctx context.Context, groupBuySessionID string) ([]int64, error) {
+	var userIDs []int64
+	err := r.db.WithContext(ctx).
+		Model(&entity.GroupBuyReminder{}).
+		Where("group_buy_session_id = ?", groupBuySessionID).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
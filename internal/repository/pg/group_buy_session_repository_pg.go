@@ -2,10 +2,12 @@ package pg
 
 import (
 	"context"
+	"time"
 
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GroupBuySessionRepositoryPg struct {
@@ -17,7 +19,7 @@ func NewGroupBuySessionRepositoryPg(db *gorm.DB) repository.GroupBuySessionRepos
 }
 
 func (g *GroupBuySessionRepositoryPg) ChangeStatus(ctx context.Context, sessionID string, status string, sellerID int64) error {
-	return g.db.WithContext(ctx).Model(&entity.GroupBuySession{}).Where("id = ? and seller_id = ?", sessionID, sellerID).Update("status", status).Error
+	return TxFromContext(ctx, g.db).Model(&entity.GroupBuySession{}).Where("id = ? and seller_id = ?", sessionID, sellerID).Update("status", status).Error
 }
 
 func (g *GroupBuySessionRepositoryPg) GetAllForSeller(ctx context.Context, sellerID int64) ([]entity.GroupBuySession, error) {
@@ -39,16 +41,88 @@ func (g *GroupBuySessionRepositoryPg) GetAllForSeller(ctx context.Context, selle
 	return sessions, nil
 }
 
-func (g *GroupBuySessionRepositoryPg) GetAllForBuyer(ctx context.Context) ([]entity.GroupBuySession, error) {
-	var sessions []entity.GroupBuySession
-	if err := g.db.Select("Sellers").Preload("ProductVariant").Preload("GroupBuyTiers").Order("created_at DESC").Find(&sessions).Error; err != nil {
+func (g *GroupBuySessionRepositoryPg) GetStatsForSeller(ctx context.Context, sellerID int64, soldStatuses []string, paidStatuses []string) (map[string]entity.GroupBuyCampaignStats, error) {
+	// a member has at most one row per buyer session, so every order is joined once
+	var rows []entity.GroupBuyCampaignStats
+	err := g.db.WithContext(ctx).Raw(`
+		SELECT g.id AS group_buy_session_id,
+			COUNT(DISTINCT bs.id) AS buyer_sessions,
+			COUNT(DISTINCT m.id) AS members,
+			COUNT(DISTINCT m.id) FILTER (WHERE o.id IS NOT NULL) AS ordered_members,
+			COUNT(DISTINCT m.id) FILTER (WHERE o.status IN ?) AS paid_members,
+			COALESCE(SUM(o.quantity) FILTER (WHERE o.status IN ?), 0) AS units_sold,
+			COALESCE(SUM(o.total_amount) FILTER (WHERE o.status IN ?), 0) AS revenue,
+			COALESCE(MAX(bs.current_participants) FILTER (WHERE bs.status <> 'cancelled'), 0) AS top_participants
+		FROM group_buy_sessions g
+		LEFT JOIN buyer_group_sessions bs ON bs.group_buy_session_id = g.id
+		LEFT JOIN buyer_group_members m ON m.session_id = bs.id
+		LEFT JOIN orders o ON o.buyer_group_session_id = bs.id AND o.user_id = m.user_id
+		WHERE g.seller_id = ?
+		GROUP BY g.id
+	`, paidStatuses, soldStatuses, soldStatuses, sellerID).Scan(&rows).Error
+	if err != nil {
 		return nil, err
 	}
-	return sessions, nil
+
+	stats := make(map[string]entity.GroupBuyCampaignStats, len(rows))
+	for _, row := range rows {
+		stats[row.GroupBuySessionID] = row
+	}
+	return stats, nil
+}
+
+func (g *GroupBuySessionRepositoryPg) GetAllForBuyer(ctx context.Context, status string, categoryID int64, sort string, limit, offset int) ([]entity.GroupBuySession, int64, error) {
+	query := g.db.WithContext(ctx).
+		Model(&entity.GroupBuySession{}).
+		Where("group_buy_sessions.status = ? AND group_buy_sessions.expires_at > NOW()", status)
+
+	if categoryID != 0 {
+		query = query.
+			Joins("JOIN product_variants ON product_variants.id = group_buy_sessions.product_variant_id").
+			Joins("JOIN products ON products.id = product_variants.product_id").
+			Where("products.category_id = ?", categoryID)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	switch sort {
+	case repository.GroupBuySortEndingSoon:
+		query = query.Order("group_buy_sessions.expires_at ASC")
+	case repository.GroupBuySortStartingSoon:
+		query = query.Order("group_buy_sessions.starts_at ASC")
+	case repository.GroupBuySortBiggestDiscount:
+		query = query.Order("(SELECT COALESCE(MAX(discount_percentage), 0) FROM group_buy_tiers WHERE group_buy_tiers.group_buy_session_id = group_buy_sessions.id) DESC")
+	default:
+		query = query.Order("group_buy_sessions.created_at DESC")
+	}
+
+	var sessions []entity.GroupBuySession
+	err := query.
+		Select("group_buy_sessions.*").
+		Preload("ProductVariant").
+		Preload("ProductVariant.Product", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "title", "slug", "category_id")
+		}).
+		Preload("ProductVariant.Product.ProductImages", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "product_id", "image_url")
+		}).
+		Preload("GroupBuyTiers", func(db *gorm.DB) *gorm.DB {
+			return db.Order("participant_threshold ASC")
+		}).
+		Limit(limit).
+		Offset(offset).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
 }
 
 func (g *GroupBuySessionRepositoryPg) Create(ctx context.Context, session *entity.GroupBuySession) error {
-	return g.db.Create(session).Error
+	return TxFromContext(ctx, g.db).Create(session).Error
 }
 
 func (g *GroupBuySessionRepositoryPg) Delete(ctx context.Context, sessionID string) error {
@@ -63,10 +137,48 @@ func (g *GroupBuySessionRepositoryPg) FindByID(ctx context.Context, sessionID st
 	return &session, nil
 }
 
+func (g *GroupBuySessionRepositoryPg) FindByIDForUpdate(ctx context.Context, sessionID string) (*entity.GroupBuySession, error) {
+	db := TxFromContext(ctx, g.db)
+	var session entity.GroupBuySession
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
 func (g *GroupBuySessionRepositoryPg) FindByProductVariantID(ctx context.Context, productVariantID string) (*entity.GroupBuySession, error) {
 	var session entity.GroupBuySession
-	if err := g.db.WithContext(ctx).Where("product_variant_id = ?", productVariantID).Preload("GroupBuyTiers").First(&session).Error; err != nil {
+	if err := g.db.WithContext(ctx).
+		Where("product_variant_id = ? AND status = ? AND expires_at > NOW()", productVariantID, "active").
+		Preload("GroupBuyTiers").
+		Order("created_at DESC").
+		First(&session).Error; err != nil {
 		return nil, err
 	}
 	return &session, nil
 }
+
+func (g *GroupBuySessionRepositoryPg) ExistsForSchedule(ctx context.Context, scheduleID string, startsAt time.Time) (bool, error) {
+	var count int64
+	err := TxFromContext(ctx, g.db).
+		Model(&entity.GroupBuySession{}).
+		Where("schedule_id = ? AND starts_at = ?", scheduleID, startsAt).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (g *GroupBuySessionRepositoryPg) FindUpcomingDue(ctx context.Context, now time.Time) ([]entity.GroupBuySession, error) {
+	var sessions []entity.GroupBuySession
+	err := g.db.WithContext(ctx).
+		Where("status = ? AND starts_at <= ?", "upcoming", now).
+		Order("starts_at ASC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (g *GroupBuySessionRepositoryPg) CancelUpcomingBySchedule(ctx context.Context, scheduleID string) error {
+	return TxFromContext(ctx, g.db).
+		Model(&entity.GroupBuySession{}).
+		Where("schedule_id = ? AND status = ?", scheduleID, "upcoming").
+		Update("status", "cancelled").Error
+}
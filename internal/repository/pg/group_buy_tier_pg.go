@@ -27,3 +27,12 @@ func (g *GroupBuyTierRepositoryPg) FindByID(ctx context.Context, tierID string)
 	}
 	return &tier, nil
 }
+
+func (g *GroupBuyTierRepositoryPg) FindBySessionID(ctx context.Context, groupBuySessionID string) ([]entity.GroupBuyTier, error) {
+	db := TxFromContext(ctx, g.db)
+	var tiers []entity.GroupBuyTier
+	if err := db.Where("group_buy_session_id = ?", groupBuySessionID).Order("participant_threshold ASC").Find(&tiers).Error; err != nil {
+		return nil, err
+	}
+	return tiers, nil
+}
@@ -0,0 +1,60 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/repository"
+	"gorm.io/gorm"
+)
+
+type LoginHistoryRepositoryPg struct {
+	db *gorm.DB
+}
+
+func NewLoginHistoryRepositoryPg(db *gorm.DB) repository.LoginHistoryRepository {
+	return &LoginHistoryRepositoryPg{db: db}
+}
+
+func (r *LoginHistoryRepositoryPg) Create(ctx context.Context, history *entity.LoginHistory) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Create(history).Error
+}
+
+func (r *LoginHistoryRepositoryPg) CountFailuresByIP(ctx context.Context, ipAddress string, since time.Time) (int64, error) {
+	db := TxFromContext(ctx, r.db)
+	var count int64
+	err := db.
+		Model(&entity.LoginHistory{}).
+		Where("ip_address = ? AND success = ? AND created_at >= ?", ipAddress, false, since).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *LoginHistoryRepositoryPg) HasSucceededFromDevice(ctx context.Context, userID int64, deviceInfo string) (bool, error) {
+	db := TxFromContext(ctx, r.db)
+	var count int64
+	err := db.
+		Model(&entity.LoginHistory{}).
+		Where("user_id = ? AND device_info = ? AND success = ?", userID, deviceInfo, true).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *LoginHistoryRepositoryPg) FindByUserID(ctx context.Context, userID int64, limit, offset int) ([]entity.LoginHistory, int64, error) {
+	db := TxFromContext(ctx, r.db)
+	var histories []entity.LoginHistory
+	var total int64
+
+	query := db.Model(&entity.LoginHistory{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&histories).Error; err != nil {
+		return nil, 0, err
+	}
+	return histories, total, nil
+}
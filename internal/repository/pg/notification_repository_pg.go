@@ -0,0 +1,71 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepositoryPg struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepositoryPg(db *gorm.DB) repository.NotificationRepository {
+	return &NotificationRepositoryPg{db: db}
+}
+
+func (r *NotificationRepositoryPg) Create(ctx context.Context, notification *entity.Notification) (bool, error) {
+	db := TxFromContext(ctx, r.db)
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "dedupe_key"}},
+		DoNothing: true,
+	}).Create(notification)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *NotificationRepositoryPg) FindByUserID(ctx context.Context, userID int64, limit, offset int) ([]entity.Notification, int64, error) {
+	db := TxFromContext(ctx, r.db)
+	var notifications []entity.Notification
+	var total int64
+
+	query := db.Model(&entity.Notification{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+func (r *NotificationRepositoryPg) CountUnread(ctx context.Context, userID int64) (int64, error) {
+	db := TxFromContext(ctx, r.db)
+	var count int64
+	err := db.
+		Model(&entity.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *NotificationRepositoryPg) MarkRead(ctx context.Context, userID int64, notificationID string) (bool, error) {
+	db := TxFromContext(ctx, r.db)
+	// an entry that was already read keeps its first read_at
+	result := db.Model(&entity.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now()))
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *NotificationRepositoryPg) MarkAllRead(ctx context.Context, userID int64) (int64, error) {
+	db := TxFromContext(ctx, r.db)
+	result := db.Model(&entity.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,43 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/repository"
+	"gorm.io/gorm"
+)
+
+type OrderAdjustmentRepositoryPg struct {
+	db *gorm.DB
+}
+
+func NewOrderAdjustmentRepositoryPg(db *gorm.DB) repository.OrderAdjustmentRepository {
+	return &OrderAdjustmentRepositoryPg{db: db}
+}
+
+func (r *OrderAdjustmentRepositoryPg) Create(ctx context.Context, adjustment *entity.OrderAdjustment) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Create(adjustment).Error
+}
+
+func (r *OrderAdjustmentRepositoryPg) FindByOrderID(ctx context.Context, orderID string) ([]entity.OrderAdjustment, error) {
+	db := TxFromContext(ctx, r.db)
+	var adjustments []entity.OrderAdjustment
+	err := db.
+		Where("order_id = ?", orderID).
+		Order("created_at ASC").
+		Find(&adjustments).Error
+	return adjustments, err
+}
+
+func (r *OrderAdjustmentRepositoryPg) SumByOrderID(ctx context.Context, orderID string, sourceType string) (float64, error) {
+	db := TxFromContext(ctx, r.db)
+	var total float64
+	err := db.
+		Model(&entity.OrderAdjustment{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("order_id = ? AND source_type = ?", orderID, sourceType).
+		Scan(&total).Error
+	return total, err
+}
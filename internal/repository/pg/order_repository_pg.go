@@ -1,9 +1,12 @@
 package pg
 
 import (
+	"context"
+
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepositoryPg struct {
@@ -14,13 +17,15 @@ func NewOrderRepositoryPg(db *gorm.DB) repository.OrderRepository {
 	return &OrderRepositoryPg{db: db}
 }
 
-func (r *OrderRepositoryPg) Create(order *entity.Order) error {
-	return r.db.Create(order).Error
+func (r *OrderRepositoryPg) Create(ctx context.Context, order *entity.Order) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Create(order).Error
 }
 
-func (r *OrderRepositoryPg) FindByID(orderID string) (*entity.Order, error) {
+func (r *OrderRepositoryPg) FindByID(ctx context.Context, orderID string) (*entity.Order, error) {
+	db := TxFromContext(ctx, r.db)
 	var order entity.Order
-	err := r.db.
+	err := db.
 		Preload("ProductVariant").
 		Preload("ProductVariant.Product").
 		Preload("ProductVariant.Product.ProductImages", func(db *gorm.DB) *gorm.DB {
@@ -29,6 +34,7 @@ func (r *OrderRepositoryPg) FindByID(orderID string) (*entity.Order, error) {
 		Preload("Payment").
 		Preload("ShippingDetail").
 		Preload("Seller").
+		Preload("Adjustments").
 		First(&order, "id = ?", orderID).Error
 	if err != nil {
 		return nil, err
@@ -36,9 +42,22 @@ func (r *OrderRepositoryPg) FindByID(orderID string) (*entity.Order, error) {
 	return &order, nil
 }
 
-func (r *OrderRepositoryPg) FindByOrderNumber(orderNumber string) (*entity.Order, error) {
+func (r *OrderRepositoryPg) FindByIDForUpdate(ctx context.Context, orderID string) (*entity.Order, error) {
+	db := TxFromContext(ctx, r.db)
 	var order entity.Order
-	err := r.db.
+	err := db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&order, "id = ?", orderID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *OrderRepositoryPg) FindByOrderNumber(ctx context.Context, orderNumber string) (*entity.Order, error) {
+	db := TxFromContext(ctx, r.db)
+	var order entity.Order
+	err := db.
 		Preload("ProductVariant").
 		Preload("Payment").
 		Preload("ShippingDetail").
@@ -49,17 +68,18 @@ func (r *OrderRepositoryPg) FindByOrderNumber(orderNumber string) (*entity.Order
 	return &order, nil
 }
 
-func (r *OrderRepositoryPg) FindByUserID(userID int64, limit, offset int) ([]entity.Order, int64, error) {
+func (r *OrderRepositoryPg) FindByUserID(ctx context.Context, userID int64, limit, offset int) ([]entity.Order, int64, error) {
+	db := TxFromContext(ctx, r.db)
 	var orders []entity.Order
 	var total int64
 
 	// Get total count
-	if err := r.db.Model(&entity.Order{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+	if err := db.Model(&entity.Order{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated orders
-	err := r.db.
+	err := db.
 		Preload("ProductVariant").
 		Preload("ProductVariant.Product").
 		Preload("ProductVariant.Product.ProductImages", func(db *gorm.DB) *gorm.DB {
@@ -80,10 +100,62 @@ func (r *OrderRepositoryPg) FindByUserID(userID int64, limit, offset int) ([]ent
 	return orders, total, nil
 }
 
-func (r *OrderRepositoryPg) Update(order *entity.Order) error {
-	return r.db.Save(order).Error
+func (r *OrderRepositoryPg) FindByBuyerGroupSessionID(ctx context.Context, buyerSessionID string) ([]entity.Order, error) {
+	db := TxFromContext(ctx, r.db)
+	var orders []entity.Order
+	err := db.
+		Where("buyer_group_session_id = ?", buyerSessionID).
+		Order("created_at ASC").
+		Find(&orders).Error
+	return orders, err
+}
+
+func (r *OrderRepositoryPg) SumGroupBuyQuantity(ctx context.Context, groupBuySessionID string, statuses []string) (int, error) {
+	db := TxFromContext(ctx, r.db)
+	var total int
+	err := db.
+		Model(&entity.Order{}).
+		Select("COALESCE(SUM(orders.quantity), 0)").
+		Joins("JOIN buyer_group_sessions ON buyer_group_sessions.id = orders.buyer_group_session_id").
+		Where("buyer_group_sessions.group_buy_session_id = ? AND orders.status IN ?", groupBuySessionID, statuses).
+		Scan(&total).Error
+	return total, err
+}
+
+func (r *OrderRepositoryPg) SumGroupBuyQuantities(ctx context.Context, groupBuySessionIDs []string, statuses []string) (map[string]int, error) {
+	totals := make(map[string]int, len(groupBuySessionIDs))
+	if len(groupBuySessionIDs) == 0 {
+		return totals, nil
+	}
+
+	db := TxFromContext(ctx, r.db)
+	var rows []struct {
+		GroupBuySessionID string
+		Total             int
+	}
+	err := db.
+		Model(&entity.Order{}).
+		Select("buyer_group_sessions.group_buy_session_id, COALESCE(SUM(orders.quantity), 0) AS total").
+		Joins("JOIN buyer_group_sessions ON buyer_group_sessions.id = orders.buyer_group_session_id").
+		Where("buyer_group_sessions.group_buy_session_id IN ? AND orders.status IN ?", groupBuySessionIDs, statuses).
+		Group("buyer_group_sessions.group_buy_session_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		totals[row.GroupBuySessionID] = row.Total
+	}
+	return totals, nil
+}
+
+func (r *OrderRepositoryPg) Update(ctx context.Context, order *entity.Order) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Save(order).Error
 }
 
-func (r *OrderRepositoryPg) UpdateStatus(orderID, status string) error {
-	return r.db.Model(&entity.Order{}).Where("id = ?", orderID).Update("status", status).Error
+func (r *OrderRepositoryPg) UpdateStatus(ctx context.Context, orderID, status string) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Model(&entity.Order{}).Where("id = ?", orderID).Update("status", status).Error
 }
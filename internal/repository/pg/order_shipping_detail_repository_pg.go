@@ -1,6 +1,8 @@
 package pg
 
 import (
+	"context"
+
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/repository"
 	"gorm.io/gorm"
@@ -14,13 +16,15 @@ func NewOrderShippingDetailRepositoryPg(db *gorm.DB) repository.OrderShippingDet
 	return &OrderShippingDetailRepositoryPg{db: db}
 }
 
-func (r *OrderShippingDetailRepositoryPg) Create(detail *entity.OrderShippingDetail) error {
-	return r.db.Create(detail).Error
+func (r *OrderShippingDetailRepositoryPg) Create(ctx context.Context, detail *entity.OrderShippingDetail) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Create(detail).Error
 }
 
-func (r *OrderShippingDetailRepositoryPg) FindByOrderID(orderID string) (*entity.OrderShippingDetail, error) {
+func (r *OrderShippingDetailRepositoryPg) FindByOrderID(ctx context.Context, orderID string) (*entity.OrderShippingDetail, error) {
+	db := TxFromContext(ctx, r.db)
 	var detail entity.OrderShippingDetail
-	err := r.db.First(&detail, "order_id = ?", orderID).Error
+	err := db.First(&detail, "order_id = ?", orderID).Error
 	if err != nil {
 		return nil, err
 	}
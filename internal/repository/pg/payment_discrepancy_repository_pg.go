@@ -0,0 +1,43 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentDiscrepancyRepositoryPg struct {
+	db *gorm.DB
+}
+
+func NewPaymentDiscrepancyRepositoryPg(db *gorm.DB) repository.PaymentDiscrepancyRepository {
+	return &PaymentDiscrepancyRepositoryPg{db: db}
+}
+
+func (r *PaymentDiscrepancyRepositoryPg) Create(ctx context.Context, discrepancy *entity.PaymentDiscrepancy) error {
+	db := TxFromContext(ctx, r.db)
+	return db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "payment_id"}, {Name: "gateway_status"}},
+			DoNothing: true,
+		}).
+		Create(discrepancy).Error
+}
+
+func (r *PaymentDiscrepancyRepositoryPg) FindUnresolved(ctx context.Context) ([]entity.PaymentDiscrepancy, error) {
+	db := TxFromContext(ctx, r.db)
+	var discrepancies []entity.PaymentDiscrepancy
+	err := db.
+		Preload("Order").
+		Preload("Payment").
+		Where("resolved_at IS NULL").
+		Order("created_at DESC").
+		Find(&discrepancies).Error
+	if err != nil {
+		return nil, err
+	}
+	return discrepancies, nil
+}
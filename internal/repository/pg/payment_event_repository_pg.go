@@ -0,0 +1,94 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentEventRepositoryPg struct {
+	db *gorm.DB
+}
+
+func NewPaymentEventRepositoryPg(db *gorm.DB) repository.PaymentEventRepository {
+	return &PaymentEventRepositoryPg{db: db}
+}
+
+func (r *PaymentEventRepositoryPg) Create(ctx context.Context, event *entity.PaymentEvent) (bool, error) {
+	db := TxFromContext(ctx, r.db)
+	result := db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "transaction_id"}, {Name: "transaction_status"}},
+			DoNothing: true,
+		}).
+		Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *PaymentEventRepositoryPg) FindByID(ctx context.Context, eventID string) (*entity.PaymentEvent, error) {
+	db := TxFromContext(ctx, r.db)
+	var event entity.PaymentEvent
+	if err := db.First(&event, "id = ?", eventID).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *PaymentEventRepositoryPg) FindByIDForUpdate(ctx context.Context, eventID string) (*entity.PaymentEvent, error) {
+	db := TxFromContext(ctx, r.db)
+	var event entity.PaymentEvent
+	err := db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&event, "id = ?", eventID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *PaymentEventRepositoryPg) FindByTransaction(ctx context.Context, transactionID, transactionStatus string) (*entity.PaymentEvent, error) {
+	db := TxFromContext(ctx, r.db)
+	var event entity.PaymentEvent
+	err := db.First(&event, "transaction_id = ? AND transaction_status = ?", transactionID, transactionStatus).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *PaymentEventRepositoryPg) FindAll(ctx context.Context, status string, limit, offset int) ([]entity.PaymentEvent, int64, error) {
+	db := TxFromContext(ctx, r.db)
+	var events []entity.PaymentEvent
+	var total int64
+
+	query := db.Model(&entity.PaymentEvent{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+func (r *PaymentEventRepositoryPg) Update(ctx context.Context, event *entity.PaymentEvent) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Save(event).Error
+}
@@ -1,11 +1,13 @@
 package pg
 
 import (
+	"context"
 	"time"
 
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PaymentRepositoryPg struct {
@@ -16,31 +18,57 @@ func NewPaymentRepositoryPg(db *gorm.DB) repository.PaymentRepository {
 	return &PaymentRepositoryPg{db: db}
 }
 
-func (r *PaymentRepositoryPg) Create(payment *entity.Payment) error {
-	return r.db.Create(payment).Error
+func (r *PaymentRepositoryPg) Create(ctx context.Context, payment *entity.Payment) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Create(payment).Error
 }
 
-func (r *PaymentRepositoryPg) FindByOrderID(orderID string) (*entity.Payment, error) {
+func (r *PaymentRepositoryPg) FindByOrderID(ctx context.Context, orderID string) (*entity.Payment, error) {
+	db := TxFromContext(ctx, r.db)
 	var payment entity.Payment
-	err := r.db.First(&payment, "order_id = ?", orderID).Error
+	err := db.First(&payment, "order_id = ?", orderID).Error
 	if err != nil {
 		return nil, err
 	}
 	return &payment, nil
 }
 
-func (r *PaymentRepositoryPg) FindByGatewayTransactionID(txID string) (*entity.Payment, error) {
+func (r *PaymentRepositoryPg) FindByOrderIDForUpdate(ctx context.Context, orderID string) (*entity.Payment, error) {
+	db := TxFromContext(ctx, r.db)
 	var payment entity.Payment
-	err := r.db.Preload("Order").First(&payment, "gateway_transaction_id = ?", txID).Error
+	err := db.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&payment, "order_id = ?", orderID).Error
 	if err != nil {
 		return nil, err
 	}
 	return &payment, nil
 }
 
-func (r *PaymentRepositoryPg) FindExpiredPending() ([]entity.Payment, error) {
+func (r *PaymentRepositoryPg) FindByGatewayOrderID(ctx context.Context, gatewayOrderID string) (*entity.Payment, error) {
+	db := TxFromContext(ctx, r.db)
+	var payment entity.Payment
+	err := db.Preload("Order").First(&payment, "gateway_order_id = ?", gatewayOrderID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepositoryPg) FindByGatewayTransactionID(ctx context.Context, txID string) (*entity.Payment, error) {
+	db := TxFromContext(ctx, r.db)
+	var payment entity.Payment
+	err := db.Preload("Order").First(&payment, "gateway_transaction_id = ?", txID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepositoryPg) FindExpiredPending(ctx context.Context) ([]entity.Payment, error) {
+	db := TxFromContext(ctx, r.db)
 	var payments []entity.Payment
-	err := r.db.
+	err := db.
 		Preload("Order").
 		Where("status = ? AND expired_at < ?", entity.PaymentStatusPending, time.Now()).
 		Find(&payments).Error
@@ -50,10 +78,40 @@ func (r *PaymentRepositoryPg) FindExpiredPending() ([]entity.Payment, error) {
 	return payments, nil
 }
 
-func (r *PaymentRepositoryPg) Update(payment *entity.Payment) error {
-	return r.db.Save(payment).Error
+func (r *PaymentRepositoryPg) FindPending(ctx context.Context) ([]entity.Payment, error) {
+	db := TxFromContext(ctx, r.db)
+	var payments []entity.Payment
+	err := db.
+		Preload("Order").
+		Where("status = ?", entity.PaymentStatusPending).
+		Order("created_at ASC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (r *PaymentRepositoryPg) FindClosedSince(ctx context.Context, since time.Time) ([]entity.Payment, error) {
+	db := TxFromContext(ctx, r.db)
+	var payments []entity.Payment
+	err := db.
+		Preload("Order").
+		Where("status <> ? AND updated_at >= ?", entity.PaymentStatusPending, since).
+		Order("updated_at ASC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (r *PaymentRepositoryPg) Update(ctx context.Context, payment *entity.Payment) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Save(payment).Error
 }
 
-func (r *PaymentRepositoryPg) UpdateStatus(paymentID, status string) error {
-	return r.db.Model(&entity.Payment{}).Where("id = ?", paymentID).Update("status", status).Error
+func (r *PaymentRepositoryPg) UpdateStatus(ctx context.Context, paymentID, status string) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Model(&entity.Payment{}).Where("id = ?", paymentID).Update("status", status).Error
 }
@@ -64,3 +64,34 @@ func (r *ProductVariantStockRepositoryPg) DeductStockWithVersion(ctx context.Con
 
 	return nil
 }
+
+func (r *ProductVariantStockRepositoryPg) ReserveStock(ctx context.Context, variantID string, quantity int) (bool, error) {
+	db := TxFromContext(ctx, r.db)
+
+	result := db.Exec(`
+		UPDATE product_variant_stocks
+		SET reserved_stock = reserved_stock + ?,
+			version = version + 1,
+			last_updated = NOW()
+		WHERE product_variant_id = ? AND current_stock - reserved_stock >= ?
+	`, quantity, variantID, quantity)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *ProductVariantStockRepositoryPg) ReturnStock(ctx context.Context, variantID string, unreserve int, restock int) error {
+	db := TxFromContext(ctx, r.db)
+
+	return db.Exec(`
+		UPDATE product_variant_stocks
+		SET reserved_stock = GREATEST(reserved_stock - ?, 0),
+			current_stock = current_stock + ?,
+			version = version + 1,
+			last_updated = NOW()
+		WHERE product_variant_id = ?
+	`, unreserve, restock, variantID).Error
+}
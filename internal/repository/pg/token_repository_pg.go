@@ -2,9 +2,9 @@ package pg
 
 import (
 	"context"
+	"time"
 
 	"github.com/febry3/gamingin/internal/entity"
-	"github.com/febry3/gamingin/internal/errorx"
 	"github.com/febry3/gamingin/internal/repository"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -23,23 +23,18 @@ func NewTokenRepositoryPg(db *gorm.DB, log *logrus.Logger) repository.TokenRepos
 	}
 }
 
-func (t *TokenRepositoryPg) CreateOrUpdate(ctx context.Context, token *entity.RefreshToken) (*entity.RefreshToken, error) {
-	if token == nil {
-		return nil, errorx.ErrTokenEmpty
-	}
-
-	err := t.db.WithContext(ctx).
-		Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "user_id"}, {Name: "device_info"}},
-			DoUpdates: clause.AssignmentColumns([]string{"is_revoked", "role", "token_hash", "expires_at", "created_at"}),
-		}).
-		Create(token).Error
+func (t *TokenRepositoryPg) Create(ctx context.Context, token *entity.RefreshToken) error {
+	db := TxFromContext(ctx, t.db)
+	return db.WithContext(ctx).Create(token).Error
+}
 
-	if err != nil {
-		t.log.WithError(err).Error("failed to upsert refresh token")
-		return nil, err
-	}
-	return token, nil
+func (t *TokenRepositoryPg) FindByAccessTokenForUpdate(ctx context.Context, accessToken string) (entity.RefreshToken, error) {
+	db := TxFromContext(ctx, t.db)
+	var token entity.RefreshToken
+	err := db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&token, "token_hash = ?", accessToken).Error
+	return token, err
 }
 
 func (t *TokenRepositoryPg) FindByAccessToken(ctx context.Context, accessToken string) (entity.RefreshToken, error) {
@@ -52,18 +47,93 @@ func (t *TokenRepositoryPg) FindByAccessToken(ctx context.Context, accessToken s
 	return token, nil
 }
 
-func (t *TokenRepositoryPg) DeleteByUserID(ctx context.Context, id int) error {
-	err := t.db.WithContext(ctx).Where("user_id = ?", id).Delete(&entity.RefreshToken{}).Error
+func (t *TokenRepositoryPg) FindActiveByUserID(ctx context.Context, userID int64) ([]entity.RefreshToken, error) {
+	var tokens []entity.RefreshToken
+	err := t.db.WithContext(ctx).
+		Where("user_id = ? AND is_revoked = ? AND expires_at > ?", userID, false, time.Now()).
+		Order("created_at DESC").
+		Find(&tokens).Error
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return tokens, nil
+}
+
+func (t *TokenRepositoryPg) Revoke(ctx context.Context, tokenID string) error {
+	db := TxFromContext(ctx, t.db)
+	return db.WithContext(ctx).
+		Model(&entity.RefreshToken{}).
+		Where("token_id = ?", tokenID).
+		Update("is_revoked", true).Error
 }
 
-func (t *TokenRepositoryPg) DeleteByAccessToken(ctx context.Context, accessToken string) error {
-	err := t.db.WithContext(ctx).Where("token_hash = ?", accessToken).Delete(&entity.RefreshToken{}).Error
+func (t *TokenRepositoryPg) RevokeFamily(ctx context.Context, familyID string) error {
+	db := TxFromContext(ctx, t.db)
+	return db.WithContext(ctx).
+		Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND is_revoked = ?", familyID, false).
+		Update("is_revoked", true).Error
+}
+
+func (t *TokenRepositoryPg) RevokeByID(ctx context.Context, userID int64, familyID string) (bool, error) {
+	db := TxFromContext(ctx, t.db)
+	result := db.WithContext(ctx).
+		Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND user_id = ? AND is_revoked = ?", familyID, userID, false).
+		Update("is_revoked", true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (t *TokenRepositoryPg) RevokeByUserID(ctx context.Context, userID int64, exceptFamilyID string) ([]string, error) {
+	db := TxFromContext(ctx, t.db)
+
+	// RETURNING fills revoked with the rows that were updated
+	var revoked []entity.RefreshToken
+	query := db.WithContext(ctx).
+		Model(&revoked).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "family_id"}}}).
+		Where("user_id = ? AND is_revoked = ?", userID, false)
+	if exceptFamilyID != "" {
+		query = query.Where("family_id <> ?", exceptFamilyID)
+	}
+
+	if err := query.Update("is_revoked", true).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(revoked))
+	for _, token := range revoked {
+		ids = append(ids, token.FamilyID)
+	}
+	return ids, nil
+}
+
+func (t *TokenRepositoryPg) RevokeByDevice(ctx context.Context, userID int64, deviceInfo string) ([]string, error) {
+	db := TxFromContext(ctx, t.db)
+
+	var revoked []entity.RefreshToken
+	err := db.WithContext(ctx).
+		Model(&revoked).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "family_id"}}}).
+		Where("user_id = ? AND device_info = ? AND is_revoked = ?", userID, deviceInfo, false).
+		Update("is_revoked", true).Error
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+
+	ids := make([]string, 0, len(revoked))
+	for _, token := range revoked {
+		ids = append(ids, token.FamilyID)
+	}
+	return ids, nil
+}
+
+func (t *TokenRepositoryPg) DeleteRevokedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := t.db.WithContext(ctx).
+		Where("is_revoked = ? AND created_at < ?", true, before).
+		Delete(&entity.RefreshToken{})
+	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,126 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TwoFactorRepositoryPg struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepositoryPg(db *gorm.DB) repository.TwoFactorRepository {
+	return &TwoFactorRepositoryPg{db: db}
+}
+
+func (r *TwoFactorRepositoryPg) FindByUserID(ctx context.Context, userID int64) (entity.UserTwoFactor, error) {
+	db := TxFromContext(ctx, r.db)
+	var twoFactor entity.UserTwoFactor
+	err := db.First(&twoFactor, "user_id = ?", userID).Error
+	return twoFactor, err
+}
+
+func (r *TwoFactorRepositoryPg) Save(ctx context.Context, twoFactor *entity.UserTwoFactor) error {
+	db := TxFromContext(ctx, r.db)
+	return db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled_at", "last_used_step", "updated_at"}),
+		}).
+		Create(twoFactor).Error
+}
+
+func (r *TwoFactorRepositoryPg) Enable(ctx context.Context, userID int64) error {
+	db := TxFromContext(ctx, r.db)
+	return db.
+		Model(&entity.UserTwoFactor{}).
+		Where("user_id = ?", userID).
+		Update("enabled_at", time.Now()).Error
+}
+
+func (r *TwoFactorRepositoryPg) UseStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	db := TxFromContext(ctx, r.db)
+	result := db.
+		Model(&entity.UserTwoFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *TwoFactorRepositoryPg) Delete(ctx context.Context, userID int64) error {
+	db := TxFromContext(ctx, r.db)
+	if err := db.Delete(&entity.TwoFactorRecoveryCode{}, "user_id = ?", userID).Error; err != nil {
+		return err
+	}
+	return db.Delete(&entity.UserTwoFactor{}, "user_id = ?", userID).Error
+}
+
+func (r *TwoFactorRepositoryPg) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	db := TxFromContext(ctx, r.db)
+	if err := db.Delete(&entity.TwoFactorRecoveryCode{}, "user_id = ?", userID).Error; err != nil {
+		return err
+	}
+
+	codes := make([]entity.TwoFactorRecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, entity.TwoFactorRecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return db.Create(&codes).Error
+}
+
+func (r *TwoFactorRepositoryPg) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	db := TxFromContext(ctx, r.db)
+	result := db.
+		Model(&entity.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *TwoFactorRepositoryPg) CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int64, error) {
+	db := TxFromContext(ctx, r.db)
+	var count int64
+	err := db.
+		Model(&entity.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *TwoFactorRepositoryPg) FindPolicies(ctx context.Context) ([]entity.TwoFactorPolicy, error) {
+	db := TxFromContext(ctx, r.db)
+	var policies []entity.TwoFactorPolicy
+	err := db.Order("role ASC").Find(&policies).Error
+	return policies, err
+}
+
+func (r *TwoFactorRepositoryPg) FindPolicy(ctx context.Context, role string) (entity.TwoFactorPolicy, error) {
+	db := TxFromContext(ctx, r.db)
+	var policy entity.TwoFactorPolicy
+	err := db.First(&policy, "role = ?", role).Error
+	return policy, err
+}
+
+func (r *TwoFactorRepositoryPg) SavePolicy(ctx context.Context, policy *entity.TwoFactorPolicy) error {
+	db := TxFromContext(ctx, r.db)
+	return db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "role"}},
+			DoUpdates: clause.AssignmentColumns([]string{"required", "updated_at"}),
+		}).
+		Create(policy).Error
+}
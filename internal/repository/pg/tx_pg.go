@@ -15,8 +15,11 @@ func NewTxManager(db *gorm.DB) repository.TxManager {
 	return &TxManagerPg{db: db}
 }
 
+// WithTransaction runs fn in a transaction. When ctx already carries one, fn runs in a
+// savepoint of it so nested calls see the same locks instead of deadlocking on them.
 func (t *TxManagerPg) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
-	return t.db.Transaction(func(tx *gorm.DB) error {
+	db := TxFromContext(ctx, t.db)
+	return db.Transaction(func(tx *gorm.DB) error {
 		txCtx := context.WithValue(ctx, repository.TxKey{}, tx)
 		return fn(txCtx)
 	})
@@ -2,6 +2,7 @@ package pg
 
 import (
 	"context"
+	"time"
 
 	"github.com/febry3/gamingin/internal/errorx"
 	"github.com/sirupsen/logrus"
@@ -9,6 +10,7 @@ import (
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepositoryPg struct {
@@ -67,3 +69,42 @@ func (u UserRepositoryPg) Update(ctx context.Context, user entity.User) (entity.
 
 	return user, nil
 }
+
+func (u UserRepositoryPg) IncrementFailedLogins(ctx context.Context, id int64) (int, error) {
+	db := TxFromContext(ctx, u.db)
+	var user entity.User
+	result := db.WithContext(ctx).
+		Model(&user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_attempts"}}}).
+		Where("id = ?", id).
+		UpdateColumn("failed_login_attempts", gorm.Expr("failed_login_attempts + 1"))
+	if result.Error != nil {
+		u.log.Errorf("[UserRepositoryPg] Increment Failed Logins Error: %v]", result.Error.Error())
+		return 0, result.Error
+	}
+	return user.FailedLoginAttempts, nil
+}
+
+func (u UserRepositoryPg) Lock(ctx context.Context, id int64, until time.Time) error {
+	db := TxFromContext(ctx, u.db)
+	return db.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]any{
+			"locked_until":          until,
+			"lockout_count":         gorm.Expr("lockout_count + 1"),
+			"failed_login_attempts": 0,
+		}).Error
+}
+
+func (u UserRepositoryPg) ResetFailedLogins(ctx context.Context, id int64) error {
+	db := TxFromContext(ctx, u.db)
+	return db.WithContext(ctx).
+		Model(&entity.User{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]any{
+			"locked_until":          nil,
+			"lockout_count":         0,
+			"failed_login_attempts": 0,
+		}).Error
+}
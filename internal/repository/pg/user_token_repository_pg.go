@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/repository"
+	"gorm.io/gorm"
+)
+
+type UserTokenRepositoryPg struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepositoryPg(db *gorm.DB) repository.UserTokenRepository {
+	return &UserTokenRepositoryPg{db: db}
+}
+
+func (r *UserTokenRepositoryPg) Create(ctx context.Context, token *entity.UserToken) error {
+	db := TxFromContext(ctx, r.db)
+	return db.Create(token).Error
+}
+
+func (r *UserTokenRepositoryPg) FindByHash(ctx context.Context, purpose string, tokenHash string) (entity.UserToken, error) {
+	db := TxFromContext(ctx, r.db)
+	var token entity.UserToken
+	err := db.First(&token, "purpose = ? AND token_hash = ?", purpose, tokenHash).Error
+	return token, err
+}
+
+func (r *UserTokenRepositoryPg) FindLatest(ctx context.Context, userID int64, purpose string) (entity.UserToken, error) {
+	db := TxFromContext(ctx, r.db)
+	var token entity.UserToken
+	err := db.
+		Where("user_id = ? AND purpose = ?", userID, purpose).
+		Order("created_at DESC").
+		First(&token).Error
+	return token, err
+}
+
+func (r *UserTokenRepositoryPg) CountSince(ctx context.Context, userID int64, purpose string, since time.Time) (int64, error) {
+	db := TxFromContext(ctx, r.db)
+	var count int64
+	err := db.
+		Model(&entity.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at >= ?", userID, purpose, since).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *UserTokenRepositoryPg) MarkUsed(ctx context.Context, id string) (bool, error) {
+	db := TxFromContext(ctx, r.db)
+	result := db.
+		Model(&entity.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserTokenRepositoryPg) InvalidateAll(ctx context.Context, userID int64, purpose string) error {
+	db := TxFromContext(ctx, r.db)
+	return db.
+		Model(&entity.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
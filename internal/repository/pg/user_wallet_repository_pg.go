@@ -7,6 +7,7 @@ import (
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userWalletRepositoryPg struct {
@@ -52,3 +53,16 @@ func (r *userWalletRepositoryPg) CountUserWallet(ctx context.Context, userID int
 	}
 	return count, nil
 }
+
+func (r *userWalletRepositoryPg) Credit(ctx context.Context, userID int64, amount float64) error {
+	db := TxFromContext(ctx, r.db)
+	return db.
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"balance":    gorm.Expr("user_wallets.balance + EXCLUDED.balance"),
+				"updated_at": gorm.Expr("NOW()"),
+			}),
+		}).
+		Create(&entity.UserWallet{UserID: userID, Balance: amount}).Error
+}
@@ -13,4 +13,9 @@ type ProductVariantStockRepository interface {
 	// DeductStockWithVersion atomically decrements current_stock and reserved_stock with optimistic locking
 	// Returns error if version mismatch (concurrent modification detected)
 	DeductStockWithVersion(ctx context.Context, variantID string, quantity int, expectedVersion int) error
+	// ReserveStock atomically adds quantity to reserved_stock if that much is still available.
+	// Returns false when the unreserved stock is short.
+	ReserveStock(ctx context.Context, variantID string, quantity int) (bool, error)
+	// ReturnStock atomically drops unreserve from reserved_stock and puts restock back on current_stock
+	ReturnStock(ctx context.Context, variantID string, unreserve int, restock int) error
 }
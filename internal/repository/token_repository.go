@@ -2,13 +2,30 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/febry3/gamingin/internal/entity"
 )
 
 type TokenRepository interface {
-	CreateOrUpdate(ctx context.Context, token *entity.RefreshToken) (*entity.RefreshToken, error)
+	Create(ctx context.Context, token *entity.RefreshToken) error
 	FindByAccessToken(ctx context.Context, accessToken string) (entity.RefreshToken, error)
-	DeleteByUserID(ctx context.Context, id int) error
-	DeleteByAccessToken(ctx context.Context, accessToken string) error
+	FindByAccessTokenForUpdate(ctx context.Context, accessToken string) (entity.RefreshToken, error)
+	// FindActiveByUserID returns the current token of every active session of the user
+	FindActiveByUserID(ctx context.Context, userID int64) ([]entity.RefreshToken, error)
+	Revoke(ctx context.Context, tokenID string) error
+	// RevokeFamily revokes every token rotated from the same login
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeByID revokes one session of the user, it reports false when there is no
+	// active session with that family ID
+	RevokeByID(ctx context.Context, userID int64, familyID string) (bool, error)
+	// RevokeByUserID revokes every session of the user except exceptFamilyID, pass an
+	// empty string to revoke them all. It returns the family IDs it revoked.
+	RevokeByUserID(ctx context.Context, userID int64, exceptFamilyID string) ([]string, error)
+	// RevokeByDevice revokes the active session the user has on the device so a new login
+	// replaces it. It returns the family IDs it revoked.
+	RevokeByDevice(ctx context.Context, userID int64, deviceInfo string) ([]string, error)
+	// DeleteRevokedBefore purges revoked tokens issued before the cutoff and returns how
+	// many were deleted
+	DeleteRevokedBefore(ctx context.Context, before time.Time) (int64, error)
 }
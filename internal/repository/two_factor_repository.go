@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
+
+type TwoFactorRepository interface {
+	FindByUserID(ctx context.Context, userID int64) (entity.UserTwoFactor, error)
+	// Save stores a new secret for the user, replacing a pending enrolment
+	Save(ctx context.Context, twoFactor *entity.UserTwoFactor) error
+	Enable(ctx context.Context, userID int64) error
+	// UseStep records the TOTP step of an accepted code, it reports false when the
+	// step was already used
+	UseStep(ctx context.Context, userID int64, step int64) (bool, error)
+	Delete(ctx context.Context, userID int64) error
+
+	// ReplaceRecoveryCodes drops every recovery code of the user and stores the new hashes
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	// UseRecoveryCode consumes an unused code, it reports false when there is none
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int64, error)
+
+	FindPolicies(ctx context.Context) ([]entity.TwoFactorPolicy, error)
+	FindPolicy(ctx context.Context, role string) (entity.TwoFactorPolicy, error)
+	SavePolicy(ctx context.Context, policy *entity.TwoFactorPolicy) error
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"time"
+
 	"github.com/febry3/gamingin/internal/entity"
 )
 
@@ -10,4 +12,8 @@ type UserRepository interface {
 	FindByID(ctx context.Context, id int64) (entity.User, error)
 	FindByEmail(ctx context.Context, email string) (entity.User, bool, error)
 	Update(ctx context.Context, user entity.User) (entity.User, error)
+	// IncrementFailedLogins adds a failed attempt and returns the new count
+	IncrementFailedLogins(ctx context.Context, id int64) (int, error)
+	Lock(ctx context.Context, id int64, until time.Time) error
+	ResetFailedLogins(ctx context.Context, id int64) error
 }
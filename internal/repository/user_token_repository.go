@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
+
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *entity.UserToken) error
+	FindByHash(ctx context.Context, purpose string, tokenHash string) (entity.UserToken, error)
+	// FindLatest returns the most recently issued token of a purpose for the user
+	FindLatest(ctx context.Context, userID int64, purpose string) (entity.UserToken, error)
+	CountSince(ctx context.Context, userID int64, purpose string, since time.Time) (int64, error)
+	// MarkUsed consumes the token, it reports false when the token was already used
+	MarkUsed(ctx context.Context, id string) (bool, error)
+	// InvalidateAll consumes every unused token of a purpose for the user
+	InvalidateAll(ctx context.Context, userID int64, purpose string) error
+}
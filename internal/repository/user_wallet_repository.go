@@ -11,4 +11,6 @@ type UserWalletRepository interface {
 	GetUserBalanceByUserID(ctx context.Context, userID int64) (float64, error)
 	CountUserWallet(ctx context.Context, userID int64) (int64, error)
 	CreateOrUpdateUserWallet(ctx context.Context, userWallet *entity.UserWallet) error
+	// Credit adds amount to the balance in one statement, creating the wallet if needed
+	Credit(ctx context.Context, userID int64, amount float64) error
 }
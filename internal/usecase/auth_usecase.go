@@ -4,12 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"net/url"
 	"time"
 
 	"github.com/febry3/gamingin/internal/dto"
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/errorx"
 	"github.com/febry3/gamingin/internal/helpers"
+	"github.com/febry3/gamingin/internal/infra/mailer"
+	"github.com/febry3/gamingin/internal/infra/session"
 	"github.com/febry3/gamingin/internal/repository"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -21,20 +24,64 @@ type AuthUsecaseContract interface {
 	Login(ctx context.Context, request dto.LoginRequest) (dto.LoginResponse, string, error)
 	Register(ctx context.Context, request dto.RegisterRequest) (dto.RegisterResponse, error)
 	Logout(ctx context.Context, tokenId string) error
-	RefreshAccessToken(ctx context.Context, refreshToken string) (string, error)
+	RefreshAccessToken(ctx context.Context, refreshToken string) (string, string, error)
 	LoginOrRegisterWithGoogle(ctx context.Context, request dto.LoginWithGoogleData) (dto.LoginResponse, string, error)
+	VerifyEmail(ctx context.Context, request dto.VerifyEmailRequest) error
+	ResendVerificationEmail(ctx context.Context, userID int64) error
+	ForgotPassword(ctx context.Context, request dto.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, request dto.ResetPasswordRequest) error
+	ChangePassword(ctx context.Context, userID int64, request dto.ChangePasswordRequest, currentSessionID string) error
+	GetSessions(ctx context.Context, userID int64, currentSessionID string) ([]dto.SessionResponse, error)
+	RevokeSession(ctx context.Context, userID int64, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID int64, currentSessionID string) (int, error)
+	GetLoginHistory(ctx context.Context, userID int64, page, limit int) (*dto.LoginHistoryListResponse, error)
+	GetAuthProviders(ctx context.Context, userID int64) ([]dto.AuthProviderResponse, error)
+	LinkGoogleProvider(ctx context.Context, userID int64, request dto.LoginWithGoogleData) error
+	LinkEmailProvider(ctx context.Context, userID int64, request dto.SetPasswordRequest) error
+	UnlinkProvider(ctx context.Context, userID int64, provider string) error
+	// StartTwoFactorSetup enrols a user whose role requires 2FA during sign in
+	StartTwoFactorSetup(ctx context.Context, request dto.TwoFactorChallengeRequest) (*dto.TwoFactorEnrollResponse, error)
+	VerifyTwoFactorLogin(ctx context.Context, request dto.TwoFactorVerifyRequest) (dto.LoginResponse, string, error)
 }
 
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+	// a user can ask for a new emailed token once per cooldown and at most
+	// userTokenDailyLimit times a day for each purpose
+	userTokenResendCooldown = time.Minute
+	userTokenDailyLimit     = 5
+
+	// maxFailedLogins wrong passwords in a row lock the account, the first lockout
+	// lasts lockoutBaseDuration and every further one doubles up to lockoutMaxDuration
+	maxFailedLogins     = 5
+	lockoutBaseDuration = 5 * time.Minute
+	lockoutMaxDuration  = 24 * time.Hour
+	// an IP with ipFailureLimit failed logins within ipFailureWindow is refused
+	ipFailureLimit  = 20
+	ipFailureWindow = 15 * time.Minute
+
+	// twoFactorChallengeTTL is how long the client has to send the second factor
+	twoFactorChallengeTTL = 5 * time.Minute
+)
+
 type AuthUsecase struct {
 	token        repository.TokenRepository
 	user         repository.UserRepository
 	authProvider repository.AuthProviderRepository
 	seller       repository.SellerRepository
+	userToken    repository.UserTokenRepository
+	loginHistory repository.LoginHistoryRepository
+	tx           repository.TxManager
+	notifier     NotificationUsecaseContract
+	twoFactor    TwoFactorUsecaseContract
+	sessions     session.Denylist
 	log          *logrus.Logger
 	jwt          helpers.JwtService
+	frontendURL  string
 }
 
-func NewAuthUsecase(user repository.UserRepository, log *logrus.Logger, jwt helpers.JwtService, token repository.TokenRepository, authProvider repository.AuthProviderRepository, seller repository.SellerRepository) AuthUsecaseContract {
+func NewAuthUsecase(user repository.UserRepository, log *logrus.Logger, jwt helpers.JwtService, token repository.TokenRepository, authProvider repository.AuthProviderRepository, seller repository.SellerRepository, userToken repository.UserTokenRepository, loginHistory repository.LoginHistoryRepository, tx repository.TxManager, notifier NotificationUsecaseContract, twoFactor TwoFactorUsecaseContract, sessions session.Denylist, frontendURL string) AuthUsecaseContract {
 	return &AuthUsecase{
 		token:        token,
 		user:         user,
@@ -42,6 +89,13 @@ func NewAuthUsecase(user repository.UserRepository, log *logrus.Logger, jwt help
 		jwt:          jwt,
 		authProvider: authProvider,
 		seller:       seller,
+		userToken:    userToken,
+		loginHistory: loginHistory,
+		tx:           tx,
+		notifier:     notifier,
+		twoFactor:    twoFactor,
+		sessions:     sessions,
+		frontendURL:  frontendURL,
 	}
 }
 
@@ -93,6 +147,11 @@ func (a *AuthUsecase) Register(ctx context.Context, request dto.RegisterRequest)
 		return dto.RegisterResponse{}, err
 	}
 
+	// The account exists at this point, a failed email can be requested again
+	if err := a.sendVerificationEmail(ctx, user); err != nil {
+		a.log.Errorf("[AuthUsecase] Send Verification Email Error: %v", err.Error())
+	}
+
 	return dto.RegisterResponse{
 		ID:          user.ID,
 		Username:    user.Username,
@@ -103,122 +162,472 @@ func (a *AuthUsecase) Register(ctx context.Context, request dto.RegisterRequest)
 	}, nil
 }
 
+func (a *AuthUsecase) VerifyEmail(ctx context.Context, request dto.VerifyEmailRequest) error {
+	if err := validator.New().Struct(request); err != nil {
+		a.log.Errorf("[AuthUsecase] Validate Verify Email Error: %v", err.Error())
+		return err
+	}
+
+	token, err := a.userToken.FindByHash(ctx, entity.UserTokenPurposeEmailVerification, helpers.HashToken(request.Token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errorx.ErrVerificationTokenInvalid
+		}
+		a.log.Errorf("[AuthUsecase] Find Verification Token Error: %v", err.Error())
+		return err
+	}
+
+	if token.UsedAt != nil {
+		return errorx.ErrVerificationTokenInvalid
+	}
+
+	if token.IsExpired() {
+		return errorx.ErrVerificationTokenExpired
+	}
+
+	user, err := a.user.FindByID(ctx, token.UserID)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Find User Error: %v", err.Error())
+		return err
+	}
+
+	alreadyVerified := user.IsEmailVerified()
+	err = a.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		used, err := a.userToken.MarkUsed(txCtx, token.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errorx.ErrVerificationTokenInvalid
+		}
+
+		if alreadyVerified {
+			return nil
+		}
+
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		_, err = a.user.Update(txCtx, user)
+		return err
+	})
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Verify Email Error: %v", err.Error())
+		return err
+	}
+
+	if !alreadyVerified {
+		a.notifier.NotifyUser(ctx, user.ID, mailer.TemplateWelcome, nil)
+	}
+	return nil
+}
+
+func (a *AuthUsecase) ResendVerificationEmail(ctx context.Context, userID int64) error {
+	user, err := a.user.FindByID(ctx, userID)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Find User Error: %v", err.Error())
+		return errorx.ErrUserNotFound
+	}
+
+	if user.IsEmailVerified() {
+		return errorx.ErrEmailAlreadyVerified
+	}
+
+	allowed, err := a.canIssueUserToken(ctx, user.ID, entity.UserTokenPurposeEmailVerification)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Check Verification Token Limit Error: %v", err.Error())
+		return err
+	}
+	if !allowed {
+		return errorx.ErrVerificationRateLimited
+	}
+
+	return a.sendVerificationEmail(ctx, user)
+}
+
+func (a *AuthUsecase) sendVerificationEmail(ctx context.Context, user entity.User) error {
+	plainToken, err := a.issueUserToken(ctx, user.ID, entity.UserTokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	a.notifier.NotifyUser(ctx, user.ID, mailer.TemplateVerifyEmail, map[string]any{
+		"VerifyURL": a.frontendURL + "/verify-email?token=" + url.QueryEscape(plainToken),
+		"ExpiresIn": "24 hours",
+	})
+	return nil
+}
+
+// ForgotPassword emails a reset link when the address belongs to an email account.
+// It never tells the caller whether the address is registered.
+func (a *AuthUsecase) ForgotPassword(ctx context.Context, request dto.ForgotPasswordRequest) error {
+	if err := validator.New().Struct(request); err != nil {
+		a.log.Errorf("[AuthUsecase] Validate Forgot Password Error: %v", err.Error())
+		return err
+	}
+
+	user, _, err := a.user.FindByEmail(ctx, request.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if _, err := a.authProvider.FindByUserIDAndProvider(ctx, user.ID, "email"); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			a.log.Infof("[AuthUsecase] User %d has no password to reset", user.ID)
+			return nil
+		}
+		return err
+	}
+
+	allowed, err := a.canIssueUserToken(ctx, user.ID, entity.UserTokenPurposePasswordReset)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Check Reset Token Limit Error: %v", err.Error())
+		return err
+	}
+	if !allowed {
+		a.log.Warnf("[AuthUsecase] Password reset for user %d is rate limited", user.ID)
+		return nil
+	}
+
+	plainToken, err := a.issueUserToken(ctx, user.ID, entity.UserTokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Issue Reset Token Error: %v", err.Error())
+		return err
+	}
+
+	a.notifier.NotifyUser(ctx, user.ID, mailer.TemplatePasswordReset, map[string]any{
+		"ResetURL":  a.frontendURL + "/reset-password?token=" + url.QueryEscape(plainToken),
+		"ExpiresIn": "1 hour",
+	})
+	return nil
+}
+
+// ResetPassword sets a new password from a reset token and signs the user out of
+// every device.
+func (a *AuthUsecase) ResetPassword(ctx context.Context, request dto.ResetPasswordRequest) error {
+	if err := validator.New().Struct(request); err != nil {
+		a.log.Errorf("[AuthUsecase] Validate Reset Password Error: %v", err.Error())
+		return err
+	}
+
+	token, err := a.userToken.FindByHash(ctx, entity.UserTokenPurposePasswordReset, helpers.HashToken(request.Token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errorx.ErrResetTokenInvalid
+		}
+		a.log.Errorf("[AuthUsecase] Find Reset Token Error: %v", err.Error())
+		return err
+	}
+
+	if token.UsedAt != nil {
+		return errorx.ErrResetTokenInvalid
+	}
+
+	if token.IsExpired() {
+		return errorx.ErrResetTokenExpired
+	}
+
+	authProvider, err := a.authProvider.FindByUserIDAndProvider(ctx, token.UserID, "email")
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Find Email Provider Error: %v", err.Error())
+		return err
+	}
+
+	hashedPassword, err := helpers.Hash(request.Password)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Hash Password Error: %v", err.Error())
+		return err
+	}
+
+	var revokedSessions []string
+	err = a.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		used, err := a.userToken.MarkUsed(txCtx, token.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errorx.ErrResetTokenInvalid
+		}
+
+		if err := a.authProvider.UpdatePassword(txCtx, authProvider.AuthProviderID, hashedPassword); err != nil {
+			return err
+		}
+
+		if err := a.userToken.InvalidateAll(txCtx, token.UserID, entity.UserTokenPurposePasswordReset); err != nil {
+			return err
+		}
+
+		revokedSessions, err = a.token.RevokeByUserID(txCtx, token.UserID, "")
+		return err
+	})
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Reset Password Error: %v", err.Error())
+		return err
+	}
+
+	a.denySessions(ctx, revokedSessions)
+
+	a.notifier.NotifyUser(ctx, token.UserID, mailer.TemplatePasswordChanged, nil)
+	return nil
+}
+
+// ChangePassword replaces the password of a signed in user. Every other device is
+// signed out, the session making the request stays signed in.
+func (a *AuthUsecase) ChangePassword(ctx context.Context, userID int64, request dto.ChangePasswordRequest, currentSessionID string) error {
+	if err := validator.New().Struct(request); err != nil {
+		a.log.Errorf("[AuthUsecase] Validate Change Password Error: %v", err.Error())
+		return err
+	}
+
+	authProvider, err := a.authProvider.FindByUserIDAndProvider(ctx, userID, "email")
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errorx.ErrNoPassword
+		}
+		a.log.Errorf("[AuthUsecase] Find Email Provider Error: %v", err.Error())
+		return err
+	}
+
+	if !helpers.Compare([]byte(authProvider.Password.String), request.CurrentPassword) {
+		return errorx.ErrInvalidCredentials
+	}
+
+	hashedPassword, err := helpers.Hash(request.NewPassword)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Hash Password Error: %v", err.Error())
+		return err
+	}
+
+	var revokedSessions []string
+	err = a.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := a.authProvider.UpdatePassword(txCtx, authProvider.AuthProviderID, hashedPassword); err != nil {
+			return err
+		}
+
+		if err := a.userToken.InvalidateAll(txCtx, userID, entity.UserTokenPurposePasswordReset); err != nil {
+			return err
+		}
+
+		var err error
+		revokedSessions, err = a.token.RevokeByUserID(txCtx, userID, currentSessionID)
+		return err
+	})
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Change Password Error: %v", err.Error())
+		return err
+	}
+
+	a.denySessions(ctx, revokedSessions)
+
+	a.notifier.NotifyUser(ctx, userID, mailer.TemplatePasswordChanged, nil)
+	return nil
+}
+
+// canIssueUserToken reports whether the user may be sent another token of the
+// purpose: once per userTokenResendCooldown and at most userTokenDailyLimit a day.
+func (a *AuthUsecase) canIssueUserToken(ctx context.Context, userID int64, purpose string) (bool, error) {
+	latest, err := a.userToken.FindLatest(ctx, userID, purpose)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	if err == nil && time.Since(latest.CreatedAt) < userTokenResendCooldown {
+		return false, nil
+	}
+
+	sent, err := a.userToken.CountSince(ctx, userID, purpose, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return false, err
+	}
+	return sent < userTokenDailyLimit, nil
+}
+
+// issueUserToken replaces any pending token of the purpose with a new one and
+// returns its plain value, only the hash is stored.
+func (a *AuthUsecase) issueUserToken(ctx context.Context, userID int64, purpose string, ttl time.Duration) (string, error) {
+	plainToken, err := helpers.GenerateToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = a.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := a.userToken.InvalidateAll(txCtx, userID, purpose); err != nil {
+			return err
+		}
+		return a.userToken.Create(txCtx, &entity.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: helpers.HashToken(plainToken),
+			ExpiresAt: time.Now().Add(ttl),
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return plainToken, nil
+}
+
 func (a *AuthUsecase) Login(ctx context.Context, request dto.LoginRequest) (dto.LoginResponse, string, error) {
 	if err := validator.New().Struct(request); err != nil {
 		a.log.Errorf("[AuthUsecase] Validate Register Error: %v", err.Error())
 		return dto.LoginResponse{}, "", err
 	}
 
+	ipFailures, err := a.loginHistory.CountFailuresByIP(ctx, request.IPAddress, time.Now().Add(-ipFailureWindow))
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Count IP Failures Error: %v", err.Error())
+		return dto.LoginResponse{}, "", err
+	}
+	if ipFailures >= ipFailureLimit {
+		a.log.WithFields(logrus.Fields{"event": "login_ip_blocked", "ip_address": request.IPAddress}).Warn("[AuthUsecase] Too many failed logins from IP")
+		return dto.LoginResponse{}, "", errorx.ErrTooManyLoginAttempts
+	}
+
 	user, isFound, err := a.user.FindByEmail(ctx, request.Email)
 	if err != nil {
 		if !isFound {
 			a.log.Errorf("[AuthUsecase] Email Not Found: %v", err.Error())
+			a.recordLoginFailure(ctx, nil, request, entity.LoginFailureUnknownEmail)
 			return dto.LoginResponse{}, "", errorx.ErrInvalidLogin
 		}
 		a.log.Errorf("[AuthUsecase] FindByEmail Error: %v", err.Error())
 		return dto.LoginResponse{}, "", err
 	}
 
-	authProvider, err := a.authProvider.FindByUserID(ctx, user.ID)
+	if user.IsLocked() {
+		a.recordLoginFailure(ctx, &user.ID, request, entity.LoginFailureLocked)
+		return dto.LoginResponse{}, "", errorx.ErrAccountLocked
+	}
+
+	authProvider, err := a.authProvider.FindByUserIDAndProvider(ctx, user.ID, "email")
 	if err != nil {
-		a.log.Errorf("[AuthUsecase] FindByEmail on AuthProvider Error: %v", err.Error())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// the account signs in with Google only
+			return dto.LoginResponse{}, "", errorx.ErrInvalidLogin
+		}
+		a.log.Errorf("[AuthUsecase] FindByUserIDAndProvider on AuthProvider Error: %v", err.Error())
 		return dto.LoginResponse{}, "", err
 	}
 
 	isMatch := helpers.Compare([]byte(authProvider.Password.String), request.Password)
 	if !isMatch {
+		a.recordLoginFailure(ctx, &user.ID, request, entity.LoginFailureWrongPassword)
+		if err := a.registerFailedLogin(ctx, user); err != nil {
+			return dto.LoginResponse{}, "", err
+		}
 		return dto.LoginResponse{}, "", errorx.ErrInvalidCredentials
 	}
 
-	var seller *entity.Seller
-	if user.Role == "seller" {
-		seller, _ = a.seller.GetSeller(ctx, user.ID)
+	if user.FailedLoginAttempts > 0 || user.LockoutCount > 0 {
+		if err := a.user.ResetFailedLogins(ctx, user.ID); err != nil {
+			a.log.Errorf("[AuthUsecase] Reset Failed Logins Error: %v", err.Error())
+		}
 	}
 
-	if seller == nil {
-		seller = &entity.Seller{ID: 0}
+	if challenge, ok, err := a.challengeTwoFactor(ctx, user); err != nil || ok {
+		return challenge, "", err
 	}
 
-	accessToken := a.jwt.IssueAccessToken(dto.JwtPayload{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
-		SellerID: seller.ID,
-	})
-
-	plainTextRefreshToken := uuid.New().String()
+	a.recordLoginSuccess(ctx, user.ID, "email", request.IPAddress, request.DeviceInfo)
 
-	expiresAt := time.Now().Add(a.jwt.Config.RefreshTTL)
-	refreshToken := entity.RefreshToken{
-		TokenId:    uuid.New().String(),
-		UserId:     user.ID,
-		TokenHash:  plainTextRefreshToken,
-		Role:       user.Role,
-		DeviceInfo: request.DeviceInfo,
-		IsRevoked:  false,
-		ExpiresAt:  expiresAt,
-	}
-
-	if _, err := a.token.CreateOrUpdate(ctx, &refreshToken); err != nil {
-		a.log.Errorf("[AuthUsecase] Failed to save refresh token: %v", err)
+	accessToken, plainTextRefreshToken, sellerID, err := a.issueTokens(ctx, user, request.DeviceInfo)
+	if err != nil {
 		return dto.LoginResponse{}, "", err
 	}
 	return dto.LoginResponse{
-		ID:          user.ID,
-		Username:    user.Username,
-		FirstName:   user.FirstName,
-		LastName:    user.LastName,
-		PhoneNumber: user.PhoneNumber,
-		Email:       user.Email,
-		AccessToken: accessToken,
-		Role:        user.Role,
-		SellerID:    seller.ID,
+		ID:            user.ID,
+		Username:      user.Username,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		PhoneNumber:   user.PhoneNumber,
+		Email:         user.Email,
+		AccessToken:   accessToken,
+		Role:          user.Role,
+		SellerID:      sellerID,
+		EmailVerified: user.IsEmailVerified(),
 	}, plainTextRefreshToken, nil
 }
 
-func (a *AuthUsecase) RefreshAccessToken(ctx context.Context, refreshToken string) (string, error) {
+// RefreshAccessToken rotates the refresh token and returns a new access token with
+// the new refresh token. A token that was already rotated or revoked revokes its
+// whole family, whoever presented it may have stolen it.
+func (a *AuthUsecase) RefreshAccessToken(ctx context.Context, refreshToken string) (string, string, error) {
 	if refreshToken == "" {
-		return "", errorx.ErrTokenEmpty
+		return "", "", errorx.ErrTokenEmpty
 	}
 
-	token, err := a.token.FindByAccessToken(ctx, refreshToken)
-	if err != nil {
-		a.log.Errorf("[AuthUsecase] Find Refresh Token Error: %v", err.Error())
-		return "", err
-	}
+	var (
+		current entity.RefreshToken
+		rotated entity.RefreshToken
+		reused  bool
+	)
+	plainTextRefreshToken := uuid.New().String()
 
-	a.log.Debug("[AuthUsecase] Refresh Token Expired", token.IsExpired(), token.ExpiresAt, time.Now())
-	if token.IsExpired() {
-		a.log.Errorf("[AuthUsecase] Refresh Token is expired")
-		return "", errorx.ErrTokenExpired
-	}
+	err := a.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		current, err = a.token.FindByAccessTokenForUpdate(txCtx, refreshToken)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errorx.ErrTokenInvalid
+			}
+			return err
+		}
+
+		if current.IsRevoked {
+			reused = true
+			return a.token.RevokeFamily(txCtx, current.FamilyID)
+		}
 
-	user, err := a.user.FindByID(ctx, token.UserId)
+		if current.IsExpired() {
+			return errorx.ErrTokenExpired
+		}
+
+		if err := a.token.Revoke(txCtx, current.TokenId); err != nil {
+			return err
+		}
 
+		rotated = entity.RefreshToken{
+			TokenId:    uuid.New().String(),
+			FamilyID:   current.FamilyID,
+			UserId:     current.UserId,
+			TokenHash:  plainTextRefreshToken,
+			Role:       current.Role,
+			DeviceInfo: current.DeviceInfo,
+			ExpiresAt:  time.Now().Add(a.jwt.Config.RefreshTTL),
+			// keeps the time the session started
+			CreatedAt: current.CreatedAt,
+		}
+		return a.token.Create(txCtx, &rotated)
+	})
 	if err != nil {
-		a.log.Errorf("[AuthUsecase] Find User Error: %v", err.Error())
-		return "", err
+		a.log.Errorf("[AuthUsecase] Rotate Refresh Token Error: %v", err.Error())
+		return "", "", err
 	}
 
-	var seller *entity.Seller
-	if user.Role == "seller" {
-		seller, _ = a.seller.GetSeller(ctx, user.ID)
+	if reused {
+		a.log.WithFields(logrus.Fields{
+			"event":       "refresh_token_reuse",
+			"user_id":     current.UserId,
+			"family_id":   current.FamilyID,
+			"token_id":    current.TokenId,
+			"device_info": current.DeviceInfo,
+		}).Warn("[AuthUsecase] Revoked refresh token presented again, session family revoked")
+		a.denySessions(ctx, []string{current.FamilyID})
+		return "", "", errorx.ErrTokenReused
 	}
 
-	if seller == nil {
-		seller = &entity.Seller{ID: 0}
+	user, err := a.user.FindByID(ctx, rotated.UserId)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Find User Error: %v", err.Error())
+		return "", "", err
 	}
 
-	newAccessToken := a.jwt.IssueAccessToken(dto.JwtPayload{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
-		SellerID: seller.ID,
-	})
-
-	return newAccessToken, nil
+	newAccessToken, _, err := a.issueAccessToken(ctx, user, rotated.FamilyID)
+	if err != nil {
+		return "", "", err
+	}
+	return newAccessToken, plainTextRefreshToken, nil
 }
 
 func (a *AuthUsecase) LoginOrRegisterWithGoogle(ctx context.Context, request dto.LoginWithGoogleData) (dto.LoginResponse, string, error) {
@@ -231,10 +640,13 @@ func (a *AuthUsecase) LoginOrRegisterWithGoogle(ctx context.Context, request dto
 	user, err := a.user.FindByID(ctx, authProvider.UserId)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Google has already confirmed the address belongs to the account
+			verifiedAt := time.Now()
 			tempUser := entity.User{
-				Email:      request.Email,
-				FirstName:  request.FirstName,
-				ProfileUrl: request.PictureUrl,
+				Email:           request.Email,
+				FirstName:       request.FirstName,
+				ProfileUrl:      request.PictureUrl,
+				EmailVerifiedAt: &verifiedAt,
 			}
 			err = a.user.Create(ctx, &tempUser)
 			if err != nil {
@@ -254,70 +666,591 @@ func (a *AuthUsecase) LoginOrRegisterWithGoogle(ctx context.Context, request dto
 				return dto.LoginResponse{}, "", err
 			}
 			user = tempUser
+			a.notifier.NotifyUser(ctx, user.ID, mailer.TemplateWelcome, nil)
 		} else {
 			a.log.Errorf("[AuthUsecase] Find User Error: %v", err.Error())
 			return dto.LoginResponse{}, "", err
 		}
 	}
 
-	var seller *entity.Seller
-	if user.Role == "seller" {
-		seller, _ = a.seller.GetSeller(ctx, user.ID)
+	if user.IsLocked() {
+		return dto.LoginResponse{}, "", errorx.ErrAccountLocked
 	}
 
-	if seller == nil {
-		seller = &entity.Seller{ID: 0}
+	if challenge, ok, err := a.challengeTwoFactor(ctx, user); err != nil || ok {
+		return challenge, "", err
 	}
 
-	accessToken := a.jwt.IssueAccessToken(dto.JwtPayload{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
-		SellerID: seller.ID,
+	a.recordLoginSuccess(ctx, user.ID, "google", request.IPAddress, request.DeviceInfo)
+
+	accessToken, plainTextRefreshToken, sellerID, err := a.issueTokens(ctx, user, request.DeviceInfo)
+	if err != nil {
+		return dto.LoginResponse{}, "", err
+	}
+
+	return dto.LoginResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		PhoneNumber:   user.PhoneNumber,
+		Email:         user.Email,
+		AccessToken:   accessToken,
+		ProfileUrl:    user.ProfileUrl,
+		Role:          user.Role,
+		SellerID:      sellerID,
+		EmailVerified: user.IsEmailVerified(),
+	}, plainTextRefreshToken, nil
+}
+
+func (a *AuthUsecase) Logout(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return errorx.ErrTokenEmpty
+	}
+
+	token, err := a.token.FindByAccessToken(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		a.log.Errorf("[AuthUsecase] Find Refresh Token Error: %v", err.Error())
+		return err
+	}
+
+	// revoked rather than deleted so a replay of the cookie is still recognised
+	if err := a.token.RevokeFamily(ctx, token.FamilyID); err != nil {
+		a.log.Errorf("[AuthUsecase] Revoke Refresh Token Error: %v", err.Error())
+		return err
+	}
+
+	a.denySessions(ctx, []string{token.FamilyID})
+	return nil
+}
+
+func (a *AuthUsecase) GetSessions(ctx context.Context, userID int64, currentSessionID string) ([]dto.SessionResponse, error) {
+	tokens, err := a.token.FindActiveByUserID(ctx, userID)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Find Sessions Error: %v", err.Error())
+		return nil, err
+	}
+
+	sessions := make([]dto.SessionResponse, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, dto.SessionResponse{
+			ID:         token.FamilyID,
+			DeviceInfo: token.DeviceInfo,
+			Current:    token.FamilyID == currentSessionID,
+			CreatedAt:  token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+		})
+	}
+	return sessions, nil
+}
+
+func (a *AuthUsecase) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	revoked, err := a.token.RevokeByID(ctx, userID, sessionID)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Revoke Session Error: %v", err.Error())
+		return err
+	}
+	if !revoked {
+		return errorx.ErrSessionNotFound
+	}
+
+	a.denySessions(ctx, []string{sessionID})
+	return nil
+}
+
+func (a *AuthUsecase) RevokeOtherSessions(ctx context.Context, userID int64, currentSessionID string) (int, error) {
+	if currentSessionID == "" {
+		// tokens issued before sessions existed cannot tell which device they belong to
+		return 0, errorx.ErrSessionNotFound
+	}
+
+	revoked, err := a.token.RevokeByUserID(ctx, userID, currentSessionID)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Revoke Other Sessions Error: %v", err.Error())
+		return 0, err
+	}
+
+	a.denySessions(ctx, revoked)
+	return len(revoked), nil
+}
+
+// registerFailedLogin counts a wrong password and locks the account once it reaches
+// maxFailedLogins. Every lockout since the last successful login doubles the next one.
+func (a *AuthUsecase) registerFailedLogin(ctx context.Context, user entity.User) error {
+	attempts, err := a.user.IncrementFailedLogins(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if attempts < maxFailedLogins {
+		return nil
+	}
+
+	duration := lockoutBaseDuration << min(user.LockoutCount, 16)
+	if duration > lockoutMaxDuration {
+		duration = lockoutMaxDuration
+	}
+	lockedUntil := time.Now().Add(duration)
+
+	if err := a.user.Lock(ctx, user.ID, lockedUntil); err != nil {
+		return err
+	}
+
+	a.log.WithFields(logrus.Fields{
+		"event":        "account_locked",
+		"user_id":      user.ID,
+		"locked_until": lockedUntil,
+	}).Warn("[AuthUsecase] Account locked after repeated failed logins")
+
+	a.notifier.NotifyUser(ctx, user.ID, mailer.TemplateAccountLocked, map[string]any{
+		"LockedUntil": lockedUntil.Format("02 Jan 2006 15:04 MST"),
+		"ResetURL":    a.frontendURL + "/forgot-password",
 	})
+	return nil
+}
 
-	plainTextRefreshToken := uuid.New().String()
+func (a *AuthUsecase) recordLoginFailure(ctx context.Context, userID *int64, request dto.LoginRequest, reason string) {
+	history := entity.LoginHistory{
+		UserID:        userID,
+		Email:         request.Email,
+		Method:        "email",
+		IPAddress:     request.IPAddress,
+		DeviceInfo:    request.DeviceInfo,
+		Success:       false,
+		FailureReason: reason,
+	}
+	if err := a.loginHistory.Create(ctx, &history); err != nil {
+		a.log.Errorf("[AuthUsecase] Record Login Failure Error: %v", err.Error())
+	}
+}
 
-	expiresAt := time.Now().Add(a.jwt.Config.RefreshTTL)
+// recordLoginSuccess stores the login, flagging the first one from a device
+func (a *AuthUsecase) recordLoginSuccess(ctx context.Context, userID int64, method, ipAddress, deviceInfo string) {
+	seen, err := a.loginHistory.HasSucceededFromDevice(ctx, userID, deviceInfo)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Check Login Device Error: %v", err.Error())
+	}
+
+	history := entity.LoginHistory{
+		UserID:     &userID,
+		Method:     method,
+		IPAddress:  ipAddress,
+		DeviceInfo: deviceInfo,
+		Success:    true,
+		NewDevice:  err == nil && !seen,
+	}
+	if err := a.loginHistory.Create(ctx, &history); err != nil {
+		a.log.Errorf("[AuthUsecase] Record Login Error: %v", err.Error())
+	}
+}
+
+func (a *AuthUsecase) GetLoginHistory(ctx context.Context, userID int64, page, limit int) (*dto.LoginHistoryListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	histories, total, err := a.loginHistory.FindByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Find Login History Error: %v", err.Error())
+		return nil, err
+	}
+
+	return &dto.LoginHistoryListResponse{
+		Histories:  histories,
+		TotalCount: total,
+		Page:       page,
+		Limit:      limit,
+	}, nil
+}
+
+func (a *AuthUsecase) GetAuthProviders(ctx context.Context, userID int64) ([]dto.AuthProviderResponse, error) {
+	authProviders, err := a.authProvider.FindAllByUserID(ctx, userID)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Find Providers Error: %v", err.Error())
+		return nil, err
+	}
+
+	responses := make([]dto.AuthProviderResponse, 0, len(authProviders))
+	for _, authProvider := range authProviders {
+		responses = append(responses, dto.AuthProviderResponse{Provider: authProvider.Provider})
+	}
+	return responses, nil
+}
+
+// LinkGoogleProvider connects a Google account to the user, request must come
+// from an id token the caller has already verified.
+func (a *AuthUsecase) LinkGoogleProvider(ctx context.Context, userID int64, request dto.LoginWithGoogleData) error {
+	linked, err := a.authProvider.FindByProviderId(ctx, request.ID, "google")
+	if err == nil {
+		if linked.UserId == userID {
+			return errorx.ErrProviderAlreadyLinked
+		}
+		return errorx.ErrProviderLinkedToAnother
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		a.log.Errorf("[AuthUsecase] Find Provider Error: %v", err.Error())
+		return err
+	}
+
+	if err := a.linkProvider(ctx, entity.AuthProvider{
+		UserId:     userID,
+		Provider:   "google",
+		ProviderId: request.ID,
+	}); err != nil {
+		return err
+	}
+
+	a.notifier.NotifyUser(ctx, userID, mailer.TemplateSignInChanged, map[string]any{
+		"Provider": "Google",
+		"Action":   "added",
+	})
+	return nil
+}
+
+// LinkEmailProvider adds a password to an account that only signs in with Google.
+func (a *AuthUsecase) LinkEmailProvider(ctx context.Context, userID int64, request dto.SetPasswordRequest) error {
+	if err := validator.New().Struct(request); err != nil {
+		a.log.Errorf("[AuthUsecase] Validate Set Password Error: %v", err.Error())
+		return err
+	}
+
+	user, err := a.user.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errorx.ErrUserNotFound
+		}
+		a.log.Errorf("[AuthUsecase] Find User Error: %v", err.Error())
+		return err
+	}
+
+	hashedPassword, err := helpers.Hash(request.Password)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Hash Password Error: %v", err.Error())
+		return err
+	}
+
+	if err := a.linkProvider(ctx, entity.AuthProvider{
+		UserId:     userID,
+		Provider:   "email",
+		ProviderId: user.Email,
+		Password:   sql.NullString{String: hashedPassword, Valid: true},
+	}); err != nil {
+		return err
+	}
+
+	a.notifier.NotifyUser(ctx, userID, mailer.TemplateSignInChanged, map[string]any{
+		"Provider": "Password",
+		"Action":   "added",
+	})
+	return nil
+}
+
+// linkProvider stores the provider unless the user already has one of its kind.
+// The user's providers stay locked so a concurrent unlink sees the new one.
+func (a *AuthUsecase) linkProvider(ctx context.Context, authProvider entity.AuthProvider) error {
+	err := a.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := a.authProvider.FindAllByUserIDForUpdate(txCtx, authProvider.UserId)
+		if err != nil {
+			return err
+		}
+		for _, p := range existing {
+			if p.Provider == authProvider.Provider {
+				return errorx.ErrProviderAlreadyLinked
+			}
+		}
+		return a.authProvider.Create(txCtx, &authProvider)
+	})
+	if err != nil && !errors.Is(err, errorx.ErrProviderAlreadyLinked) {
+		a.log.Errorf("[AuthUsecase] Link Provider Error: %v", err.Error())
+	}
+	return err
+}
+
+// UnlinkProvider removes a sign-in method, the last remaining one cannot be removed.
+func (a *AuthUsecase) UnlinkProvider(ctx context.Context, userID int64, provider string) error {
+	if provider != "email" && provider != "google" {
+		return errorx.ErrProviderNotSupported
+	}
+
+	err := a.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		// locking every provider of the user keeps two unlinks from each seeing
+		// the other one as the remaining method
+		authProviders, err := a.authProvider.FindAllByUserIDForUpdate(txCtx, userID)
+		if err != nil {
+			return err
+		}
+
+		var target *entity.AuthProvider
+		for i := range authProviders {
+			if authProviders[i].Provider == provider {
+				target = &authProviders[i]
+			}
+		}
+		if target == nil {
+			return errorx.ErrProviderNotLinked
+		}
+		if len(authProviders) <= 1 {
+			return errorx.ErrLastProvider
+		}
+
+		return a.authProvider.Delete(txCtx, target.AuthProviderID)
+	})
+	if err != nil {
+		if !errors.Is(err, errorx.ErrProviderNotLinked) && !errors.Is(err, errorx.ErrLastProvider) {
+			a.log.Errorf("[AuthUsecase] Unlink Provider Error: %v", err.Error())
+		}
+		return err
+	}
+
+	providerName := "Google"
+	if provider == "email" {
+		providerName = "Password"
+	}
+	a.notifier.NotifyUser(ctx, userID, mailer.TemplateSignInChanged, map[string]any{
+		"Provider": providerName,
+		"Action":   "removed",
+	})
+	return nil
+}
+
+// challengeTwoFactor starts the second step of a sign in when the user has 2FA on
+// or their role requires it. It reports false when the tokens can be issued now.
+func (a *AuthUsecase) challengeTwoFactor(ctx context.Context, user entity.User) (dto.LoginResponse, bool, error) {
+	enabled, err := a.twoFactor.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return dto.LoginResponse{}, false, err
+	}
+
+	required := false
+	if !enabled {
+		required, err = a.twoFactor.IsRequired(ctx, user.Role)
+		if err != nil {
+			return dto.LoginResponse{}, false, err
+		}
+		if !required {
+			return dto.LoginResponse{}, false, nil
+		}
+	}
+
+	challengeToken, err := a.issueUserToken(ctx, user.ID, entity.UserTokenPurposeTwoFactorLogin, twoFactorChallengeTTL)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Issue Two Factor Challenge Error: %v", err.Error())
+		return dto.LoginResponse{}, false, err
+	}
+
+	return dto.LoginResponse{
+		ID:                     user.ID,
+		Email:                  user.Email,
+		Role:                   user.Role,
+		TwoFactorRequired:      enabled,
+		TwoFactorSetupRequired: required,
+		ChallengeToken:         challengeToken,
+	}, true, nil
+}
+
+// findTwoFactorChallenge returns the user behind a pending challenge token
+// without consuming it
+func (a *AuthUsecase) findTwoFactorChallenge(ctx context.Context, challengeToken string) (entity.UserToken, entity.User, error) {
+	challenge, err := a.userToken.FindByHash(ctx, entity.UserTokenPurposeTwoFactorLogin, helpers.HashToken(challengeToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.UserToken{}, entity.User{}, errorx.ErrTwoFactorChallengeInvalid
+		}
+		a.log.Errorf("[AuthUsecase] Find Two Factor Challenge Error: %v", err.Error())
+		return entity.UserToken{}, entity.User{}, err
+	}
+	if challenge.UsedAt != nil || challenge.IsExpired() {
+		return entity.UserToken{}, entity.User{}, errorx.ErrTwoFactorChallengeInvalid
+	}
+
+	user, err := a.user.FindByID(ctx, challenge.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.UserToken{}, entity.User{}, errorx.ErrTwoFactorChallengeInvalid
+		}
+		return entity.UserToken{}, entity.User{}, err
+	}
+	return challenge, user, nil
+}
+
+func (a *AuthUsecase) StartTwoFactorSetup(ctx context.Context, request dto.TwoFactorChallengeRequest) (*dto.TwoFactorEnrollResponse, error) {
+	if err := validator.New().Struct(request); err != nil {
+		return nil, err
+	}
+
+	_, user, err := a.findTwoFactorChallenge(ctx, request.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.twoFactor.Enroll(ctx, user.ID)
+}
+
+// VerifyTwoFactorLogin finishes a sign in that was challenged for a second factor.
+// When the challenge asked for setup, the code confirms the new secret and the
+// recovery codes are returned with the tokens.
+func (a *AuthUsecase) VerifyTwoFactorLogin(ctx context.Context, request dto.TwoFactorVerifyRequest) (dto.LoginResponse, string, error) {
+	if err := validator.New().Struct(request); err != nil {
+		return dto.LoginResponse{}, "", err
+	}
+
+	challenge, user, err := a.findTwoFactorChallenge(ctx, request.ChallengeToken)
+	if err != nil {
+		return dto.LoginResponse{}, "", err
+	}
+
+	if user.IsLocked() {
+		return dto.LoginResponse{}, "", errorx.ErrAccountLocked
+	}
+
+	enabled, err := a.twoFactor.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return dto.LoginResponse{}, "", err
+	}
+
+	var recoveryCodes []string
+	ok := false
+	switch {
+	case enabled && request.RecoveryCode != "":
+		ok, err = a.twoFactor.VerifyRecoveryCode(ctx, user.ID, request.RecoveryCode)
+	case enabled:
+		ok, err = a.twoFactor.VerifyCode(ctx, user.ID, request.Code)
+	default:
+		var codes *dto.TwoFactorRecoveryCodesResponse
+		codes, err = a.twoFactor.Enable(ctx, user.ID, dto.TwoFactorCodeRequest{Code: request.Code})
+		if errors.Is(err, errorx.ErrTwoFactorCodeInvalid) {
+			err = nil
+		}
+		if codes != nil {
+			ok = true
+			recoveryCodes = codes.RecoveryCodes
+		}
+	}
+	if err != nil {
+		return dto.LoginResponse{}, "", err
+	}
+
+	attempt := dto.LoginRequest{Email: user.Email, DeviceInfo: request.DeviceInfo, IPAddress: request.IPAddress}
+	if !ok {
+		a.recordLoginFailure(ctx, &user.ID, attempt, entity.LoginFailureWrongCode)
+		if err := a.registerFailedLogin(ctx, user); err != nil {
+			return dto.LoginResponse{}, "", err
+		}
+		return dto.LoginResponse{}, "", errorx.ErrTwoFactorCodeInvalid
+	}
+
+	consumed, err := a.userToken.MarkUsed(ctx, challenge.ID)
+	if err != nil {
+		a.log.Errorf("[AuthUsecase] Mark Two Factor Challenge Used Error: %v", err.Error())
+		return dto.LoginResponse{}, "", err
+	}
+	if !consumed {
+		return dto.LoginResponse{}, "", errorx.ErrTwoFactorChallengeInvalid
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockoutCount > 0 {
+		if err := a.user.ResetFailedLogins(ctx, user.ID); err != nil {
+			a.log.Errorf("[AuthUsecase] Reset Failed Logins Error: %v", err.Error())
+		}
+	}
+
+	a.recordLoginSuccess(ctx, user.ID, "two_factor", request.IPAddress, request.DeviceInfo)
+
+	accessToken, plainTextRefreshToken, sellerID, err := a.issueTokens(ctx, user, request.DeviceInfo)
+	if err != nil {
+		return dto.LoginResponse{}, "", err
+	}
+	return dto.LoginResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		PhoneNumber:   user.PhoneNumber,
+		Email:         user.Email,
+		AccessToken:   accessToken,
+		ProfileUrl:    user.ProfileUrl,
+		Role:          user.Role,
+		SellerID:      sellerID,
+		EmailVerified: user.IsEmailVerified(),
+		RecoveryCodes: recoveryCodes,
+	}, plainTextRefreshToken, nil
+}
+
+// issueTokens starts a new session for the device and returns its access token, the
+// plain refresh token and the seller ID of the user.
+func (a *AuthUsecase) issueTokens(ctx context.Context, user entity.User, deviceInfo string) (string, string, int64, error) {
+	plainTextRefreshToken := uuid.New().String()
 	refreshToken := entity.RefreshToken{
 		TokenId:    uuid.New().String(),
+		FamilyID:   uuid.New().String(),
 		UserId:     user.ID,
 		TokenHash:  plainTextRefreshToken,
 		Role:       user.Role,
-		DeviceInfo: request.DeviceInfo,
+		DeviceInfo: deviceInfo,
 		IsRevoked:  false,
-		ExpiresAt:  expiresAt,
+		ExpiresAt:  time.Now().Add(a.jwt.Config.RefreshTTL),
 	}
 
-	if _, err := a.token.CreateOrUpdate(ctx, &refreshToken); err != nil {
+	// a new login on the device ends the session it had, its access tokens included
+	var replacedSessions []string
+	err := a.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		var err error
+		replacedSessions, err = a.token.RevokeByDevice(txCtx, user.ID, deviceInfo)
+		if err != nil {
+			return err
+		}
+		return a.token.Create(txCtx, &refreshToken)
+	})
+	if err != nil {
 		a.log.Errorf("[AuthUsecase] Failed to save refresh token: %v", err)
-		return dto.LoginResponse{}, "", err
+		return "", "", 0, err
 	}
 
-	return dto.LoginResponse{
-		ID:          user.ID,
-		Username:    user.Username,
-		FirstName:   user.FirstName,
-		LastName:    user.LastName,
-		PhoneNumber: user.PhoneNumber,
-		Email:       user.Email,
-		AccessToken: accessToken,
-		ProfileUrl:  user.ProfileUrl,
-		Role:        user.Role,
-		SellerID:    seller.ID,
-	}, plainTextRefreshToken, nil
+	a.denySessions(ctx, replacedSessions)
+
+	accessToken, sellerID, err := a.issueAccessToken(ctx, user, refreshToken.FamilyID)
+	if err != nil {
+		return "", "", 0, err
+	}
+	return accessToken, plainTextRefreshToken, sellerID, nil
 }
 
-func (a *AuthUsecase) Logout(ctx context.Context, refreshToken string) error {
-	if refreshToken == "" {
-		return errorx.ErrTokenEmpty
+func (a *AuthUsecase) issueAccessToken(ctx context.Context, user entity.User, sessionID string) (string, int64, error) {
+	var sellerID int64
+	if user.Role == "seller" {
+		if seller, _ := a.seller.GetSeller(ctx, user.ID); seller != nil {
+			sellerID = seller.ID
+		}
 	}
 
-	err := a.token.DeleteByAccessToken(ctx, refreshToken)
+	accessToken, err := a.jwt.IssueAccessToken(dto.JwtPayload{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      user.Role,
+		SellerID:  sellerID,
+		SessionID: sessionID,
+	})
 	if err != nil {
-		a.log.Errorf("[AuthUsecase] Delete Refresh Token Error: %v", err.Error())
-		return err
+		a.log.Errorf("[AuthUsecase] Issue Access Token Error: %v", err.Error())
+		return "", 0, err
+	}
+	return accessToken, sellerID, nil
+}
+
+// denySessions makes the access tokens already issued for the sessions fail before
+// they expire. The refresh tokens are revoked in the database at this point, so a
+// failure here only leaves the access tokens valid until their TTL runs out.
+func (a *AuthUsecase) denySessions(ctx context.Context, sessionIDs []string) {
+	for _, sessionID := range sessionIDs {
+		if err := a.sessions.Revoke(ctx, sessionID, a.jwt.Config.AccessTTL); err != nil {
+			a.log.Errorf("[AuthUsecase] Deny Session %s Error: %v", sessionID, err.Error())
+		}
 	}
-	return nil
 }
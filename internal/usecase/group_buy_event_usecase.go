@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/febry3/gamingin/internal/infra/realtime"
+	"github.com/sirupsen/logrus"
+)
+
+// Event types pushed to the live streams of buyer sessions and campaigns
+const (
+	GroupBuyEventMemberJoined      = "member_joined"
+	GroupBuyEventMemberLeft        = "member_left"
+	GroupBuyEventPaymentReceived   = "payment_received"
+	GroupBuyEventTierReached       = "tier_reached"
+	GroupBuyEventSessionLocked     = "session_locked"
+	GroupBuyEventSessionClosed     = "session_closed"
+	GroupBuyEventSessionExpired    = "session_expired"
+	GroupBuyEventCampaignStarted   = "campaign_started"
+	GroupBuyEventCampaignEnded     = "campaign_ended"
+	GroupBuyEventCampaignCancelled = "campaign_cancelled"
+)
+
+type GroupBuyEventUsecaseContract interface {
+	// Publish sends an event to the buyer session's stream and to its campaign's stream,
+	// an empty buyerSessionID only reaches the campaign. Failures are logged, a live
+	// update never fails the action that caused it.
+	Publish(ctx context.Context, campaignID string, buyerSessionID string, eventType string, data map[string]any)
+	SubscribeBuyerSession(ctx context.Context, buyerSessionID string) (<-chan realtime.Event, func(), error)
+	SubscribeCampaign(ctx context.Context, campaignID string) (<-chan realtime.Event, func(), error)
+}
+
+type GroupBuyEventUsecase struct {
+	broker realtime.Broker
+	log    *logrus.Logger
+}
+
+func NewGroupBuyEventUsecase(broker realtime.Broker, log *logrus.Logger) GroupBuyEventUsecaseContract {
+	return &GroupBuyEventUsecase{broker: broker, log: log}
+}
+
+func (e *GroupBuyEventUsecase) Publish(ctx context.Context, campaignID string, buyerSessionID string, eventType string, data map[string]any) {
+	if data == nil {
+		data = map[string]any{}
+	}
+	if buyerSessionID != "" {
+		data["buyer_session_id"] = buyerSessionID
+	}
+
+	event := realtime.Event{Type: eventType, Data: data, At: time.Now()}
+
+	if buyerSessionID != "" {
+		if err := e.broker.Publish(ctx, buyerSessionChannel(buyerSessionID), event); err != nil {
+			e.log.Errorf("[GroupBuyEventUsecase] Failed to publish %s to buyer session %s: %v", eventType, buyerSessionID, err)
+		}
+	}
+	if campaignID != "" {
+		if err := e.broker.Publish(ctx, campaignChannel(campaignID), event); err != nil {
+			e.log.Errorf("[GroupBuyEventUsecase] Failed to publish %s to campaign %s: %v", eventType, campaignID, err)
+		}
+	}
+}
+
+func (e *GroupBuyEventUsecase) SubscribeBuyerSession(ctx context.Context, buyerSessionID string) (<-chan realtime.Event, func(), error) {
+	return e.broker.Subscribe(ctx, buyerSessionChannel(buyerSessionID))
+}
+
+func (e *GroupBuyEventUsecase) SubscribeCampaign(ctx context.Context, campaignID string) (<-chan realtime.Event, func(), error) {
+	return e.broker.Subscribe(ctx, campaignChannel(campaignID))
+}
+
+func buyerSessionChannel(buyerSessionID string) string {
+	return "group_buy:session:" + buyerSessionID
+}
+
+func campaignChannel(campaignID string) string {
+	return "group_buy:campaign:" + campaignID
+}
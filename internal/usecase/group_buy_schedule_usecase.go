@@ -0,0 +1,329 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/febry3/gamingin/internal/dto"
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/errorx"
+	"github.com/febry3/gamingin/internal/helpers"
+	"github.com/febry3/gamingin/internal/infra/mailer"
+	"github.com/febry3/gamingin/internal/worker/tasks"
+	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
+)
+
+// defaultScheduleTimezone reads recurrences in WIB unless the seller names a zone
+const defaultScheduleTimezone = "Asia/Jakarta"
+
+func (g *GroupBuyUsecase) scheduleActivation(session *entity.GroupBuySession) {
+	task, err := tasks.NewGroupBuyActivationTask(tasks.GroupBuyActivationPayload{SessionID: session.ID})
+	if err != nil {
+		g.log.Errorf("failed to create activation task: %v", err)
+		return
+	}
+
+	if _, err := g.asynqClient.Enqueue(task, asynq.ProcessAt(session.StartsAt)); err != nil {
+		g.log.Errorf("failed to enqueue activation task: %v", err)
+		return
+	}
+	g.log.Infof("Scheduled activation of group buy session %s at %v", session.ID, session.StartsAt)
+}
+
+// ActivateSession reserves the stock of an upcoming campaign and opens it. A campaign
+// whose stock ran out since it was announced is cancelled instead and its reminder
+// subscribers are told. Either way a campaign from a schedule announces the next
+// occurrence.
+func (g *GroupBuyUsecase) ActivateSession(ctx context.Context, sessionID string) error {
+	var campaign *entity.GroupBuySession
+	activated := false
+	cancelReason := ""
+	err := g.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		locked, err := g.groupBuySessionRepo.FindByIDForUpdate(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		campaign = locked
+
+		if locked.Status != "upcoming" {
+			g.log.Infof("Group buy session %s is already %s, skipping activation", sessionID, locked.Status)
+			return nil
+		}
+		if !locked.ExpiresAt.After(time.Now()) {
+			g.log.Warnf("Group buy session %s expired before it was activated", sessionID)
+			cancelReason = "The group buy ended before it could start."
+			return g.groupBuySessionRepo.ChangeStatus(ctx, sessionID, "cancelled", locked.SellerID)
+		}
+
+		reserved, err := g.stockRepo.ReserveStock(ctx, locked.ProductVariantID, int(locked.MaxQuantity))
+		if err != nil {
+			return err
+		}
+		if !reserved {
+			g.log.Warnf("Group buy session %s cancelled, not enough stock for %d units", sessionID, locked.MaxQuantity)
+			cancelReason = "The seller ran out of stock before the group buy could start."
+			return g.groupBuySessionRepo.ChangeStatus(ctx, sessionID, "cancelled", locked.SellerID)
+		}
+
+		activated = true
+		return g.groupBuySessionRepo.ChangeStatus(ctx, sessionID, "active", locked.SellerID)
+	})
+	if err != nil {
+		g.log.Errorf("failed to activate group buy session: %v", err)
+		return err
+	}
+
+	if activated {
+		g.log.Infof("Group buy session %s is now active", sessionID)
+		g.events.Publish(ctx, sessionID, "", GroupBuyEventCampaignStarted, nil)
+		g.notifyCampaignStarted(ctx, sessionID)
+	}
+	if cancelReason != "" {
+		g.events.Publish(ctx, sessionID, "", GroupBuyEventCampaignCancelled, map[string]any{
+			"reason": cancelReason,
+		})
+		g.notifySubscribersCancelled(ctx, campaign, cancelReason)
+	}
+
+	if campaign.ScheduleID != nil {
+		return g.announceNextOccurrence(ctx, *campaign.ScheduleID, campaign.StartsAt)
+	}
+	return nil
+}
+
+func (g *GroupBuyUsecase) notifyCampaignStarted(ctx context.Context, sessionID string) {
+	userIDs, err := g.reminderRepo.GetUserIDsByGroupBuySessionID(ctx, sessionID)
+	if err != nil {
+		g.log.Errorf("failed to get reminders: %v", err)
+		return
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	campaign, err := g.groupBuySessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		g.log.Errorf("failed to get product session: %v", err)
+		return
+	}
+
+	productName := ""
+	if productVariant, err := g.productVariantRepo.GetProductVariant(ctx, campaign.ProductVariantID); err == nil {
+		productName = productVariant.Name
+	}
+	maxDiscount := 0.0
+	for _, tier := range campaign.GroupBuyTiers {
+		maxDiscount = max(maxDiscount, tier.DiscountPercentage)
+	}
+
+	g.notifier.NotifyInbox(ctx, userIDs, entity.Notification{
+		Type:      entity.NotificationGroupBuyStarted,
+		Title:     fmt.Sprintf("The group buy for %s is live", productName),
+		Body:      fmt.Sprintf("Start or join a group before it closes at %s.", campaign.ExpiresAt.Format("02 Jan 2006 15:04 MST")),
+		DedupeKey: fmt.Sprintf("group_buy:%s:started", sessionID),
+	}, mailer.TemplateGroupBuyStarted, map[string]any{
+		"GroupBuySessionID": sessionID,
+		"ProductName":       productName,
+		"MaxDiscount":       maxDiscount,
+		"ExpiresAt":         campaign.ExpiresAt.Format("02 Jan 2006 15:04 MST"),
+	})
+}
+
+func (g *GroupBuyUsecase) notifySubscribersCancelled(ctx context.Context, campaign *entity.GroupBuySession, reason string) {
+	userIDs, err := g.reminderRepo.GetUserIDsByGroupBuySessionID(ctx, campaign.ID)
+	if err != nil {
+		g.log.Errorf("failed to get reminders: %v", err)
+		return
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	productName := ""
+	if productVariant, err := g.productVariantRepo.GetProductVariant(ctx, campaign.ProductVariantID); err == nil {
+		productName = productVariant.Name
+	}
+	g.notifyCampaignCancelled(ctx, campaign.ID, productName, userIDs, reason)
+}
+
+// SweepSchedules backs up the activation tasks. A campaign whose task was lost is
+// activated once its start has passed, and an active schedule whose chain of
+// announcements broke gets its next occurrence.
+func (g *GroupBuyUsecase) SweepSchedules(ctx context.Context) error {
+	due, err := g.groupBuySessionRepo.FindUpcomingDue(ctx, time.Now())
+	if err != nil {
+		g.log.Errorf("failed to get due group buy sessions: %v", err)
+		return err
+	}
+	for _, campaign := range due {
+		g.log.Warnf("Group buy session %s is past its start, activating it", campaign.ID)
+		if err := g.ActivateSession(ctx, campaign.ID); err != nil {
+			g.log.Errorf("failed to activate group buy session %s: %v", campaign.ID, err)
+		}
+	}
+
+	schedules, err := g.scheduleRepo.GetActive(ctx)
+	if err != nil {
+		g.log.Errorf("failed to get group buy schedules: %v", err)
+		return err
+	}
+	for _, schedule := range schedules {
+		if err := g.announceNextOccurrence(ctx, schedule.ID, time.Now()); err != nil {
+			g.log.Errorf("failed to announce occurrence of schedule %s: %v", schedule.ID, err)
+		}
+	}
+
+	g.log.Infof("Swept %d due group buy sessions and %d schedules", len(due), len(schedules))
+	return nil
+}
+
+// announceNextOccurrence creates the upcoming campaign of the first occurrence after
+// after, unless the schedule stopped or the occurrence was already announced
+func (g *GroupBuyUsecase) announceNextOccurrence(ctx context.Context, scheduleID string, after time.Time) error {
+	schedule, err := g.scheduleRepo.FindByID(ctx, scheduleID)
+	if err != nil {
+		g.log.Errorf("failed to get group buy schedule: %v", err)
+		return err
+	}
+	if !schedule.IsActive {
+		return nil
+	}
+
+	// an activation that ran late must not announce an occurrence that already started
+	if now := time.Now(); now.After(after) {
+		after = now
+	}
+	startsAt, err := helpers.NextOccurrence(schedule.Recurrence, schedule.Timezone, after)
+	if err != nil {
+		g.log.Errorf("failed to compute next occurrence of schedule %s: %v", scheduleID, err)
+		return err
+	}
+
+	exists, err := g.groupBuySessionRepo.ExistsForSchedule(ctx, scheduleID, startsAt)
+	if err != nil {
+		return err
+	}
+	if exists {
+		g.log.Infof("Occurrence of schedule %s at %v was already announced", scheduleID, startsAt)
+		return nil
+	}
+
+	var tiers []dto.GroupBuyTierRequest
+	if err := json.Unmarshal(schedule.Tiers, &tiers); err != nil {
+		g.log.Errorf("failed to decode tiers of schedule %s: %v", scheduleID, err)
+		return err
+	}
+
+	_, err = g.createCampaign(ctx, &dto.GroupBuySessionRequest{
+		ProductVariantID:     schedule.ProductVariantID,
+		MinParticipants:      schedule.MinParticipants,
+		MaxParticipants:      schedule.MaxParticipants,
+		MaxQuantity:          schedule.MaxQuantity,
+		MaxQuantityPerMember: schedule.MaxQuantityPerMember,
+		StartsAt:             startsAt,
+		ExpiresAt:            startsAt.Add(time.Duration(schedule.DurationMinutes) * time.Minute),
+		Tiers:                tiers,
+	}, schedule.SellerID, &schedule.ID)
+	if err != nil {
+		g.log.Errorf("failed to announce occurrence of schedule %s: %v", scheduleID, err)
+		return err
+	}
+
+	g.log.Infof("Announced occurrence of schedule %s at %v", scheduleID, startsAt)
+	return nil
+}
+
+func (g *GroupBuyUsecase) CreateSchedule(ctx context.Context, request *dto.GroupBuyScheduleRequest, sellerID int64) (*entity.GroupBuySchedule, error) {
+	timezone := request.Timezone
+	if timezone == "" {
+		timezone = defaultScheduleTimezone
+	}
+	if _, err := helpers.NextOccurrence(request.Recurrence, timezone, time.Now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", errorx.ErrInvalidRecurrence, err)
+	}
+
+	maxQuantityPerMember := request.MaxQuantityPerMember
+	if maxQuantityPerMember < 1 {
+		maxQuantityPerMember = 1
+	}
+
+	tiers, err := json.Marshal(request.Tiers)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &entity.GroupBuySchedule{
+		SellerID:             sellerID,
+		ProductVariantID:     request.ProductVariantID,
+		MinParticipants:      request.MinParticipants,
+		MaxParticipants:      request.MaxParticipants,
+		MaxQuantity:          request.MaxQuantity,
+		MaxQuantityPerMember: maxQuantityPerMember,
+		Tiers:                tiers,
+		Recurrence:           request.Recurrence,
+		Timezone:             timezone,
+		DurationMinutes:      request.DurationMinutes,
+		IsActive:             true,
+	}
+
+	err = g.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := g.productVariantRepo.GetProductVariant(ctx, request.ProductVariantID); err != nil {
+			g.log.Errorf("failed to get product variant: %v", err)
+			return err
+		}
+		if err := g.scheduleRepo.Create(ctx, schedule); err != nil {
+			g.log.Errorf("failed to create group buy schedule: %v", err)
+			return err
+		}
+		return g.announceNextOccurrence(ctx, schedule.ID, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (g *GroupBuyUsecase) GetSchedules(ctx context.Context, sellerID int64) ([]entity.GroupBuySchedule, error) {
+	return g.scheduleRepo.GetAllForSeller(ctx, sellerID)
+}
+
+func (g *GroupBuyUsecase) StopSchedule(ctx context.Context, scheduleID string, sellerID int64) error {
+	return g.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		found, err := g.scheduleRepo.Deactivate(ctx, scheduleID, sellerID)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errorx.ErrScheduleNotFound
+		}
+		// nothing is reserved before a campaign starts, so calling it off frees nothing
+		return g.groupBuySessionRepo.CancelUpcomingBySchedule(ctx, scheduleID)
+	})
+}
+
+func (g *GroupBuyUsecase) SubscribeReminder(ctx context.Context, sessionID string, userID int64) error {
+	campaign, err := g.groupBuySessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errorx.ErrGroupBuySessionNotFound
+		}
+		return err
+	}
+	if campaign.Status != "upcoming" {
+		return errorx.ErrCampaignNotUpcoming
+	}
+
+	return g.reminderRepo.Create(ctx, &entity.GroupBuyReminder{
+		GroupBuySessionID: sessionID,
+		UserID:            userID,
+	})
+}
+
+func (g *GroupBuyUsecase) UnsubscribeReminder(ctx context.Context, sessionID string, userID int64) error {
+	return g.reminderRepo.Delete(ctx, sessionID, userID)
+}
@@ -2,12 +2,18 @@ package usecase
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/febry3/gamingin/internal/dto"
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/errorx"
+	"github.com/febry3/gamingin/internal/infra/invite"
+	"github.com/febry3/gamingin/internal/infra/mailer"
+	"github.com/febry3/gamingin/internal/infra/realtime"
 	"github.com/febry3/gamingin/internal/repository"
 	"github.com/febry3/gamingin/internal/worker/tasks"
 	"github.com/google/uuid"
@@ -21,16 +27,67 @@ type GroupBuyUsecaseContract interface {
 	DeleteGroupBuySession(ctx context.Context, sessionID string) error
 	FindGroupBuySessionByID(ctx context.Context, sessionID string) (*entity.GroupBuySession, error)
 	GetAllGroupBuySessionForSeller(ctx context.Context, sellerID int64) ([]entity.GroupBuySession, error)
-	GetAllGroupBuySessionForBuyer(ctx context.Context) ([]entity.GroupBuySession, error)
-	ChangeGroupBuySessionStatus(ctx context.Context, sessionID string, status string, sellerID int64) error
+	// GetCampaignAnalytics reports sessions, members, sales and the join to payment funnel of every campaign of the seller
+	GetCampaignAnalytics(ctx context.Context, sellerID int64) ([]dto.GroupBuyCampaignAnalytics, error)
+	GetAllGroupBuySessionForBuyer(ctx context.Context, filter dto.GroupBuyFeedFilter) (*dto.GroupBuyFeedResponse, error)
+	// ChangeGroupBuySessionStatus completes or cancels a seller's campaign ahead of its end
+	ChangeGroupBuySessionStatus(ctx context.Context, sessionID string, status string, reason string, sellerID int64) error
 	EndSession(ctx context.Context, sessionID string, productVariantID string, sellerID int64) error
+	// CancelSession calls off a seller's campaign, paid members are refunded and told the reason
+	CancelSession(ctx context.Context, sessionID string, sellerID int64, reason string) error
+	// ActivateSession opens an upcoming campaign at its start time
+	ActivateSession(ctx context.Context, sessionID string) error
+	// SweepSchedules activates the campaigns that are past their start and announces
+	// the occurrences that active schedules are missing
+	SweepSchedules(ctx context.Context) error
+	CreateSchedule(ctx context.Context, request *dto.GroupBuyScheduleRequest, sellerID int64) (*entity.GroupBuySchedule, error)
+	GetSchedules(ctx context.Context, sellerID int64) ([]entity.GroupBuySchedule, error)
+	// StopSchedule ends a recurring campaign, the occurrence that has not started is called off
+	StopSchedule(ctx context.Context, scheduleID string, sellerID int64) error
+	// SubscribeReminder asks for a notification when an upcoming campaign starts
+	SubscribeReminder(ctx context.Context, sessionID string, userID int64) error
+	UnsubscribeReminder(ctx context.Context, sessionID string, userID int64) error
 	CreateBuyerSession(ctx context.Context, request *dto.CreateBuyerGroupSessionRequest) (string, error)
 	GetSessionForBuyerByCode(ctx context.Context, sessionCode string, userId int64) (*dto.GetBuyerGroupSessionResponse, error)
-	JoinSession(ctx context.Context, sessionCode string, userID int64) error
+	// JoinSession adds the user to an open session, inviteToken is optional and credits
+	// the member whose link was used
+	JoinSession(ctx context.Context, sessionCode string, userID int64, quantity int, inviteToken string) error
+	// ChangeQuantity sets the units an unpaid member claims, an unpaid order of the member is cancelled
+	ChangeQuantity(ctx context.Context, sessionCode string, userID int64, quantity int) error
+	// NotifyMilestone tells every member that the session reached a tier threshold or is
+	// one member away from it, each alert is delivered once
+	NotifyMilestone(ctx context.Context, buyerSessionID string, milestone string, threshold int) error
+	// CreateInvite returns a member's signed share link for the session
+	CreateInvite(ctx context.Context, sessionCode string, userID int64) (*dto.GroupBuyInviteResponse, error)
+	// LeaveSession removes an unpaid member, an organizer who leaves hands the session to the next member
+	LeaveSession(ctx context.Context, sessionCode string, userID int64) error
+	// RemoveMember lets the organizer remove an unpaid member
+	RemoveMember(ctx context.Context, sessionCode string, organizerUserID int64, memberUserID int64) error
 	ChangeBuyerSessionStatus(ctx context.Context, buyerSessionID string, status string) error
+	// ExpireBuyerSession closes a buyer session that is still open or full and reports whether it did
+	ExpireBuyerSession(ctx context.Context, buyerSessionID string) (bool, error)
+	// SubscribeSessionEvents streams the live updates of a buyer session
+	SubscribeSessionEvents(ctx context.Context, sessionCode string) (<-chan realtime.Event, func(), error)
+	// SubscribeCampaignEvents streams the live updates of every buyer session of a seller's campaign
+	SubscribeCampaignEvents(ctx context.Context, campaignID string, sellerID int64) (<-chan realtime.Event, func(), error)
 	GetBuyerGroupSessionTier(ctx context.Context, tierID string) (*entity.GroupBuyTier, error)
 }
 
+// groupBuySoldStatuses are the order statuses whose units a payment took from the campaign
+var groupBuySoldStatuses = []string{
+	entity.OrderStatusPaid, entity.OrderStatusProcessing, entity.OrderStatusShipped, entity.OrderStatusDelivered,
+}
+
+// groupBuyPaidStatuses are the orders that were paid at some point
+var groupBuyPaidStatuses = []string{
+	entity.OrderStatusPaid, entity.OrderStatusProcessing, entity.OrderStatusShipped, entity.OrderStatusDelivered, entity.OrderStatusRefunded,
+}
+
+// TaskEnqueuer is the part of the asynq client the group buy and order flows schedule their tasks with
+type TaskEnqueuer interface {
+	Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+}
+
 type GroupBuyUsecase struct {
 	addressRepo           repository.AddressRepository
 	groupBuySessionRepo   repository.GroupBuySessionRepository
@@ -39,12 +96,20 @@ type GroupBuyUsecase struct {
 	productVariantRepo    repository.ProductVariantRepository
 	buyerGroupSessionRepo repository.BuyerGroupBuySessionRepository
 	buyerGroupMemberRepo  repository.BuyerGroupMemberRepository
+	orderRepo             repository.OrderRepository
+	stockRepo             repository.ProductVariantStockRepository
+	scheduleRepo          repository.GroupBuyScheduleRepository
+	reminderRepo          repository.GroupBuyReminderRepository
 	tx                    repository.TxManager
 	log                   *logrus.Logger
-	asynqClient           *asynq.Client
+	asynqClient           TaskEnqueuer
+	notifier              NotificationUsecaseContract
+	events                GroupBuyEventUsecaseContract
+	orders                OrderUsecaseContract
+	invites               *invite.Signer
 }
 
-func NewGroupBuyUsecase(addressRepo repository.AddressRepository, groupBuySessionRepo repository.GroupBuySessionRepository, groupBuyTierRepo repository.GroupBuyTierRepository, productRepo repository.ProductRepository, productVariantRepo repository.ProductVariantRepository, buyerGroupSessionRepo repository.BuyerGroupBuySessionRepository, buyerGroupMemberRepo repository.BuyerGroupMemberRepository, tx repository.TxManager, log *logrus.Logger, asynqClient *asynq.Client) GroupBuyUsecaseContract {
+func NewGroupBuyUsecase(addressRepo repository.AddressRepository, groupBuySessionRepo repository.GroupBuySessionRepository, groupBuyTierRepo repository.GroupBuyTierRepository, productRepo repository.ProductRepository, productVariantRepo repository.ProductVariantRepository, buyerGroupSessionRepo repository.BuyerGroupBuySessionRepository, buyerGroupMemberRepo repository.BuyerGroupMemberRepository, orderRepo repository.OrderRepository, stockRepo repository.ProductVariantStockRepository, scheduleRepo repository.GroupBuyScheduleRepository, reminderRepo repository.GroupBuyReminderRepository, tx repository.TxManager, log *logrus.Logger, asynqClient TaskEnqueuer, notifier NotificationUsecaseContract, events GroupBuyEventUsecaseContract, orders OrderUsecaseContract, invites *invite.Signer) GroupBuyUsecaseContract {
 	return &GroupBuyUsecase{
 		addressRepo:           addressRepo,
 		groupBuySessionRepo:   groupBuySessionRepo,
@@ -53,42 +118,75 @@ func NewGroupBuyUsecase(addressRepo repository.AddressRepository, groupBuySessio
 		productVariantRepo:    productVariantRepo,
 		buyerGroupSessionRepo: buyerGroupSessionRepo,
 		buyerGroupMemberRepo:  buyerGroupMemberRepo,
+		orderRepo:             orderRepo,
+		stockRepo:             stockRepo,
+		scheduleRepo:          scheduleRepo,
+		reminderRepo:          reminderRepo,
 		tx:                    tx,
 		log:                   log,
 		asynqClient:           asynqClient,
+		notifier:              notifier,
+		events:                events,
+		orders:                orders,
+		invites:               invites,
 	}
 }
 
 func (g *GroupBuyUsecase) CreateGroupBuySession(ctx context.Context, request *dto.GroupBuySessionRequest, sellerID int64) (*dto.GroupBuySessionResponse, error) {
+	return g.createCampaign(ctx, request, sellerID, nil)
+}
+
+// createCampaign opens a campaign now, or announces it as upcoming when it starts
+// later. An upcoming campaign only reserves its stock once ActivateSession opens it.
+func (g *GroupBuyUsecase) createCampaign(ctx context.Context, request *dto.GroupBuySessionRequest, sellerID int64, scheduleID *string) (*dto.GroupBuySessionResponse, error) {
+	startsAt := request.StartsAt
+	upcoming := startsAt.After(time.Now())
+	if !upcoming {
+		startsAt = time.Now()
+	}
+	if !request.ExpiresAt.After(startsAt) {
+		return &dto.GroupBuySessionResponse{}, errorx.ErrInvalidCampaignWindow
+	}
+
 	var groupBuySession *entity.GroupBuySession
 	var tiers []entity.GroupBuyTier
 	err := g.tx.WithTransaction(ctx, func(txCtx context.Context) error {
-		productVariant, err := g.productVariantRepo.GetProductVariant(ctx, request.ProductVariantID)
-		if err != nil {
+		if _, err := g.productVariantRepo.GetProductVariant(txCtx, request.ProductVariantID); err != nil {
 			g.log.Errorf("failed to get product variant: %v", err)
 			return err
 		}
 
-		if productVariant.Stock.CurrentStock < request.MaxQuantity {
-			g.log.Errorf("product variant stock is not enough")
-			return errorx.ErrInsufficientStock
+		status := "upcoming"
+		if !upcoming {
+			status = "active"
+			// the campaign holds its units as a reservation, paid orders take them off current_stock
+			reserved, err := g.stockRepo.ReserveStock(txCtx, request.ProductVariantID, request.MaxQuantity)
+			if err != nil {
+				g.log.Errorf("failed to reserve stock: %v", err)
+				return err
+			}
+			if !reserved {
+				g.log.Errorf("product variant stock is not enough")
+				return errorx.ErrInsufficientStock
+			}
 		}
 
-		productVariant.Stock.CurrentStock -= request.MaxQuantity
-		productVariant.Stock.ReservedStock += request.MaxQuantity
-
-		if err := g.productVariantRepo.UpdateProductVariant(ctx, productVariant, request.ProductVariantID); err != nil {
-			g.log.Errorf("failed to update product variant: %v", err)
-			return err
+		maxQuantityPerMember := request.MaxQuantityPerMember
+		if maxQuantityPerMember < 1 {
+			maxQuantityPerMember = 1
 		}
 
 		groupBuySession = &entity.GroupBuySession{
-			ProductVariantID: request.ProductVariantID,
-			SellerID:         sellerID,
-			MinParticipants:  request.MinParticipants,
-			MaxParticipants:  request.MaxParticipants,
-			MaxQuantity:      int64(request.MaxQuantity),
-			ExpiresAt:        request.ExpiresAt,
+			ProductVariantID:     request.ProductVariantID,
+			SellerID:             sellerID,
+			MinParticipants:      request.MinParticipants,
+			MaxParticipants:      request.MaxParticipants,
+			MaxQuantity:          int64(request.MaxQuantity),
+			MaxQuantityPerMember: maxQuantityPerMember,
+			Status:               status,
+			StartsAt:             startsAt,
+			ExpiresAt:            request.ExpiresAt,
+			ScheduleID:           scheduleID,
 		}
 
 		if err := g.groupBuySessionRepo.Create(txCtx, groupBuySession); err != nil {
@@ -116,6 +214,10 @@ func (g *GroupBuyUsecase) CreateGroupBuySession(ctx context.Context, request *dt
 		return &dto.GroupBuySessionResponse{}, err
 	}
 
+	if upcoming {
+		g.scheduleActivation(groupBuySession)
+	}
+
 	task, err := tasks.NewGroupBuySessionEndTask(tasks.GroupBuySessionEndPayload{
 		SessionID:        groupBuySession.ID,
 		ProductVariantID: groupBuySession.ProductVariantID,
@@ -139,6 +241,9 @@ func (g *GroupBuyUsecase) CreateGroupBuySession(ctx context.Context, request *dt
 		SellerID:         groupBuySession.SellerID,
 		MinParticipants:  groupBuySession.MinParticipants,
 		MaxParticipants:  groupBuySession.MaxParticipants,
+		Status:           groupBuySession.Status,
+		MaxQuantity:      groupBuySession.MaxQuantity,
+		StartsAt:         groupBuySession.StartsAt,
 		ExpiresAt:        groupBuySession.ExpiresAt,
 		Tiers:            tiers,
 	}, nil
@@ -159,27 +264,241 @@ func (g *GroupBuyUsecase) GetAllGroupBuySessionForSeller(ctx context.Context, se
 		return nil, err
 	}
 
-	// it seems stupid but its works, later i will fix it
+	stats, err := g.groupBuySessionRepo.GetStatsForSeller(ctx, sellerID, groupBuySoldStatuses, groupBuyPaidStatuses)
+	if err != nil {
+		g.log.Errorf("failed to get group buy stats for seller: %v", err)
+		return nil, err
+	}
+
 	for i := range groupBuySessions {
-		buyerGroupBuySession, err := g.buyerGroupSessionRepo.GetSessionByID(ctx, groupBuySessions[i].ID)
-		if err == nil {
-			g.log.Infof("Buyer group buy session %v found", buyerGroupBuySession)
-			groupBuySessions[i].CurrentParticipants = int64(len(buyerGroupBuySession.Members))
+		groupBuySessions[i].CurrentParticipants = stats[groupBuySessions[i].ID].Members
+	}
+
+	return groupBuySessions, nil
+}
+
+func (g *GroupBuyUsecase) GetCampaignAnalytics(ctx context.Context, sellerID int64) ([]dto.GroupBuyCampaignAnalytics, error) {
+	campaigns, err := g.groupBuySessionRepo.GetAllForSeller(ctx, sellerID)
+	if err != nil {
+		g.log.Errorf("failed to get group buy sessions for seller: %v", err)
+		return nil, err
+	}
+
+	stats, err := g.groupBuySessionRepo.GetStatsForSeller(ctx, sellerID, groupBuySoldStatuses, groupBuyPaidStatuses)
+	if err != nil {
+		g.log.Errorf("failed to get group buy stats for seller: %v", err)
+		return nil, err
+	}
+
+	analytics := make([]dto.GroupBuyCampaignAnalytics, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		analytics = append(analytics, toCampaignAnalytics(campaign, stats[campaign.ID]))
+	}
+	return analytics, nil
+}
+
+func toCampaignAnalytics(campaign entity.GroupBuySession, stats entity.GroupBuyCampaignStats) dto.GroupBuyCampaignAnalytics {
+	item := dto.GroupBuyCampaignAnalytics{
+		GroupBuySessionID: campaign.ID,
+		Status:            campaign.Status,
+		StartsAt:          campaign.StartsAt,
+		ExpiresAt:         campaign.ExpiresAt,
+		BuyerSessions:     stats.BuyerSessions,
+		Members:           stats.Members,
+		PaidMembers:       stats.PaidMembers,
+		UnitsSold:         stats.UnitsSold,
+		MaxQuantity:       campaign.MaxQuantity,
+		Revenue:           stats.Revenue,
+		Funnel: dto.GroupBuyFunnel{
+			Joined:      stats.Members,
+			Ordered:     stats.OrderedMembers,
+			Paid:        stats.PaidMembers,
+			OrderRate:   funnelRate(stats.OrderedMembers, stats.Members),
+			PaymentRate: funnelRate(stats.PaidMembers, stats.OrderedMembers),
+		},
+	}
+	if campaign.ProductVariant != nil {
+		item.VariantName = campaign.ProductVariant.Name
+		if campaign.ProductVariant.Product != nil {
+			item.ProductTitle = campaign.ProductVariant.Product.Title
 		}
+	}
+	if tier := entity.ApplicableTier(campaign.GroupBuyTiers, stats.TopParticipants); tier != nil {
+		item.TierThreshold = tier.ParticipantThreshold
+		item.TierDiscount = tier.DiscountPercentage
+	}
+	return item
+}
 
+func funnelRate(part, total int64) float64 {
+	if total == 0 {
+		return 0
 	}
+	return float64(part) / float64(total)
+}
 
-	return groupBuySessions, nil
+func (g *GroupBuyUsecase) GetAllGroupBuySessionForBuyer(ctx context.Context, filter dto.GroupBuyFeedFilter) (*dto.GroupBuyFeedResponse, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 50 {
+		filter.Limit = 10
+	}
+
+	status := "active"
+	if filter.Upcoming {
+		status = "upcoming"
+	}
+
+	sessions, total, err := g.groupBuySessionRepo.GetAllForBuyer(ctx, status, filter.CategoryID, filter.Sort, filter.Limit, (filter.Page-1)*filter.Limit)
+	if err != nil {
+		g.log.Errorf("failed to get group buy sessions for buyer: %v", err)
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+
+	buyerSessions, err := g.buyerGroupSessionRepo.GetActiveSessionsByGroupBuySessionIDs(ctx, ids)
+	if err != nil {
+		g.log.Errorf("failed to get buyer sessions for feed: %v", err)
+		return nil, err
+	}
+	sold, err := g.orderRepo.SumGroupBuyQuantities(ctx, ids, groupBuySoldStatuses)
+	if err != nil {
+		g.log.Errorf("failed to get sold quantities for feed: %v", err)
+		return nil, err
+	}
+
+	byCampaign := make(map[string][]entity.BuyerGroupSession, len(sessions))
+	for _, buyerSession := range buyerSessions {
+		byCampaign[buyerSession.GroupBuySessionID] = append(byCampaign[buyerSession.GroupBuySessionID], buyerSession)
+	}
+
+	now := time.Now()
+	campaigns := make([]dto.GroupBuyFeedItem, 0, len(sessions))
+	for _, session := range sessions {
+		campaigns = append(campaigns, toGroupBuyFeedItem(session, byCampaign[session.ID], sold[session.ID], now))
+	}
+
+	return &dto.GroupBuyFeedResponse{
+		Campaigns:  campaigns,
+		TotalCount: total,
+		Page:       filter.Page,
+		Limit:      filter.Limit,
+	}, nil
 }
 
-func (g *GroupBuyUsecase) GetAllGroupBuySessionForBuyer(ctx context.Context) ([]entity.GroupBuySession, error) {
-	return g.groupBuySessionRepo.GetAllForBuyer(ctx)
+// toGroupBuyFeedItem builds the feed entry of a campaign from its buyer sessions, which
+// come ordered by participants so the first open one is the largest
+func toGroupBuyFeedItem(session entity.GroupBuySession, buyerSessions []entity.BuyerGroupSession, sold int, now time.Time) dto.GroupBuyFeedItem {
+	item := dto.GroupBuyFeedItem{
+		ID:                session.ID,
+		ProductVariantID:  session.ProductVariantID,
+		Status:            session.Status,
+		Tiers:             session.GroupBuyTiers,
+		MinParticipants:   session.MinParticipants,
+		MaxParticipants:   session.MaxParticipants,
+		RemainingQuantity: max(session.MaxQuantity-int64(sold), 0),
+		StartsAt:          session.StartsAt,
+		ExpiresAt:         session.ExpiresAt,
+		TimeLeftSeconds:   max(int64(session.ExpiresAt.Sub(now).Seconds()), 0),
+		OpenSessions:      []dto.GroupBuyFeedSession{},
+	}
+
+	if variant := session.ProductVariant; variant != nil {
+		item.VariantName = variant.Name
+		item.Price = variant.Price
+		if product := variant.Product; product != nil {
+			item.ProductID = product.ID
+			item.ProductTitle = product.Title
+			item.ProductSlug = product.Slug
+			item.CategoryID = product.CategoryID
+			if len(product.ProductImages) > 0 {
+				item.ImageURL = product.ProductImages[0].ImageURL
+			}
+		}
+	}
+
+	for _, tier := range session.GroupBuyTiers {
+		item.MaxDiscount = max(item.MaxDiscount, tier.DiscountPercentage)
+	}
+
+	for _, buyerSession := range buyerSessions {
+		item.CurrentParticipants += buyerSession.CurrentParticipants
+
+		if buyerSession.Status != "open" || !buyerSession.ExpiresAt.After(now) || buyerSession.CurrentParticipants >= session.MaxParticipants {
+			continue
+		}
+
+		open := dto.GroupBuyFeedSession{
+			SessionCode:         buyerSession.SessionCode,
+			Title:               buyerSession.Title,
+			CurrentParticipants: buyerSession.CurrentParticipants,
+			SpotsLeft:           session.MaxParticipants - buyerSession.CurrentParticipants,
+			ExpiresAt:           buyerSession.ExpiresAt,
+		}
+		if tier := entity.ApplicableTier(session.GroupBuyTiers, buyerSession.CurrentParticipants); tier != nil {
+			open.CurrentDiscount = tier.DiscountPercentage
+		}
+		if tier := entity.NextTier(session.GroupBuyTiers, buyerSession.CurrentParticipants); tier != nil {
+			open.NextTierThreshold = tier.ParticipantThreshold
+		}
+		item.OpenSessions = append(item.OpenSessions, open)
+	}
+
+	// a new group starts with its organizer, so without an open group the next tier counts from one
+	leading := 1
+	if len(item.OpenSessions) > 0 {
+		leading = item.OpenSessions[0].CurrentParticipants
+	}
+	if tier := entity.NextTier(session.GroupBuyTiers, leading); tier != nil {
+		item.NextTierThreshold = tier.ParticipantThreshold
+	}
+
+	return item
 }
 
-func (g *GroupBuyUsecase) ChangeGroupBuySessionStatus(ctx context.Context, sessionID string, status string, sellerID int64) error {
-	return g.groupBuySessionRepo.ChangeStatus(ctx, sessionID, status, sellerID)
+func (g *GroupBuyUsecase) ChangeGroupBuySessionStatus(ctx context.Context, sessionID string, status string, reason string, sellerID int64) error {
+	switch status {
+	case "cancelled":
+		return g.CancelSession(ctx, sessionID, sellerID, reason)
+	case "completed":
+		campaign, err := g.sellerCampaign(ctx, sessionID, sellerID)
+		if err != nil {
+			return err
+		}
+		if campaign.Status != "active" {
+			return errorx.ErrCampaignNotCancellable
+		}
+		return g.EndSession(ctx, sessionID, campaign.ProductVariantID, sellerID)
+	default:
+		return errorx.ErrInvalidCampaignStatus
+	}
 }
 
+func (g *GroupBuyUsecase) sellerCampaign(ctx context.Context, sessionID string, sellerID int64) (*entity.GroupBuySession, error) {
+	campaign, err := g.groupBuySessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.ErrGroupBuySessionNotFound
+		}
+		return nil, err
+	}
+	if campaign.SellerID != sellerID {
+		return nil, errorx.ErrNotCampaignOwner
+	}
+	return campaign, nil
+}
+
+// EndSession closes a campaign. Every buyer session that reached MinParticipants has its
+// paid orders confirmed with the final tier, the others have their paid orders refunded.
+// Unpaid orders are cancelled either way. A buyer session is only marked completed or
+// cancelled after its orders are done and the campaign only leaves active together with
+// the stock return, so a retried task picks up where the last attempt stopped. Buyer
+// sessions started while the campaign was ending are closed after it is completed.
 func (g *GroupBuyUsecase) EndSession(ctx context.Context, sessionID string, productVariantID string, sellerID int64) error {
 	session, err := g.groupBuySessionRepo.FindByID(ctx, sessionID)
 	if err != nil {
@@ -187,7 +506,7 @@ func (g *GroupBuyUsecase) EndSession(ctx context.Context, sessionID string, prod
 		return err
 	}
 
-	if session.Status != "active" {
+	if session.Status != "active" && session.Status != "completed" {
 		g.log.Infof("Session %s is already %s, skipping", sessionID, session.Status)
 		return nil
 	}
@@ -198,18 +517,286 @@ func (g *GroupBuyUsecase) EndSession(ctx context.Context, sessionID string, prod
 		return err
 	}
 
-	err = g.groupBuySessionRepo.ChangeStatus(ctx, sessionID, "completed", sellerID)
+	if session.Status == "completed" {
+		// an earlier attempt completed the campaign but failed on a late buyer session
+		return g.closeBuyerSessions(ctx, session, productVariant.Name)
+	}
+
+	if err := g.closeBuyerSessions(ctx, session, productVariant.Name); err != nil {
+		return err
+	}
+
+	err = g.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		locked, err := g.groupBuySessionRepo.FindByIDForUpdate(txCtx, sessionID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != "active" {
+			return nil
+		}
+
+		if err := g.returnReservedStock(txCtx, locked); err != nil {
+			return err
+		}
+		return g.groupBuySessionRepo.ChangeStatus(txCtx, sessionID, "completed", sellerID)
+	})
+	if err != nil {
+		g.log.Errorf("failed to complete session: %v", err)
+		return err
+	}
+
+	// a buyer session started while the others were closed is decided the same way,
+	// claimUnits refuses new ones from here on
+	if err := g.closeBuyerSessions(ctx, session, productVariant.Name); err != nil {
+		return err
+	}
+
+	g.events.Publish(ctx, sessionID, "", GroupBuyEventCampaignEnded, nil)
+
+	g.log.Infof("Group buy session %s completed. Product: %s", sessionID, productVariant.ID)
+	return nil
+}
+
+// returnReservedStock gives back the units of an active campaign that no payment kept.
+// It runs inside the transaction that moves the campaign out of active.
+func (g *GroupBuyUsecase) returnReservedStock(ctx context.Context, campaign *entity.GroupBuySession) error {
+	// every unit a payment took off the reservation, refunded or not
+	paid, err := g.orderRepo.SumGroupBuyQuantity(ctx, campaign.ID, append(groupBuySoldStatuses, entity.OrderStatusRefunded))
+	if err != nil {
+		return err
+	}
+	refunded, err := g.orderRepo.SumGroupBuyQuantity(ctx, campaign.ID, []string{entity.OrderStatusRefunded})
+	if err != nil {
+		return err
+	}
+
+	unsold := max(int(campaign.MaxQuantity)-paid, 0)
+	if err := g.stockRepo.ReturnStock(ctx, campaign.ProductVariantID, unsold, refunded); err != nil {
+		return err
+	}
+
+	g.log.Infof("Group buy session %s returned %d unsold and %d refunded units of %s", campaign.ID, unsold, refunded, campaign.ProductVariantID)
+	return nil
+}
+
+// closeBuyerSessions decides every buyer session of an ending campaign that is not
+// decided yet
+func (g *GroupBuyUsecase) closeBuyerSessions(ctx context.Context, session *entity.GroupBuySession, productName string) error {
+	buyerSessions, err := g.buyerGroupSessionRepo.GetSessionsByGroupBuySessionID(ctx, session.ID)
+	if err != nil {
+		g.log.Errorf("failed to get buyer sessions: %v", err)
+		return err
+	}
+
+	for _, buyerSession := range buyerSessions {
+		if err := g.closeBuyerSession(ctx, session, &buyerSession, productName); err != nil {
+			g.log.Errorf("failed to close buyer session %s: %v", buyerSession.ID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// closeBuyerSession decides one buyer session of an ending campaign. The session is locked
+// first so nobody joins while its orders are settled.
+func (g *GroupBuyUsecase) closeBuyerSession(ctx context.Context, session *entity.GroupBuySession, buyerSession *entity.BuyerGroupSession, productName string) error {
+	if buyerSession.Status == "completed" || buyerSession.Status == "cancelled" {
+		return nil
+	}
+
+	locked, err := g.buyerGroupSessionRepo.ChangeBuyerSessionStatusFrom(ctx, buyerSession.ID, []string{"open", "expired"}, "locked")
+	if err != nil {
+		return err
+	}
+	if locked {
+		g.events.Publish(ctx, session.ID, buyerSession.ID, GroupBuyEventSessionLocked, nil)
+	}
+
+	succeeded := buyerSession.CurrentParticipants >= session.MinParticipants
+	status := "completed"
+	templateID := mailer.TemplateGroupBuySucceeded
+	if succeeded {
+		if err := g.orders.ConfirmGroupBuyOrders(ctx, buyerSession.ID); err != nil {
+			return err
+		}
+	} else {
+		if err := g.orders.RefundGroupBuyOrders(ctx, buyerSession.ID); err != nil {
+			return err
+		}
+		status = "cancelled"
+		templateID = mailer.TemplateGroupBuyFailed
+	}
+
+	// the payment window of a session that filled up may end while it is settled
+	closed, err := g.buyerGroupSessionRepo.ChangeBuyerSessionStatusFrom(ctx, buyerSession.ID, []string{"locked", "expired"}, status)
 	if err != nil {
-		g.log.Errorf("failed to change session status: %v", err)
 		return err
 	}
+	if !closed {
+		return nil
+	}
+
+	g.events.Publish(ctx, session.ID, buyerSession.ID, GroupBuyEventSessionClosed, map[string]any{
+		"status":       status,
+		"participants": buyerSession.CurrentParticipants,
+	})
+
+	userIDs := make([]int64, 0, len(buyerSession.Members))
+	for _, member := range buyerSession.Members {
+		userIDs = append(userIDs, member.UserID)
+	}
 
-	g.log.Infof("Group buy session %s completed successfully. Product: %s", sessionID, productVariant.ID)
+	g.notifier.NotifyUsers(ctx, userIDs, templateID, map[string]any{
+		"SessionCode":     buyerSession.SessionCode,
+		"ProductName":     productName,
+		"Participants":    buyerSession.CurrentParticipants,
+		"MinParticipants": session.MinParticipants,
+	})
 
 	return nil
 }
 
+// CancelSession calls off a campaign before its end. Every buyer session that is not
+// decided yet has its unpaid orders cancelled and its paid orders refunded, then the
+// reserved stock goes back together with the status change. A cancellation that
+// failed halfway is finished by calling it again.
+func (g *GroupBuyUsecase) CancelSession(ctx context.Context, sessionID string, sellerID int64, reason string) error {
+	campaign, err := g.sellerCampaign(ctx, sessionID, sellerID)
+	if err != nil {
+		return err
+	}
+	if campaign.Status != "active" && campaign.Status != "upcoming" {
+		return errorx.ErrCampaignNotCancellable
+	}
+
+	userIDs, err := g.cancelBuyerSessions(ctx, campaign, reason)
+	if err != nil {
+		g.log.Errorf("failed to cancel buyer sessions: %v", err)
+		return err
+	}
+
+	err = g.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		locked, err := g.groupBuySessionRepo.FindByIDForUpdate(txCtx, sessionID)
+		if err != nil {
+			return err
+		}
+
+		switch locked.Status {
+		case "upcoming":
+			// nothing is reserved before a campaign starts
+		case "active":
+			if err := g.returnReservedStock(txCtx, locked); err != nil {
+				return err
+			}
+		default:
+			return errorx.ErrCampaignNotCancellable
+		}
+
+		return g.groupBuySessionRepo.ChangeStatus(txCtx, sessionID, "cancelled", sellerID)
+	})
+	if err != nil {
+		g.log.Errorf("failed to cancel session: %v", err)
+		return err
+	}
+
+	// a buyer session started while the others were refunded is no longer joinable now
+	late, err := g.cancelBuyerSessions(ctx, campaign, reason)
+	if err != nil {
+		g.log.Errorf("failed to cancel buyer sessions: %v", err)
+		return err
+	}
+	userIDs = append(userIDs, late...)
+
+	if campaign.Status == "upcoming" {
+		subscribers, err := g.reminderRepo.GetUserIDsByGroupBuySessionID(ctx, sessionID)
+		if err != nil {
+			g.log.Errorf("failed to get reminders: %v", err)
+		}
+		userIDs = append(userIDs, subscribers...)
+	}
+
+	g.events.Publish(ctx, sessionID, "", GroupBuyEventCampaignCancelled, map[string]any{
+		"reason": reason,
+	})
+
+	productName := ""
+	if campaign.ProductVariant != nil {
+		productName = campaign.ProductVariant.Name
+	}
+	g.notifyCampaignCancelled(ctx, sessionID, productName, userIDs, reason)
+
+	g.log.Infof("Group buy session %s cancelled by seller %d: %s", sessionID, sellerID, reason)
+	return nil
+}
+
+// notifyCampaignCancelled tells members and reminder subscribers that a campaign was
+// called off
+func (g *GroupBuyUsecase) notifyCampaignCancelled(ctx context.Context, sessionID string, productName string, userIDs []int64, reason string) {
+	g.notifier.NotifyInbox(ctx, userIDs, entity.Notification{
+		Type:      entity.NotificationGroupBuyCancelled,
+		Title:     fmt.Sprintf("The group buy for %s was cancelled", productName),
+		Body:      reason,
+		DedupeKey: fmt.Sprintf("group_buy:%s:cancelled", sessionID),
+	}, mailer.TemplateGroupBuyCancelled, map[string]any{
+		"GroupBuySessionID": sessionID,
+		"ProductName":       productName,
+		"Reason":            reason,
+	})
+}
+
+// cancelBuyerSessions cancels the buyer sessions of a campaign that are not decided yet
+// and returns their members
+func (g *GroupBuyUsecase) cancelBuyerSessions(ctx context.Context, campaign *entity.GroupBuySession, reason string) ([]int64, error) {
+	buyerSessions, err := g.buyerGroupSessionRepo.GetSessionsByGroupBuySessionID(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []int64
+	for _, buyerSession := range buyerSessions {
+		if buyerSession.Status == "completed" || buyerSession.Status == "cancelled" {
+			continue
+		}
+
+		locked, err := g.buyerGroupSessionRepo.ChangeBuyerSessionStatusFrom(ctx, buyerSession.ID, []string{"open", "expired"}, "locked")
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			g.events.Publish(ctx, campaign.ID, buyerSession.ID, GroupBuyEventSessionLocked, nil)
+		}
+
+		if err := g.orders.RefundGroupBuyOrders(ctx, buyerSession.ID); err != nil {
+			return nil, err
+		}
+
+		cancelled, err := g.buyerGroupSessionRepo.ChangeBuyerSessionStatusFrom(ctx, buyerSession.ID, []string{"locked", "expired"}, "cancelled")
+		if err != nil {
+			return nil, err
+		}
+		if !cancelled {
+			continue
+		}
+
+		g.events.Publish(ctx, campaign.ID, buyerSession.ID, GroupBuyEventSessionClosed, map[string]any{
+			"status":       "cancelled",
+			"participants": buyerSession.CurrentParticipants,
+			"reason":       reason,
+		})
+		for _, member := range buyerSession.Members {
+			userIDs = append(userIDs, member.UserID)
+		}
+	}
+
+	return userIDs, nil
+}
+
 func (g *GroupBuyUsecase) CreateBuyerSession(ctx context.Context, request *dto.CreateBuyerGroupSessionRequest) (string, error) {
+	quantity := request.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+
 	var sessionCode string
 	err := g.tx.WithTransaction(ctx, func(ctx context.Context) error {
 		session, err := g.buyerGroupSessionRepo.GetSessionByOrganizerUserID(ctx, request.OrganizerUserID)
@@ -230,6 +817,10 @@ func (g *GroupBuyUsecase) CreateBuyerSession(ctx context.Context, request *dto.C
 			return errorx.ErrGroupBuySessionNotFound
 		}
 
+		if err := g.claimUnits(ctx, productSession.ID, quantity, ""); err != nil {
+			return err
+		}
+
 		buyerGroupSession := &entity.BuyerGroupSession{
 			GroupBuySessionID:   productSession.ID,
 			ProductVariantID:    request.ProductVariantID,
@@ -250,7 +841,7 @@ func (g *GroupBuyUsecase) CreateBuyerSession(ctx context.Context, request *dto.C
 		if err := g.buyerGroupMemberRepo.Create(ctx, &entity.BuyerGroupMember{
 			SessionID: buyerGroupSession.ID,
 			UserID:    request.OrganizerUserID,
-			Quantity:  1,
+			Quantity:  quantity,
 			Status:    "joined",
 		}); err != nil {
 			g.log.Errorf("[GroupBuyUsecase] Failed to create member: %v", err)
@@ -279,6 +870,9 @@ func (g *GroupBuyUsecase) CreateBuyerSession(ctx context.Context, request *dto.C
 		g.log.Errorf("failed to create session: %v", err)
 		return "", err
 	}
+
+	g.notifyJoined(ctx, request.OrganizerUserID, sessionCode)
+
 	return sessionCode, nil
 }
 
@@ -301,7 +895,7 @@ func (g *GroupBuyUsecase) GetSessionForBuyerByCode(ctx context.Context, sessionC
 		return nil, err
 	}
 
-	productSession, err := g.groupBuySessionRepo.FindByProductVariantID(ctx, buyerSession.ProductVariantID)
+	productSession, err := g.groupBuySessionRepo.FindByID(ctx, buyerSession.GroupBuySessionID)
 	if err != nil {
 		g.log.Errorf("failed to get product session: %v", err)
 		return nil, err
@@ -315,59 +909,115 @@ func (g *GroupBuyUsecase) GetSessionForBuyerByCode(ctx context.Context, sessionC
 	}, nil
 }
 
-func (g *GroupBuyUsecase) JoinSession(ctx context.Context, sessionCode string, userID int64) error {
-	err := g.tx.WithTransaction(ctx, func(ctx context.Context) error {
-		buyer_session, err := g.buyerGroupSessionRepo.GetSessionByCode(ctx, sessionCode)
+func (g *GroupBuyUsecase) JoinSession(ctx context.Context, sessionCode string, userID int64, quantity int, inviteToken string) error {
+	if quantity < 1 {
+		quantity = 1
+	}
 
+	var referredBy *int64
+	if inviteToken != "" {
+		referrerUserID, err := g.invites.Verify(sessionCode, inviteToken, time.Now())
 		if err != nil {
-			g.log.Errorf("failed to get session: %v", err)
-			return err
+			g.log.Infof("Rejected invite token for session %s: %v", sessionCode, err)
+			return errorx.ErrInvalidInvite
 		}
-
-		if buyer_session.Status != "open" {
-			g.log.Infof("Session %s is already %s, skipping", sessionCode, buyer_session.Status)
-			return nil
+		if referrerUserID != userID {
+			referredBy = &referrerUserID
 		}
+	}
 
-		// check whether user is already in session
-		for _, member := range buyer_session.Members {
-			if member.UserID == userID {
-				g.log.Infof("User %d is already in session %s, skipping", userID, sessionCode)
-				return nil
-			}
+	current, err := g.buyerGroupSessionRepo.GetSessionByCode(ctx, sessionCode)
+	if err != nil {
+		g.log.Errorf("failed to get session: %v", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errorx.ErrSessionNotFound
 		}
+		return err
+	}
 
-		product_session, err := g.groupBuySessionRepo.FindByID(ctx, buyer_session.GroupBuySessionID)
+	joined := false
+	full := false
+	var campaign *entity.GroupBuySession
+	var buyerSession *entity.BuyerGroupSession
+	err = g.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		// the campaign is locked before the buyer session, concurrent joins wait here
+		// and see the member count and the claimed units of the join before them
+		product_session, err := g.groupBuySessionRepo.FindByIDForUpdate(ctx, current.GroupBuySessionID)
 		if err != nil {
 			g.log.Errorf("failed to get product session: %v", err)
 			return err
 		}
 
+		buyer_session, err := g.buyerGroupSessionRepo.GetSessionByIDForUpdate(ctx, current.ID)
+		if err != nil {
+			g.log.Errorf("failed to get session: %v", err)
+			return err
+		}
+
+		if buyer_session.HasMember(userID) {
+			g.log.Infof("User %d is already in session %s, skipping", userID, sessionCode)
+			return nil
+		}
+
+		if buyer_session.Status == "locked" && buyer_session.IsFull(product_session.MaxParticipants) {
+			g.log.Infof("Session %s is already full", sessionCode)
+			return errorx.ErrSessionFull
+		}
+		if buyer_session.Status != "open" {
+			g.log.Infof("Session %s is already %s, skipping", sessionCode, buyer_session.Status)
+			return nil
+		}
+
 		if product_session.Status != "active" {
 			g.log.Infof("Session %s is already %s, skipping", sessionCode, product_session.Status)
 			return nil
 		}
 
-		if buyer_session.CurrentParticipants > product_session.MaxParticipants {
+		if buyer_session.IsFull(product_session.MaxParticipants) {
 			g.log.Infof("Session %s is already full", sessionCode)
 			return errorx.ErrSessionFull
 		}
 
+		if err := g.claimUnits(ctx, product_session.ID, quantity, ""); err != nil {
+			return err
+		}
+
 		if err := g.buyerGroupSessionRepo.AddMember(ctx, buyer_session); err != nil {
 			g.log.Errorf("failed to add member: %v", err)
 			return err
 		}
 
 		if err := g.buyerGroupMemberRepo.Create(ctx, &entity.BuyerGroupMember{
-			SessionID: buyer_session.ID,
-			UserID:    userID,
-			Quantity:  1,
-			Status:    "joined",
+			SessionID:        buyer_session.ID,
+			UserID:           userID,
+			Quantity:         quantity,
+			Status:           "joined",
+			ReferredByUserID: referredBy,
 		}); err != nil {
 			g.log.Errorf("failed to join session: %v", err)
 			return err
 		}
 
+		buyer_session.CurrentParticipants++
+
+		// a full session takes no more joins, its members go on to pay
+		if buyer_session.IsFull(product_session.MaxParticipants) {
+			if err := g.buyerGroupSessionRepo.ChangeBuyerSessionStatus(ctx, buyer_session.ID, "locked"); err != nil {
+				g.log.Errorf("failed to lock full session: %v", err)
+				return err
+			}
+			full = true
+		}
+
+		product_session.GroupBuyTiers, err = g.groupBuyTierRepo.FindBySessionID(ctx, product_session.ID)
+		if err != nil {
+			g.log.Errorf("failed to get tiers: %v", err)
+			return err
+		}
+
+		joined = true
+		campaign = product_session
+		buyerSession = buyer_session
 		return nil
 	})
 	if err != nil {
@@ -375,13 +1025,514 @@ func (g *GroupBuyUsecase) JoinSession(ctx context.Context, sessionCode string, u
 		return err
 	}
 
+	if joined {
+		g.notifyJoined(ctx, userID, sessionCode)
+
+		participants := buyerSession.CurrentParticipants
+		g.events.Publish(ctx, campaign.ID, buyerSession.ID, GroupBuyEventMemberJoined, map[string]any{
+			"user_id":             userID,
+			"quantity":            quantity,
+			"participants":        participants,
+			"referred_by_user_id": referredBy,
+		})
+		if tier := entity.ApplicableTier(campaign.GroupBuyTiers, participants); tier != nil && tier.ParticipantThreshold == participants {
+			g.events.Publish(ctx, campaign.ID, buyerSession.ID, GroupBuyEventTierReached, map[string]any{
+				"participants":        participants,
+				"threshold":           tier.ParticipantThreshold,
+				"discount_percentage": tier.DiscountPercentage,
+			})
+		}
+		if full {
+			g.log.Infof("Session %s is full with %d participants and is now locked", sessionCode, participants)
+			g.events.Publish(ctx, campaign.ID, buyerSession.ID, GroupBuyEventSessionLocked, map[string]any{
+				"participants": participants,
+			})
+		}
+		g.enqueueMilestones(campaign.GroupBuyTiers, buyerSession.ID, participants)
+	}
+
+	return nil
+}
+
+// enqueueMilestones queues the alerts for a join that reached a tier or left the
+// session one member short of the next one
+func (g *GroupBuyUsecase) enqueueMilestones(tiers []entity.GroupBuyTier, buyerSessionID string, participants int) {
+	var milestones []tasks.GroupBuyMilestonePayload
+	if tier := entity.ApplicableTier(tiers, participants); tier != nil && tier.ParticipantThreshold == participants {
+		milestones = append(milestones, tasks.GroupBuyMilestonePayload{
+			BuyerSessionID: buyerSessionID,
+			Milestone:      entity.NotificationGroupBuyTierReached,
+			Threshold:      tier.ParticipantThreshold,
+		})
+	}
+	if next := entity.NextTier(tiers, participants); next != nil && next.ParticipantThreshold == participants+1 {
+		milestones = append(milestones, tasks.GroupBuyMilestonePayload{
+			BuyerSessionID: buyerSessionID,
+			Milestone:      entity.NotificationGroupBuyTierNear,
+			Threshold:      next.ParticipantThreshold,
+		})
+	}
+
+	for _, milestone := range milestones {
+		task, err := tasks.NewGroupBuyMilestoneTask(milestone)
+		if err != nil {
+			g.log.Errorf("failed to create milestone task: %v", err)
+			continue
+		}
+		if _, err := g.asynqClient.Enqueue(task, asynq.Queue("default")); err != nil {
+			g.log.Errorf("failed to enqueue milestone task: %v", err)
+		}
+	}
+}
+
+func (g *GroupBuyUsecase) NotifyMilestone(ctx context.Context, buyerSessionID string, milestone string, threshold int) error {
+	buyerSession, err := g.buyerGroupSessionRepo.GetSessionByID(ctx, buyerSessionID)
+	if err != nil {
+		g.log.Errorf("failed to get session: %v", err)
+		return err
+	}
+
+	// members who left since the join can make the alert stale, a session locked
+	// because it filled up keeps its members
+	stale := buyerSession.Status != "open" && buyerSession.Status != "locked"
+	switch milestone {
+	case entity.NotificationGroupBuyTierReached:
+		stale = stale || buyerSession.CurrentParticipants < threshold
+	case entity.NotificationGroupBuyTierNear:
+		stale = stale || buyerSession.CurrentParticipants != threshold-1
+	default:
+		return fmt.Errorf("unknown group buy milestone: %s", milestone)
+	}
+	if stale {
+		g.log.Infof("Milestone %s at %d no longer applies to session %s, skipping", milestone, threshold, buyerSession.SessionCode)
+		return nil
+	}
+
+	campaign, err := g.groupBuySessionRepo.FindByID(ctx, buyerSession.GroupBuySessionID)
+	if err != nil {
+		g.log.Errorf("failed to get product session: %v", err)
+		return err
+	}
+	var tier *entity.GroupBuyTier
+	for i := range campaign.GroupBuyTiers {
+		if campaign.GroupBuyTiers[i].ParticipantThreshold == threshold {
+			tier = &campaign.GroupBuyTiers[i]
+		}
+	}
+	if tier == nil {
+		g.log.Infof("Session %s has no tier at %d, skipping", buyerSession.SessionCode, threshold)
+		return nil
+	}
+
+	productName := ""
+	if productVariant, err := g.productVariantRepo.GetProductVariant(ctx, buyerSession.ProductVariantID); err == nil {
+		productName = productVariant.Name
+	}
+
+	var userIDs []int64
+	for _, member := range buyerSession.Members {
+		if member.Status != "cancelled" {
+			userIDs = append(userIDs, member.UserID)
+		}
+	}
+
+	notification := entity.Notification{
+		Type:      milestone,
+		DedupeKey: fmt.Sprintf("group_buy:%s:%s:%d", buyerSession.ID, milestone, threshold),
+	}
+	templateID := mailer.TemplateGroupBuyTierReached
+	if milestone == entity.NotificationGroupBuyTierReached {
+		notification.Title = fmt.Sprintf("%.0f%% off unlocked in %s", tier.DiscountPercentage, buyerSession.SessionCode)
+		notification.Body = fmt.Sprintf("%d people joined, every member now gets %.0f%% off.", buyerSession.CurrentParticipants, tier.DiscountPercentage)
+	} else {
+		templateID = mailer.TemplateGroupBuyTierNear
+		notification.Title = fmt.Sprintf("One more person for %.0f%% off", tier.DiscountPercentage)
+		notification.Body = fmt.Sprintf("%s needs one more member to unlock %.0f%% off for everyone.", buyerSession.SessionCode, tier.DiscountPercentage)
+	}
+
+	g.notifier.NotifyInbox(ctx, userIDs, notification, templateID, map[string]any{
+		"SessionCode":        buyerSession.SessionCode,
+		"ProductName":        productName,
+		"Participants":       buyerSession.CurrentParticipants,
+		"Threshold":          threshold,
+		"DiscountPercentage": tier.DiscountPercentage,
+		"ExpiresAt":          buyerSession.ExpiresAt.Format("02 Jan 2006 15:04 MST"),
+	})
+	return nil
+}
+
+// CreateInvite signs a link that names the caller as referrer and stops working when
+// the session expires. The Open Graph fields let chat apps preview the product.
+func (g *GroupBuyUsecase) CreateInvite(ctx context.Context, sessionCode string, userID int64) (*dto.GroupBuyInviteResponse, error) {
+	buyerSession, err := g.openSessionByCode(ctx, sessionCode)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember := false
+	for _, member := range buyerSession.Members {
+		if member.UserID == userID {
+			isMember = true
+			break
+		}
+	}
+	if !isMember {
+		return nil, errorx.ErrNotSessionMember
+	}
+
+	campaign, err := g.groupBuySessionRepo.FindByID(ctx, buyerSession.GroupBuySessionID)
+	if err != nil {
+		g.log.Errorf("failed to get product session: %v", err)
+		return nil, err
+	}
+	productVariant, err := g.productVariantRepo.GetProductVariant(ctx, buyerSession.ProductVariantID)
+	if err != nil {
+		g.log.Errorf("failed to get product variant: %v", err)
+		return nil, err
+	}
+
+	link, token := g.invites.Link(buyerSession.SessionCode, userID, buyerSession.ExpiresAt)
+	png, err := invite.QRCode(link)
+	if err != nil {
+		g.log.Errorf("failed to generate invite qr code: %v", err)
+		return nil, err
+	}
+
+	return &dto.GroupBuyInviteResponse{
+		SessionCode: buyerSession.SessionCode,
+		URL:         link,
+		Token:       token,
+		ExpiresAt:   buyerSession.ExpiresAt,
+		QRCode:      "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		OpenGraph:   toOpenGraphMetadata(buyerSession, campaign, productVariant, link),
+	}, nil
+}
+
+func toOpenGraphMetadata(buyerSession *entity.BuyerGroupSession, campaign *entity.GroupBuySession, productVariant *entity.ProductVariant, link string) dto.OpenGraphMetadata {
+	title := productVariant.Name
+	image := ""
+	if productVariant.Product != nil {
+		title = productVariant.Product.Title + " - " + productVariant.Name
+		if len(productVariant.Product.ProductImages) > 0 {
+			image = productVariant.Product.ProductImages[0].ImageURL
+		}
+	}
+
+	currentDiscount := 0.0
+	if tier := entity.ApplicableTier(campaign.GroupBuyTiers, buyerSession.CurrentParticipants); tier != nil {
+		currentDiscount = tier.DiscountPercentage
+	}
+	description := fmt.Sprintf("%s, %d joined so far.", formatRupiah(productVariant.Price), buyerSession.CurrentParticipants)
+	if next := entity.NextTier(campaign.GroupBuyTiers, buyerSession.CurrentParticipants); next != nil {
+		description = fmt.Sprintf("%s, %d joined so far. %.0f%% off once %d people join.",
+			formatRupiah(productVariant.Price), buyerSession.CurrentParticipants, next.DiscountPercentage, next.ParticipantThreshold)
+	} else if currentDiscount > 0 {
+		description = fmt.Sprintf("%s, %d joined so far. %.0f%% off unlocked.",
+			formatRupiah(productVariant.Price), buyerSession.CurrentParticipants, currentDiscount)
+	}
+
+	return dto.OpenGraphMetadata{
+		Title:           "Join my group buy: " + title,
+		Description:     description,
+		Image:           image,
+		URL:             link,
+		Type:            "product",
+		PriceAmount:     productVariant.Price,
+		PriceCurrency:   "IDR",
+		CurrentDiscount: currentDiscount,
+		Tiers:           campaign.GroupBuyTiers,
+	}
+}
+
+func (g *GroupBuyUsecase) ChangeQuantity(ctx context.Context, sessionCode string, userID int64, quantity int) error {
+	buyerSession, err := g.openSessionByCode(ctx, sessionCode)
+	if err != nil {
+		return err
+	}
+
+	// the order was priced for the old quantity
+	if err := g.orders.CancelGroupBuyMemberOrders(ctx, buyerSession.ID, userID); err != nil {
+		g.log.Errorf("failed to cancel member orders: %v", err)
+		return err
+	}
+
+	err = g.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		// the campaign is locked before the buyer session
+		if _, err := g.groupBuySessionRepo.FindByIDForUpdate(ctx, buyerSession.GroupBuySessionID); err != nil {
+			return err
+		}
+
+		current, err := g.buyerGroupSessionRepo.GetSessionByIDForUpdate(ctx, buyerSession.ID)
+		if err != nil {
+			return err
+		}
+		if current.Status != "open" {
+			return errorx.ErrSessionClosed
+		}
+
+		var member *entity.BuyerGroupMember
+		for i := range current.Members {
+			if current.Members[i].UserID == userID {
+				member = &current.Members[i]
+			}
+		}
+		if member == nil {
+			return errorx.ErrNotSessionMember
+		}
+		if member.IsPaid() {
+			return errorx.ErrMemberAlreadyPaid
+		}
+
+		if err := g.claimUnits(ctx, current.GroupBuySessionID, quantity, member.ID); err != nil {
+			return err
+		}
+		return g.buyerGroupMemberRepo.UpdateQuantity(ctx, member.ID, quantity)
+	})
+	if err != nil {
+		g.log.Errorf("failed to change quantity: %v", err)
+		return err
+	}
+
+	g.log.Infof("User %d now claims %d units in session %s", userID, quantity, sessionCode)
+	return nil
+}
+
+// claimUnits checks that a member can claim quantity units of the campaign. The
+// campaign row stays locked until the surrounding transaction ends, so concurrent
+// claims are counted one after the other and never overshoot MaxQuantity.
+func (g *GroupBuyUsecase) claimUnits(ctx context.Context, campaignID string, quantity int, excludeMemberID string) error {
+	campaign, err := g.groupBuySessionRepo.FindByIDForUpdate(ctx, campaignID)
+	if err != nil {
+		g.log.Errorf("failed to lock product session: %v", err)
+		return err
+	}
+	// the campaign lock orders this against the end or cancellation of the campaign
+	if campaign.Status != "active" {
+		return errorx.ErrSessionClosed
+	}
+	if quantity > campaign.MaxQuantityPerMember {
+		return errorx.ErrQuantityPerMember
+	}
+
+	claimed, err := g.buyerGroupMemberRepo.SumClaimedQuantity(ctx, campaignID, excludeMemberID)
+	if err != nil {
+		g.log.Errorf("failed to sum claimed quantity: %v", err)
+		return err
+	}
+	if int64(claimed+quantity) > campaign.MaxQuantity {
+		g.log.Infof("Campaign %s has %d of %d units claimed, refusing %d more", campaignID, claimed, campaign.MaxQuantity, quantity)
+		return errorx.ErrQuantityUnavailable
+	}
+	return nil
+}
+
+func (g *GroupBuyUsecase) LeaveSession(ctx context.Context, sessionCode string, userID int64) error {
+	buyerSession, err := g.openSessionByCode(ctx, sessionCode)
+	if err != nil {
+		return err
+	}
+
+	if err := g.removeMember(ctx, buyerSession, userID); err != nil {
+		g.log.Errorf("failed to leave session: %v", err)
+		return err
+	}
+
+	g.log.Infof("User %d left session %s", userID, sessionCode)
+	return nil
+}
+
+func (g *GroupBuyUsecase) RemoveMember(ctx context.Context, sessionCode string, organizerUserID int64, memberUserID int64) error {
+	if organizerUserID == memberUserID {
+		return errorx.ErrCannotRemoveSelf
+	}
+
+	buyerSession, err := g.openSessionByCode(ctx, sessionCode)
+	if err != nil {
+		return err
+	}
+	if buyerSession.OrganizerUserID != organizerUserID {
+		return errorx.ErrNotSessionOrganizer
+	}
+
+	if err := g.removeMember(ctx, buyerSession, memberUserID); err != nil {
+		g.log.Errorf("failed to remove member: %v", err)
+		return err
+	}
+
+	g.log.Infof("User %d removed from session %s by organizer %d", memberUserID, sessionCode, organizerUserID)
+
+	data := map[string]any{
+		"SessionCode": buyerSession.SessionCode,
+		"ProductName": "",
+	}
+	if productVariant, err := g.productVariantRepo.GetProductVariant(ctx, buyerSession.ProductVariantID); err == nil {
+		data["ProductName"] = productVariant.Name
+	}
+	g.notifier.NotifyUser(ctx, memberUserID, mailer.TemplateGroupBuyRemoved, data)
+
 	return nil
 }
 
+func (g *GroupBuyUsecase) openSessionByCode(ctx context.Context, sessionCode string) (*entity.BuyerGroupSession, error) {
+	buyerSession, err := g.buyerGroupSessionRepo.GetSessionByCode(ctx, sessionCode)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	if buyerSession.Status != "open" {
+		return nil, errorx.ErrSessionClosed
+	}
+	return buyerSession, nil
+}
+
+// removeMember cancels the member's unpaid order first, so a member who paid in the
+// meantime stays. The session row is then locked while the member count and the
+// organizer change, and a session left without members is cancelled. Orders take the
+// same lock, so one placed after the cancellation is seen there and stops the removal.
+func (g *GroupBuyUsecase) removeMember(ctx context.Context, current *entity.BuyerGroupSession, userID int64) error {
+	if err := g.orders.CancelGroupBuyMemberOrders(ctx, current.ID, userID); err != nil {
+		return err
+	}
+
+	var after *entity.BuyerGroupSession
+	err := g.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		buyerSession, err := g.buyerGroupSessionRepo.GetSessionByIDForUpdate(ctx, current.ID)
+		if err != nil {
+			return err
+		}
+		if buyerSession.Status != "open" {
+			return errorx.ErrSessionClosed
+		}
+
+		var member *entity.BuyerGroupMember
+		var next *entity.BuyerGroupMember
+		for i := range buyerSession.Members {
+			switch {
+			case buyerSession.Members[i].UserID == userID:
+				member = &buyerSession.Members[i]
+			case next == nil:
+				// members come in join order
+				next = &buyerSession.Members[i]
+			}
+		}
+		if member == nil {
+			return errorx.ErrNotSessionMember
+		}
+
+		orders, err := g.orderRepo.FindByBuyerGroupSessionID(ctx, buyerSession.ID)
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if order.UserID != userID {
+				continue
+			}
+			if order.Status == entity.OrderStatusPendingPayment {
+				return errorx.ErrMemberHasOrder
+			}
+			if slices.Contains(groupBuySoldStatuses, order.Status) {
+				return errorx.ErrMemberAlreadyPaid
+			}
+		}
+
+		if err := g.buyerGroupMemberRepo.Delete(ctx, member.ID); err != nil {
+			return err
+		}
+		if err := g.buyerGroupSessionRepo.RemoveMember(ctx, buyerSession); err != nil {
+			return err
+		}
+		buyerSession.CurrentParticipants--
+		after = buyerSession
+
+		if next == nil {
+			buyerSession.Status = "cancelled"
+			return g.buyerGroupSessionRepo.ChangeBuyerSessionStatus(ctx, buyerSession.ID, "cancelled")
+		}
+		if buyerSession.OrganizerUserID == userID {
+			g.log.Infof("Organizer of session %s handed over to user %d", buyerSession.SessionCode, next.UserID)
+			buyerSession.OrganizerUserID = next.UserID
+			return g.buyerGroupSessionRepo.ChangeOrganizer(ctx, buyerSession.ID, next.UserID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	g.events.Publish(ctx, after.GroupBuySessionID, after.ID, GroupBuyEventMemberLeft, map[string]any{
+		"user_id":           userID,
+		"participants":      after.CurrentParticipants,
+		"organizer_user_id": after.OrganizerUserID,
+		"status":            after.Status,
+	})
+	return nil
+}
+
+func (g *GroupBuyUsecase) notifyJoined(ctx context.Context, userID int64, sessionCode string) {
+	buyerSession, err := g.buyerGroupSessionRepo.GetSessionByCode(ctx, sessionCode)
+	if err != nil {
+		g.log.Errorf("failed to get session for notification: %v", err)
+		return
+	}
+
+	data := map[string]any{
+		"SessionCode": buyerSession.SessionCode,
+		"ProductName": "",
+		"ExpiresAt":   buyerSession.ExpiresAt.Format("02 Jan 2006 15:04 MST"),
+	}
+	if productVariant, err := g.productVariantRepo.GetProductVariant(ctx, buyerSession.ProductVariantID); err == nil {
+		data["ProductName"] = productVariant.Name
+	}
+
+	g.notifier.NotifyUser(ctx, userID, mailer.TemplateGroupBuyJoined, data)
+}
+
 func (g *GroupBuyUsecase) ChangeBuyerSessionStatus(ctx context.Context, buyerSessionID string, status string) error {
 	return g.buyerGroupSessionRepo.ChangeBuyerSessionStatus(ctx, buyerSessionID, status)
 }
 
+func (g *GroupBuyUsecase) ExpireBuyerSession(ctx context.Context, buyerSessionID string) (bool, error) {
+	// a session locked because it filled up still ends its payment window here
+	expired, err := g.buyerGroupSessionRepo.ChangeBuyerSessionStatusFrom(ctx, buyerSessionID, []string{"open", "locked"}, "expired")
+	if err != nil || !expired {
+		return expired, err
+	}
+
+	if buyerSession, err := g.buyerGroupSessionRepo.GetSessionByID(ctx, buyerSessionID); err == nil {
+		g.events.Publish(ctx, buyerSession.GroupBuySessionID, buyerSession.ID, GroupBuyEventSessionExpired, map[string]any{
+			"participants": buyerSession.CurrentParticipants,
+		})
+	}
+	return true, nil
+}
+
+func (g *GroupBuyUsecase) SubscribeSessionEvents(ctx context.Context, sessionCode string) (<-chan realtime.Event, func(), error) {
+	buyerSession, err := g.buyerGroupSessionRepo.GetSessionByCode(ctx, sessionCode)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errorx.ErrSessionNotFound
+		}
+		return nil, nil, err
+	}
+	return g.events.SubscribeBuyerSession(ctx, buyerSession.ID)
+}
+
+func (g *GroupBuyUsecase) SubscribeCampaignEvents(ctx context.Context, campaignID string, sellerID int64) (<-chan realtime.Event, func(), error) {
+	campaign, err := g.groupBuySessionRepo.FindByID(ctx, campaignID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errorx.ErrGroupBuySessionNotFound
+		}
+		return nil, nil, err
+	}
+	if campaign.SellerID != sellerID {
+		return nil, nil, errorx.ErrGroupBuySessionNotFound
+	}
+	return g.events.SubscribeCampaign(ctx, campaign.ID)
+}
+
 func (g *GroupBuyUsecase) GetBuyerGroupSessionTier(ctx context.Context, tierID string) (*entity.GroupBuyTier, error) {
 	tier, err := g.groupBuyTierRepo.FindByID(ctx, tierID)
 	if err != nil {
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/febry3/gamingin/internal/dto"
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/errorx"
+	"github.com/febry3/gamingin/internal/repository"
+	"github.com/febry3/gamingin/internal/worker/tasks"
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+)
+
+type NotificationUsecaseContract interface {
+	// NotifyUser queues a templated email to the user's address. Failures are logged,
+	// a missing email never fails the action that triggered it.
+	NotifyUser(ctx context.Context, userID int64, templateID string, data map[string]any)
+	NotifyUsers(ctx context.Context, userIDs []int64, templateID string, data map[string]any)
+	// NotifyInbox stores the notification in each user's inbox with data as its payload
+	// and emails the template to the users it was new for. A user who already has a
+	// notification with the same dedupe key gets neither.
+	NotifyInbox(ctx context.Context, userIDs []int64, notification entity.Notification, templateID string, data map[string]any)
+	GetInbox(ctx context.Context, userID int64, page, limit int) (*dto.NotificationListResponse, error)
+	MarkRead(ctx context.Context, userID int64, notificationID string) error
+	MarkAllRead(ctx context.Context, userID int64) error
+}
+
+// emailMaxRetry is how many times asynq retries a failed delivery before archiving it
+const emailMaxRetry = 5
+
+type NotificationUsecase struct {
+	userRepo         repository.UserRepository
+	notificationRepo repository.NotificationRepository
+	asynqClient      *asynq.Client
+	log              *logrus.Logger
+}
+
+func NewNotificationUsecase(userRepo repository.UserRepository, notificationRepo repository.NotificationRepository, asynqClient *asynq.Client, log *logrus.Logger) NotificationUsecaseContract {
+	return &NotificationUsecase{
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		asynqClient:      asynqClient,
+		log:              log,
+	}
+}
+
+func (n *NotificationUsecase) NotifyUser(ctx context.Context, userID int64, templateID string, data map[string]any) {
+	user, err := n.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		n.log.Errorf("[NotificationUsecase] Failed to find user %d for %s: %v", userID, templateID, err)
+		return
+	}
+
+	if user.Email == "" {
+		n.log.Warnf("[NotificationUsecase] User %d has no email, skipping %s", userID, templateID)
+		return
+	}
+
+	payloadData := make(map[string]any, len(data)+1)
+	for k, v := range data {
+		payloadData[k] = v
+	}
+	payloadData["Name"] = displayName(user.FirstName, user.Username)
+
+	task, err := tasks.NewEmailDeliveryTask(tasks.EmailDeliveryPayload{
+		UserID:     userID,
+		Email:      user.Email,
+		TemplateID: templateID,
+		Data:       payloadData,
+	})
+	if err != nil {
+		n.log.Errorf("[NotificationUsecase] Failed to create email task %s: %v", templateID, err)
+		return
+	}
+
+	if _, err := n.asynqClient.Enqueue(task, asynq.Queue("default"), asynq.MaxRetry(emailMaxRetry)); err != nil {
+		n.log.Errorf("[NotificationUsecase] Failed to enqueue email %s for user %d: %v", templateID, userID, err)
+	}
+}
+
+func (n *NotificationUsecase) NotifyUsers(ctx context.Context, userIDs []int64, templateID string, data map[string]any) {
+	for _, userID := range userIDs {
+		n.NotifyUser(ctx, userID, templateID, data)
+	}
+}
+
+func (n *NotificationUsecase) NotifyInbox(ctx context.Context, userIDs []int64, notification entity.Notification, templateID string, data map[string]any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		n.log.Errorf("[NotificationUsecase] Failed to encode %s payload: %v", notification.Type, err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		entry := notification
+		entry.UserID = userID
+		entry.Data = payload
+
+		created, err := n.notificationRepo.Create(ctx, &entry)
+		if err != nil {
+			n.log.Errorf("[NotificationUsecase] Failed to store %s for user %d: %v", notification.Type, userID, err)
+			continue
+		}
+		if !created {
+			n.log.Infof("[NotificationUsecase] User %d already has %s, skipping", userID, notification.DedupeKey)
+			continue
+		}
+
+		n.NotifyUser(ctx, userID, templateID, data)
+	}
+}
+
+func (n *NotificationUsecase) GetInbox(ctx context.Context, userID int64, page, limit int) (*dto.NotificationListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	notifications, total, err := n.notificationRepo.FindByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		n.log.Errorf("[NotificationUsecase] Find Notifications Error: %v", err.Error())
+		return nil, err
+	}
+
+	unread, err := n.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		n.log.Errorf("[NotificationUsecase] Count Unread Error: %v", err.Error())
+		return nil, err
+	}
+
+	return &dto.NotificationListResponse{
+		Notifications: notifications,
+		UnreadCount:   unread,
+		TotalCount:    total,
+		Page:          page,
+		Limit:         limit,
+	}, nil
+}
+
+func (n *NotificationUsecase) MarkRead(ctx context.Context, userID int64, notificationID string) error {
+	found, err := n.notificationRepo.MarkRead(ctx, userID, notificationID)
+	if err != nil {
+		n.log.Errorf("[NotificationUsecase] Mark Read Error: %v", err.Error())
+		return err
+	}
+	if !found {
+		return errorx.ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (n *NotificationUsecase) MarkAllRead(ctx context.Context, userID int64) error {
+	if _, err := n.notificationRepo.MarkAllRead(ctx, userID); err != nil {
+		n.log.Errorf("[NotificationUsecase] Mark All Read Error: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+func displayName(firstName, username string) string {
+	if firstName != "" {
+		return firstName
+	}
+	return username
+}
+
+func formatRupiah(amount float64) string {
+	return fmt.Sprintf("Rp %.0f", amount)
+}
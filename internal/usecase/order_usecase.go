@@ -2,13 +2,17 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/febry3/gamingin/internal/dto"
 	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/errorx"
+	"github.com/febry3/gamingin/internal/infra/mailer"
 	"github.com/febry3/gamingin/internal/infra/payment"
 	"github.com/febry3/gamingin/internal/repository"
 	"github.com/febry3/gamingin/internal/worker/tasks"
@@ -24,7 +28,57 @@ type OrderUsecaseContract interface {
 	GetOrders(ctx context.Context, userID int64, page, limit int) (*dto.OrderListResponse, error)
 	GetOrderByID(ctx context.Context, userID int64, orderID string) (*dto.OrderResponse, error)
 	HandlePaymentNotification(ctx context.Context, notification *dto.MidtransNotification) error
+	RegeneratePayment(ctx context.Context, userID int64, orderID string, request *dto.RegeneratePaymentRequest) (*dto.OrderResponse, error)
+	UpdateOrderStatusBySeller(ctx context.Context, sellerID int64, orderID string, request *dto.UpdateOrderStatusRequest) (*dto.OrderResponse, error)
 	ExpireOrder(ctx context.Context, orderID string) error
+	ProcessPaymentEvent(ctx context.Context, eventID string) error
+	GetPaymentEvents(ctx context.Context, status string, page, limit int) (*dto.PaymentEventListResponse, error)
+	ReplayPaymentEvent(ctx context.Context, eventID string) error
+	ReconcilePayments(ctx context.Context) error
+	GetPaymentDiscrepancies(ctx context.Context) ([]entity.PaymentDiscrepancy, error)
+	// SettleGroupBuyDiscounts pays out to the wallet the extra discount the paid orders
+	// of a closed buyer session earned on the final tier
+	SettleGroupBuyDiscounts(ctx context.Context, buyerSessionID string) error
+	// ConfirmGroupBuyOrders closes the unpaid orders of a buyer session that reached its
+	// target and releases the paid ones to the seller with the final tier applied
+	ConfirmGroupBuyOrders(ctx context.Context, buyerSessionID string) error
+	// RefundGroupBuyOrders closes the unpaid orders of a buyer session that missed its
+	// target and refunds the paid ones
+	RefundGroupBuyOrders(ctx context.Context, buyerSessionID string) error
+	// CancelGroupBuyMemberOrders cancels a member's unpaid orders in a buyer session, it
+	// returns errorx.ErrMemberAlreadyPaid when the member has paid
+	CancelGroupBuyMemberOrders(ctx context.Context, buyerSessionID string, userID int64) error
+}
+
+// directOrderPaymentWindow is how long a direct order keeps its stock reserved while waiting for payment
+const directOrderPaymentWindow = 5 * time.Minute
+
+// reconcileLookback is how far back the reconciler re-checks payments that are already closed
+const reconcileLookback = 24 * time.Hour
+
+// Payment outcomes group gateway statuses that mean the same thing for an order,
+// e.g. an expired VA that we later cancelled is still just unpaid.
+const (
+	paymentOutcomePaid    = "paid"
+	paymentOutcomePending = "pending"
+	paymentOutcomeUnpaid  = "unpaid"
+	paymentOutcomeUnknown = ""
+)
+
+func paymentOutcome(status string) string {
+	switch status {
+	case "settlement", "capture":
+		return paymentOutcomePaid
+	case "pending":
+		return paymentOutcomePending
+	case "expire", "cancel", "deny", "failure":
+		return paymentOutcomeUnpaid
+	case "":
+		return paymentOutcomeUnknown
+	default:
+		// refund, partial_refund, chargeback, ... never match a local status
+		return status
+	}
 }
 
 type OrderUsecase struct {
@@ -35,9 +89,18 @@ type OrderUsecase struct {
 	variantRepo      repository.ProductVariantRepository
 	stockRepo        repository.ProductVariantStockRepository
 	buyerSessionRepo repository.BuyerGroupBuySessionRepository
+	memberRepo       repository.BuyerGroupMemberRepository
+	userRepo         repository.UserRepository
+	discrepancyRepo  repository.PaymentDiscrepancyRepository
+	eventRepo        repository.PaymentEventRepository
+	adjustmentRepo   repository.OrderAdjustmentRepository
+	tierRepo         repository.GroupBuyTierRepository
+	walletRepo       repository.UserWalletRepository
+	notifier         NotificationUsecaseContract
+	events           GroupBuyEventUsecaseContract
 	paymentGateway   payment.PaymentGateway
 	tx               repository.TxManager
-	asynqClient      *asynq.Client
+	asynqClient      TaskEnqueuer
 	log              *logrus.Logger
 }
 
@@ -49,9 +112,18 @@ func NewOrderUsecase(
 	variantRepo repository.ProductVariantRepository,
 	stockRepo repository.ProductVariantStockRepository,
 	buyerSessionRepo repository.BuyerGroupBuySessionRepository,
+	memberRepo repository.BuyerGroupMemberRepository,
+	userRepo repository.UserRepository,
+	discrepancyRepo repository.PaymentDiscrepancyRepository,
+	eventRepo repository.PaymentEventRepository,
+	adjustmentRepo repository.OrderAdjustmentRepository,
+	tierRepo repository.GroupBuyTierRepository,
+	walletRepo repository.UserWalletRepository,
+	notifier NotificationUsecaseContract,
+	events GroupBuyEventUsecaseContract,
 	paymentGateway payment.PaymentGateway,
 	tx repository.TxManager,
-	asynqClient *asynq.Client,
+	asynqClient TaskEnqueuer,
 	log *logrus.Logger,
 ) OrderUsecaseContract {
 	return &OrderUsecase{
@@ -62,6 +134,15 @@ func NewOrderUsecase(
 		variantRepo:      variantRepo,
 		stockRepo:        stockRepo,
 		buyerSessionRepo: buyerSessionRepo,
+		memberRepo:       memberRepo,
+		userRepo:         userRepo,
+		discrepancyRepo:  discrepancyRepo,
+		eventRepo:        eventRepo,
+		adjustmentRepo:   adjustmentRepo,
+		tierRepo:         tierRepo,
+		walletRepo:       walletRepo,
+		notifier:         notifier,
+		events:           events,
 		paymentGateway:   paymentGateway,
 		tx:               tx,
 		asynqClient:      asynqClient,
@@ -70,6 +151,10 @@ func NewOrderUsecase(
 }
 
 func (u *OrderUsecase) CreateDirectOrder(ctx context.Context, userID int64, request *dto.CreateOrderRequest) (*dto.OrderResponse, error) {
+	if err := u.ensureEmailVerified(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	variant, err := u.variantRepo.GetProductVariant(ctx, request.ProductVariantID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -123,7 +208,7 @@ func (u *OrderUsecase) CreateDirectOrder(ctx context.Context, userID int64, requ
 			AddressID:        request.AddressID,
 		}
 
-		if err := u.orderRepo.Create(order); err != nil {
+		if err := u.orderRepo.Create(ctx, order); err != nil {
 			return fmt.Errorf("failed to create order: %w", err)
 		}
 
@@ -142,7 +227,7 @@ func (u *OrderUsecase) CreateDirectOrder(ctx context.Context, userID int64, requ
 			Notes:         address.Notes,
 		}
 
-		if err := u.shippingRepo.Create(shippingDetail); err != nil {
+		if err := u.shippingRepo.Create(ctx, shippingDetail); err != nil {
 			return fmt.Errorf("failed to create shipping detail: %w", err)
 		}
 
@@ -162,9 +247,10 @@ func (u *OrderUsecase) CreateDirectOrder(ctx context.Context, userID int64, requ
 
 	paymentResult, err = u.paymentGateway.ChargeVA(ctx, orderNumber, int64(totalAmount), request.BankCode, nil)
 	if err != nil {
-		u.orderRepo.UpdateStatus(order.ID, entity.OrderStatusCancelled)
-		u.log.Errorf("Failed to create VA payment: %v", err)
-		return nil, errorx.NewInternalError("Failed to create payment. Please try again.")
+		// Keep the order and its reservation, the buyer can request a new VA until the window ends
+		u.log.Errorf("Failed to create VA payment for order %s: %v", orderNumber, err)
+		u.scheduleOrderExpiration(order, directOrderPaymentWindow)
+		return u.buildOrderResponse(order, nil, variant, nil), nil
 	}
 
 	paymentEntity := &entity.Payment{
@@ -177,28 +263,33 @@ func (u *OrderUsecase) CreateDirectOrder(ctx context.Context, userID int64, requ
 		BillKey:              paymentResult.BillKey,
 		BillerCode:           paymentResult.BillerCode,
 		GatewayTransactionID: paymentResult.TransactionID,
+		GatewayOrderID:       orderNumber,
 		ExpiredAt:            paymentResult.ExpiredAt,
 	}
 
-	if err := u.paymentRepo.Create(paymentEntity); err != nil {
-		u.log.Errorf("Failed to save payment record: %v", err)
-		// Continue - the order is created, we can retry payment later
-	}
-
-	task, err := tasks.NewOrderExpirationTask(order.ID, orderNumber, "", userID, int64(totalAmount))
-	if err == nil {
-		_, err = u.asynqClient.Enqueue(task, asynq.ProcessIn(5*time.Minute), asynq.Queue("critical"))
-		if err != nil {
-			u.log.Warnf("Failed to schedule expiration task for order %s: %v", orderNumber, err)
+	if err := u.paymentRepo.Create(ctx, paymentEntity); err != nil {
+		// No row tracks the charge, so cancel it before the buyer requests a new VA
+		u.log.Errorf("Failed to save payment record for order %s: %v", orderNumber, err)
+		if err := u.paymentGateway.CancelTransaction(ctx, orderNumber); err != nil {
+			u.log.Warnf("Failed to cancel untracked gateway transaction for order %s: %v", orderNumber, err)
 		}
+		u.scheduleOrderExpiration(order, directOrderPaymentWindow)
+		return u.buildOrderResponse(order, nil, variant, nil), nil
 	}
 
+	u.scheduleOrderExpiration(order, directOrderPaymentWindow)
+	u.notifyOrderCreated(ctx, order, paymentEntity)
+
 	u.log.Infof("Order created: %s, VA: %s", orderNumber, paymentResult.VANumber)
 
 	return u.buildOrderResponse(order, paymentEntity, variant, nil), nil
 }
 
 func (u *OrderUsecase) CreateGroupBuyOrder(ctx context.Context, userID int64, request *dto.CreateGroupBuyOrderRequest) (*dto.OrderResponse, error) {
+	if err := u.ensureEmailVerified(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	session, err := u.buyerSessionRepo.GetSessionByID(ctx, request.BuyerGroupSessionID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -207,14 +298,19 @@ func (u *OrderUsecase) CreateGroupBuyOrder(ctx context.Context, userID int64, re
 		return nil, err
 	}
 
-	isMember := false
-	for _, member := range session.Members {
-		if member.UserID == userID {
-			isMember = true
+	// a full session is locked to joins while its members still pay
+	if session.Status != "open" && (session.Status != "locked" || session.IsExpired()) {
+		return nil, errorx.NewBadRequestError("Group buy session is closed")
+	}
+
+	var member *entity.BuyerGroupMember
+	for i := range session.Members {
+		if session.Members[i].UserID == userID {
+			member = &session.Members[i]
 			break
 		}
 	}
-	if !isMember {
+	if member == nil {
 		return nil, errorx.NewForbiddenError("You are not a member of this group buy session")
 	}
 
@@ -235,11 +331,23 @@ func (u *OrderUsecase) CreateGroupBuyOrder(ctx context.Context, userID int64, re
 		return nil, errorx.NewForbiddenError("Address does not belong to user")
 	}
 
+	// the tier comes from the participants who actually joined, never from the client
+	tiers, err := u.tierRepo.FindBySessionID(ctx, session.GroupBuySessionID)
+	if err != nil {
+		return nil, err
+	}
+	tier := entity.ApplicableTier(tiers, session.CurrentParticipants)
+
 	priceAtOrder := variant.Price
-	quantity := 1 // Group buy is typically 1 item per member
+	// the member claimed these units against the campaign when joining
+	quantity := member.Quantity
 	subtotal := priceAtOrder * float64(quantity)
+	discountAmount := 0.0
+	if tier != nil {
+		discountAmount = groupBuyDiscount(subtotal, tier)
+	}
 	deliveryCharge := 0.0
-	totalAmount := subtotal + deliveryCharge
+	totalAmount := subtotal - discountAmount + deliveryCharge
 
 	orderNumber := u.generateOrderNumber()
 
@@ -247,6 +355,30 @@ func (u *OrderUsecase) CreateGroupBuyOrder(ctx context.Context, userID int64, re
 	var paymentResult *payment.VAPaymentResult
 
 	err = u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		// the session row serializes the orders of its members, each member holds one
+		// order for its claimed units
+		locked, err := u.buyerSessionRepo.GetSessionByIDForUpdate(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+		// the member may have left or been removed since the session was read
+		if !locked.HasMember(userID) {
+			return errorx.NewForbiddenError("You are not a member of this group buy session")
+		}
+		if locked.Status != "open" && (locked.Status != "locked" || locked.IsExpired()) {
+			return errorx.NewBadRequestError("Group buy session is closed")
+		}
+		existing, err := u.orderRepo.FindByBuyerGroupSessionID(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+		for _, o := range existing {
+			if o.UserID != userID || o.Status == entity.OrderStatusCancelled || o.Status == entity.OrderStatusExpired {
+				continue
+			}
+			return errorx.NewBadRequestError("You already have an order for this group buy session")
+		}
+
 		order = &entity.Order{
 			OrderNumber:         orderNumber,
 			UserID:              userID,
@@ -256,16 +388,31 @@ func (u *OrderUsecase) CreateGroupBuyOrder(ctx context.Context, userID int64, re
 			Quantity:            quantity,
 			PriceAtOrder:        priceAtOrder,
 			Subtotal:            subtotal,
+			DiscountAmount:      discountAmount,
 			DeliveryCharge:      deliveryCharge,
 			TotalAmount:         totalAmount,
 			Status:              entity.OrderStatusPendingPayment,
 			AddressID:           request.AddressID,
 		}
 
-		if err := u.orderRepo.Create(order); err != nil {
+		if err := u.orderRepo.Create(ctx, order); err != nil {
 			return fmt.Errorf("failed to create order: %w", err)
 		}
 
+		if tier != nil {
+			adjustment := entity.OrderAdjustment{
+				OrderID:     order.ID,
+				Description: fmt.Sprintf("Group buy discount %.0f%% at %d participants", tier.DiscountPercentage, session.CurrentParticipants),
+				Amount:      -discountAmount,
+				SourceType:  entity.AdjustmentSourceGroupBuy,
+				SourceID:    tier.ID,
+			}
+			if err := u.adjustmentRepo.Create(ctx, &adjustment); err != nil {
+				return fmt.Errorf("failed to create order adjustment: %w", err)
+			}
+			order.Adjustments = append(order.Adjustments, adjustment)
+		}
+
 		shippingDetail := &entity.OrderShippingDetail{
 			OrderID:       order.ID,
 			ReceiverName:  address.ReceiverName,
@@ -280,7 +427,7 @@ func (u *OrderUsecase) CreateGroupBuyOrder(ctx context.Context, userID int64, re
 			Notes:         address.Notes,
 		}
 
-		if err := u.shippingRepo.Create(shippingDetail); err != nil {
+		if err := u.shippingRepo.Create(ctx, shippingDetail); err != nil {
 			return fmt.Errorf("failed to create shipping detail: %w", err)
 		}
 
@@ -293,7 +440,10 @@ func (u *OrderUsecase) CreateGroupBuyOrder(ctx context.Context, userID int64, re
 
 	paymentResult, err = u.paymentGateway.ChargeVA(ctx, orderNumber, int64(totalAmount), request.BankCode, &session.ExpiresAt)
 	if err != nil {
-		u.orderRepo.UpdateStatus(order.ID, entity.OrderStatusCancelled)
+		u.log.Errorf("Failed to create VA payment for order %s: %v", orderNumber, err)
+		if err := u.orderRepo.UpdateStatus(ctx, order.ID, entity.OrderStatusCancelled); err != nil {
+			u.log.Errorf("Failed to cancel order %s: %v", orderNumber, err)
+		}
 		return nil, errorx.NewInternalError("Failed to create payment")
 	}
 
@@ -307,11 +457,24 @@ func (u *OrderUsecase) CreateGroupBuyOrder(ctx context.Context, userID int64, re
 		BillKey:              paymentResult.BillKey,
 		BillerCode:           paymentResult.BillerCode,
 		GatewayTransactionID: paymentResult.TransactionID,
+		GatewayOrderID:       orderNumber,
+		// the VA was charged to expire with the session
+		ExpiredAt: session.ExpiresAt,
+	}
+	if err := u.paymentRepo.Create(ctx, paymentEntity); err != nil {
+		// No row tracks the charge, so cancel it together with the order
+		u.log.Errorf("Failed to save payment record for order %s: %v", orderNumber, err)
+		if err := u.paymentGateway.CancelTransaction(ctx, orderNumber); err != nil {
+			u.log.Warnf("Failed to cancel untracked gateway transaction for order %s: %v", orderNumber, err)
+		}
+		if err := u.orderRepo.UpdateStatus(ctx, order.ID, entity.OrderStatusCancelled); err != nil {
+			u.log.Errorf("Failed to cancel order %s: %v", orderNumber, err)
+		}
+		return nil, errorx.NewInternalError("Failed to create payment")
 	}
-	u.paymentRepo.Create(paymentEntity)
+	u.notifyOrderCreated(ctx, order, paymentEntity)
 
-	task, _ := tasks.NewOrderExpirationTask(order.ID, orderNumber, request.GroupBuyTierID, userID, int64(totalAmount))
-	u.asynqClient.Enqueue(task, asynq.ProcessIn(1*time.Minute), asynq.Queue("critical"))
+	u.scheduleOrderExpiration(order, time.Until(session.ExpiresAt))
 
 	return u.buildOrderResponse(order, paymentEntity, variant, nil), nil
 }
@@ -325,7 +488,7 @@ func (u *OrderUsecase) GetOrders(ctx context.Context, userID int64, page, limit
 	}
 	offset := (page - 1) * limit
 
-	orders, total, err := u.orderRepo.FindByUserID(userID, limit, offset)
+	orders, total, err := u.orderRepo.FindByUserID(ctx, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -344,7 +507,7 @@ func (u *OrderUsecase) GetOrders(ctx context.Context, userID int64, page, limit
 }
 
 func (u *OrderUsecase) GetOrderByID(ctx context.Context, userID int64, orderID string) (*dto.OrderResponse, error) {
-	order, err := u.orderRepo.FindByID(orderID)
+	order, err := u.orderRepo.FindByID(ctx, orderID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errorx.NewNotFoundError("Order not found")
@@ -370,115 +533,1106 @@ func (u *OrderUsecase) HandlePaymentNotification(ctx context.Context, notificati
 		return errorx.NewBadRequestError("Invalid signature")
 	}
 
-	order, err := u.orderRepo.FindByOrderNumber(notification.OrderID)
+	payload, err := json.Marshal(notification)
 	if err != nil {
-		u.log.Errorf("Order not found for notification: %s", notification.OrderID)
-		return errorx.NewNotFoundError("Order not found")
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	event := &entity.PaymentEvent{
+		TransactionID:     notification.TransactionID,
+		TransactionStatus: notification.TransactionStatus,
+		OrderNumber:       notification.OrderID,
+		Payload:           string(payload),
+		Status:            entity.PaymentEventStatusReceived,
 	}
 
-	paymentEntity, err := u.paymentRepo.FindByOrderID(order.ID)
+	created, err := u.eventRepo.Create(ctx, event)
 	if err != nil {
-		u.log.Errorf("Payment not found for order: %s", order.ID)
+		u.log.Errorf("Failed to store payment event for order %s: %v", notification.OrderID, err)
 		return err
 	}
 
-	switch notification.TransactionStatus {
-	case "settlement", "capture":
+	if !created {
+		// Midtrans retries until it gets a 200, the first delivery is already stored
+		event, err = u.eventRepo.FindByTransaction(ctx, notification.TransactionID, notification.TransactionStatus)
+		if err != nil {
+			return err
+		}
+
+		if event.Status == entity.PaymentEventStatusProcessed || event.Status == entity.PaymentEventStatusIgnored {
+			u.log.Infof("Duplicate notification for order %s (%s), already handled", event.OrderNumber, event.TransactionStatus)
+			return nil
+		}
+	}
+
+	return u.enqueuePaymentEvent(event)
+}
+
+// ProcessPaymentEvent applies a stored notification to its order. The event row is locked
+// for the whole transaction so concurrent deliveries of the same event run one at a time,
+// and an event that was already handled is skipped.
+func (u *OrderUsecase) ProcessPaymentEvent(ctx context.Context, eventID string) error {
+	err := u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		event, err := u.eventRepo.FindByIDForUpdate(ctx, eventID)
+		if err != nil {
+			return err
+		}
+
+		if event.Status == entity.PaymentEventStatusProcessed || event.Status == entity.PaymentEventStatusIgnored {
+			u.log.Infof("Payment event %s already %s, skipping", event.ID, event.Status)
+			return nil
+		}
+
+		status, reason, err := u.applyPaymentEvent(ctx, event)
+		if err != nil {
+			return err
+		}
+
 		now := time.Now()
-		paymentEntity.Status = entity.PaymentStatusSettlement
-		paymentEntity.PaidAt = &now
-		u.paymentRepo.Update(paymentEntity)
+		event.Status = status
+		event.Error = reason
+		event.Attempts++
+		event.ProcessedAt = &now
+		return u.eventRepo.Update(ctx, event)
+	})
 
-		order.Status = entity.OrderStatusPaid
-		u.orderRepo.Update(order)
+	if err != nil {
+		u.log.Errorf("Failed to process payment event %s: %v", eventID, err)
+		u.markPaymentEventFailed(ctx, eventID, err)
+		return err
+	}
 
-		if err := u.deductStockOnPayment(ctx, order.ProductVariantID, order.Quantity); err != nil {
-			u.log.Errorf("Failed to deduct stock for order %s: %v", order.OrderNumber, err)
+	return nil
+}
+
+func (u *OrderUsecase) GetPaymentEvents(ctx context.Context, status string, page, limit int) (*dto.PaymentEventListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	events, total, err := u.eventRepo.FindAll(ctx, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PaymentEventListResponse{
+		Events:     events,
+		TotalCount: total,
+		Page:       page,
+		Limit:      limit,
+	}, nil
+}
+
+// ReplayPaymentEvent queues a stored event again. Processing only moves pending payments,
+// so replaying an event whose effect was already applied is a no-op.
+func (u *OrderUsecase) ReplayPaymentEvent(ctx context.Context, eventID string) error {
+	event, err := u.eventRepo.FindByID(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errorx.NewNotFoundError("Payment event not found")
 		}
+		return err
+	}
 
-		u.log.Infof("Payment settled for order: %s", order.OrderNumber)
+	event.Status = entity.PaymentEventStatusReceived
+	event.Error = ""
+	event.ProcessedAt = nil
+	if err := u.eventRepo.Update(ctx, event); err != nil {
+		return err
+	}
 
-	case "pending":
-		// Still waiting for payment
-		u.log.Infof("Payment pending for order: %s", order.OrderNumber)
+	u.log.Infof("Replaying payment event %s for order %s", event.ID, event.OrderNumber)
+	return u.enqueuePaymentEvent(event)
+}
 
-	case "expire":
-		paymentEntity.Status = entity.PaymentStatusExpire
-		u.paymentRepo.Update(paymentEntity)
+// RegeneratePayment cancels the current VA of a pending order and charges a new one for the
+// chosen bank. The new VA expires when the order's payment window ends and the stock
+// reservation is left in place.
+func (u *OrderUsecase) RegeneratePayment(ctx context.Context, userID int64, orderID string, request *dto.RegeneratePaymentRequest) (*dto.OrderResponse, error) {
+	order, err := u.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewNotFoundError("Order not found")
+		}
+		return nil, err
+	}
 
-		order.Status = entity.OrderStatusExpired
-		u.orderRepo.Update(order)
+	if order.UserID != userID {
+		return nil, errorx.NewForbiddenError("Order not found")
+	}
 
-		// Release reserved stock
-		u.releaseStock(order.ProductVariantID, order.Quantity)
+	if order.Status != entity.OrderStatusPendingPayment || (order.Payment != nil && order.Payment.Status != entity.PaymentStatusPending) {
+		return nil, errorx.NewBadRequestError("Order is not awaiting payment")
+	}
 
-		u.log.Infof("Payment expired for order: %s", order.OrderNumber)
+	deadline, err := u.paymentDeadline(ctx, order)
+	if err != nil {
+		u.log.Errorf("Failed to get payment deadline for order %s: %v", order.OrderNumber, err)
+		return nil, err
+	}
 
-	case "cancel", "deny":
-		paymentEntity.Status = entity.PaymentStatusCancel
-		u.paymentRepo.Update(paymentEntity)
+	if time.Until(deadline) < time.Minute {
+		return nil, errorx.NewBadRequestError("Payment window for this order has ended")
+	}
 
-		order.Status = entity.OrderStatusCancelled
-		u.orderRepo.Update(order)
+	gatewayOrderID := fmt.Sprintf("%s-%d", order.OrderNumber, time.Now().Unix())
+
+	if order.Payment != nil {
+		if err := u.detachCharge(ctx, order, gatewayOrderID); err != nil {
+			return nil, err
+		}
+	}
 
-		u.releaseStock(order.ProductVariantID, order.Quantity)
+	paymentResult, err := u.paymentGateway.ChargeVA(ctx, gatewayOrderID, int64(order.TotalAmount), request.BankCode, &deadline)
+	if err != nil {
+		u.log.Errorf("Failed to create VA payment for order %s: %v", order.OrderNumber, err)
+		return nil, errorx.NewInternalError("Failed to create payment. Please try again.")
+	}
 
-		u.log.Infof("Payment cancelled/denied for order: %s", order.OrderNumber)
+	var paymentEntity *entity.Payment
+	err = u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		locked, err := u.orderRepo.FindByIDForUpdate(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		paymentEntity, err = u.paymentRepo.FindByOrderIDForUpdate(ctx, order.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// Another request or the expiration task got here first
+		if locked.Status != entity.OrderStatusPendingPayment ||
+			(order.Payment == nil && paymentEntity != nil) ||
+			(paymentEntity != nil && paymentEntity.GatewayOrderID != gatewayOrderID) {
+			return errorx.NewBadRequestError("Order payment was changed, please try again")
+		}
+
+		if paymentEntity == nil {
+			paymentEntity = &entity.Payment{
+				OrderID:       order.ID,
+				Amount:        order.TotalAmount,
+				Status:        entity.PaymentStatusPending,
+				PaymentMethod: "bank_transfer",
+			}
+		}
+
+		paymentEntity.BankCode = request.BankCode
+		paymentEntity.VANumber = paymentResult.VANumber
+		paymentEntity.BillKey = paymentResult.BillKey
+		paymentEntity.BillerCode = paymentResult.BillerCode
+		paymentEntity.GatewayTransactionID = paymentResult.TransactionID
+		paymentEntity.GatewayOrderID = gatewayOrderID
+		paymentEntity.ExpiredAt = deadline
+
+		if paymentEntity.ID == "" {
+			return u.paymentRepo.Create(ctx, paymentEntity)
+		}
+		return u.paymentRepo.Update(ctx, paymentEntity)
+	})
+
+	if err != nil {
+		u.log.Errorf("Failed to save regenerated payment for order %s: %v", order.OrderNumber, err)
+		if cancelErr := u.paymentGateway.CancelTransaction(ctx, gatewayOrderID); cancelErr != nil {
+			u.log.Warnf("Failed to cancel unused charge %s: %v", gatewayOrderID, cancelErr)
+		}
+		return nil, err
 	}
 
-	return nil
+	u.log.Infof("Payment regenerated for order %s: bank=%s, VA=%s", order.OrderNumber, request.BankCode, paymentResult.VANumber)
+	u.notifyOrderCreated(ctx, order, paymentEntity)
+
+	return u.buildOrderResponse(order, paymentEntity, order.ProductVariant, order.ShippingDetail), nil
+}
+
+// detachCharge points the payment at the next gateway order id before the current charge is
+// cancelled, so the cancel notification of the old charge cannot cancel the order. If the old
+// charge cannot be cancelled it is attached again.
+func (u *OrderUsecase) detachCharge(ctx context.Context, order *entity.Order, nextGatewayOrderID string) error {
+	var previous entity.Payment
+	err := u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		paymentEntity, err := u.paymentRepo.FindByOrderIDForUpdate(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		if paymentEntity.Status != entity.PaymentStatusPending {
+			return errorx.NewBadRequestError("Order is not awaiting payment")
+		}
+
+		previous = *paymentEntity
+		paymentEntity.GatewayOrderID = nextGatewayOrderID
+		paymentEntity.GatewayTransactionID = ""
+		paymentEntity.VANumber = ""
+		paymentEntity.BillKey = ""
+		paymentEntity.BillerCode = ""
+		return u.paymentRepo.Update(ctx, paymentEntity)
+	})
+	if err != nil {
+		return err
+	}
+
+	// A failed charge has nothing to cancel at the gateway
+	if previous.GatewayTransactionID == "" {
+		return nil
+	}
+
+	cancelErr := u.paymentGateway.CancelTransaction(ctx, previous.GatewayOrderID)
+	if cancelErr == nil {
+		return nil
+	}
+
+	u.log.Warnf("Failed to cancel charge %s for order %s: %v", previous.GatewayOrderID, order.OrderNumber, cancelErr)
+
+	err = u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		paymentEntity, err := u.paymentRepo.FindByOrderIDForUpdate(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		if paymentEntity.GatewayOrderID != nextGatewayOrderID {
+			return nil
+		}
+		return u.paymentRepo.Update(ctx, &previous)
+	})
+	if err != nil {
+		u.log.Errorf("Failed to restore charge %s for order %s: %v", previous.GatewayOrderID, order.OrderNumber, err)
+		return err
+	}
+
+	// The usual reason a pending VA cannot be cancelled is that it was just paid
+	if status, err := u.paymentGateway.GetTransactionStatus(ctx, previous.GatewayOrderID); err == nil && paymentOutcome(status.Status) == paymentOutcomePaid {
+		if _, err := u.transitionPayment(ctx, order.ID, status.Status, status.PaidAt); err != nil {
+			u.log.Errorf("Failed to settle order %s: %v", order.OrderNumber, err)
+		}
+		return errorx.NewBadRequestError("Order has already been paid")
+	}
+
+	return errorx.NewInternalError("Failed to cancel the current payment. Please try again.")
+}
+
+// paymentDeadline is when the order stops accepting payment: the end of the buyer session for
+// group buy orders and the reservation window for direct orders.
+func (u *OrderUsecase) paymentDeadline(ctx context.Context, order *entity.Order) (time.Time, error) {
+	if order.BuyerGroupSessionID != nil {
+		session, err := u.buyerSessionRepo.GetSessionByID(ctx, *order.BuyerGroupSessionID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return session.ExpiresAt, nil
+	}
+
+	return order.CreatedAt.Add(directOrderPaymentWindow), nil
+}
+
+func (u *OrderUsecase) scheduleOrderExpiration(order *entity.Order, delay time.Duration) {
+	task, err := tasks.NewOrderExpirationTask(order.ID, order.OrderNumber, order.UserID)
+	if err != nil {
+		u.log.Warnf("Failed to create expiration task for order %s: %v", order.OrderNumber, err)
+		return
+	}
+
+	if _, err := u.asynqClient.Enqueue(task, asynq.ProcessIn(delay), asynq.Queue("critical")); err != nil {
+		u.log.Warnf("Failed to schedule expiration task for order %s: %v", order.OrderNumber, err)
+	}
 }
 
 func (u *OrderUsecase) ExpireOrder(ctx context.Context, orderID string) error {
-	order, err := u.orderRepo.FindByID(orderID)
+	return u.closeUnpaidOrder(ctx, orderID, entity.PaymentStatusExpire)
+}
+
+// closeUnpaidOrder expires or cancels an order that is still waiting for payment. The gateway
+// is asked first, an order that was paid without a notification is settled instead and one
+// the gateway has no transaction for is closed as unpaid.
+func (u *OrderUsecase) closeUnpaidOrder(ctx context.Context, orderID string, transactionStatus string) error {
+	closedStatus := entity.OrderStatusExpired
+	if transactionStatus == entity.PaymentStatusCancel {
+		closedStatus = entity.OrderStatusCancelled
+	}
+
+	order, err := u.orderRepo.FindByID(ctx, orderID)
 	if err != nil {
 		return err
 	}
 
 	if order.Status != entity.OrderStatusPendingPayment {
-		u.log.Infof("Order %s is not pending, skipping expiration", order.OrderNumber)
+		u.log.Infof("Order %s is not pending, skipping %s", order.OrderNumber, closedStatus)
 		return nil
 	}
 
-	order.Status = entity.OrderStatusExpired
-	if err := u.orderRepo.Update(order); err != nil {
+	paymentEntity, err := u.paymentRepo.FindByOrderID(ctx, order.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return err
 	}
 
-	if payment, err := u.paymentRepo.FindByOrderID(order.ID); err == nil {
-		payment.Status = entity.PaymentStatusExpire
-		u.paymentRepo.Update(payment)
+	if paymentEntity == nil {
+		if err := u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			locked, err := u.orderRepo.FindByIDForUpdate(ctx, order.ID)
+			if err != nil {
+				return err
+			}
+			if locked.Status != entity.OrderStatusPendingPayment {
+				return nil
+			}
+			if err := u.orderRepo.UpdateStatus(ctx, order.ID, closedStatus); err != nil {
+				return err
+			}
+			order.Status = closedStatus
+			return u.releaseOrderStock(ctx, order)
+		}); err != nil {
+			return err
+		}
+
+		if order.Status == entity.OrderStatusExpired {
+			u.notifier.NotifyUser(ctx, order.UserID, mailer.TemplateOrderExpired, orderNotificationData(order, nil))
+		}
+
+		u.log.Infof("Order %s: %s", closedStatus, order.OrderNumber)
+		return nil
+	}
+
+	// The webhook may never have arrived, so ask the gateway before expiring a paid order
+	hasCharge := paymentEntity.GatewayTransactionID != ""
+	if hasCharge {
+		status, err := u.paymentGateway.GetTransactionStatus(ctx, paymentEntity.GatewayOrderID)
+		switch {
+		case errors.Is(err, payment.ErrTransactionNotFound):
+			// nothing was ever charged, the order is unpaid
+			u.log.Warnf("Gateway has no transaction for order %s, closing it as unpaid", order.OrderNumber)
+			hasCharge = false
+		case err != nil:
+			// the task is retried, the gateway may answer next time
+			u.log.Errorf("Failed to check gateway status for order %s: %v", order.OrderNumber, err)
+			return err
+		case paymentOutcome(status.Status) == paymentOutcomePaid:
+			u.log.Warnf("Order %s was paid at the gateway but no notification arrived, settling instead of closing it", order.OrderNumber)
+			_, err := u.transitionPayment(ctx, order.ID, status.Status, status.PaidAt)
+			return err
+		}
+	}
+
+	applied, err := u.transitionPayment(ctx, order.ID, transactionStatus, nil)
+	if err != nil {
+		return err
 	}
 
-	u.releaseStock(order.ProductVariantID, order.Quantity)
+	if !applied {
+		u.log.Infof("Payment for order %s changed before it was closed, skipping", order.OrderNumber)
+		return nil
+	}
 
-	u.paymentGateway.CancelTransaction(ctx, order.OrderNumber)
+	if hasCharge {
+		if err := u.paymentGateway.CancelTransaction(ctx, paymentEntity.GatewayOrderID); err != nil {
+			u.log.Warnf("Failed to cancel gateway transaction for order %s: %v", order.OrderNumber, err)
+		}
+	}
 
-	u.log.Infof("Order expired: %s", order.OrderNumber)
+	u.log.Infof("Order %s: %s", closedStatus, order.OrderNumber)
 	return nil
 }
 
-func (u *OrderUsecase) generateOrderNumber() string {
-	now := time.Now()
-	randomSuffix := uuid.New().String()[:8]
-	return fmt.Sprintf("ORD-%s-%s", now.Format("20060102"), randomSuffix)
+// ReconcilePayments polls the gateway for every pending payment and applies the
+// transition the webhook would have applied. It also re-checks recently closed
+// payments and reports the ones the gateway disagrees with for manual review.
+func (u *OrderUsecase) ReconcilePayments(ctx context.Context) error {
+	pending, err := u.paymentRepo.FindPending(ctx)
+	if err != nil {
+		u.log.Errorf("[Reconciliation] Failed to load pending payments: %v", err)
+		return err
+	}
+
+	for i := range pending {
+		u.reconcilePendingPayment(ctx, &pending[i])
+	}
+
+	closed, err := u.paymentRepo.FindClosedSince(ctx, time.Now().Add(-reconcileLookback))
+	if err != nil {
+		u.log.Errorf("[Reconciliation] Failed to load closed payments: %v", err)
+		return err
+	}
+
+	for i := range closed {
+		u.reconcileClosedPayment(ctx, &closed[i])
+	}
+
+	u.log.Infof("[Reconciliation] Checked %d pending and %d closed payments", len(pending), len(closed))
+	return nil
+}
+
+func (u *OrderUsecase) GetPaymentDiscrepancies(ctx context.Context) ([]entity.PaymentDiscrepancy, error) {
+	return u.discrepancyRepo.FindUnresolved(ctx)
 }
 
-func (u *OrderUsecase) releaseStock(variantID string, quantity int) {
-	stock, err := u.stockRepo.GetStockByVariantID(context.Background(), variantID)
+func (u *OrderUsecase) reconcilePendingPayment(ctx context.Context, paymentEntity *entity.Payment) {
+	order := paymentEntity.Order
+	if order == nil || paymentEntity.GatewayTransactionID == "" {
+		return
+	}
+
+	status, err := u.paymentGateway.GetTransactionStatus(ctx, paymentEntity.GatewayOrderID)
 	if err != nil {
-		u.log.Warnf("Failed to get stock for release: %v", err)
+		u.log.Warnf("[Reconciliation] Failed to check status for order %s: %v", order.OrderNumber, err)
 		return
 	}
 
-	stock.ReservedStock -= quantity
-	if stock.ReservedStock < 0 {
-		stock.ReservedStock = 0
+	outcome := paymentOutcome(status.Status)
+	if outcome == paymentOutcomeUnknown || outcome == paymentOutcomePending {
+		return
 	}
 
-	if err := u.stockRepo.UpdateStock(context.Background(), stock, variantID); err != nil {
-		u.log.Warnf("Failed to release stock: %v", err)
+	if outcome == paymentOutcomePaid && status.GrossAmount != 0 && status.GrossAmount != paymentEntity.Amount {
+		u.reportDiscrepancy(ctx, order, paymentEntity, status.Status, fmt.Sprintf("gateway amount %.2f does not match payment amount %.2f", status.GrossAmount, paymentEntity.Amount))
+		return
+	}
+
+	if order.Status != entity.OrderStatusPendingPayment {
+		if outcome == paymentOutcomePaid {
+			u.reportDiscrepancy(ctx, order, paymentEntity, status.Status, fmt.Sprintf("gateway reports payment on %s order", order.Status))
+		}
+		return
 	}
+
+	u.log.Infof("[Reconciliation] Applying missed %s status for order %s", status.Status, order.OrderNumber)
+	if _, err := u.transitionPayment(ctx, order.ID, status.Status, status.PaidAt); err != nil {
+		u.log.Errorf("[Reconciliation] Failed to apply status for order %s: %v", order.OrderNumber, err)
+	}
+}
+
+func (u *OrderUsecase) reconcileClosedPayment(ctx context.Context, paymentEntity *entity.Payment) {
+	order := paymentEntity.Order
+	if order == nil || paymentEntity.GatewayTransactionID == "" {
+		return
+	}
+
+	status, err := u.paymentGateway.GetTransactionStatus(ctx, paymentEntity.GatewayOrderID)
+	if err != nil {
+		u.log.Warnf("[Reconciliation] Failed to check status for order %s: %v", order.OrderNumber, err)
+		return
+	}
+
+	gatewayOutcome := paymentOutcome(status.Status)
+	if gatewayOutcome == paymentOutcomeUnknown || gatewayOutcome == paymentOutcome(paymentEntity.Status) {
+		return
+	}
+
+	switch gatewayOutcome {
+	case paymentOutcomePaid:
+		u.reportDiscrepancy(ctx, order, paymentEntity, status.Status, fmt.Sprintf("gateway reports settlement on %s order", order.Status))
+	case paymentOutcomePending:
+		u.reportDiscrepancy(ctx, order, paymentEntity, status.Status, "gateway transaction is still payable after the order was closed")
+	default:
+		u.reportDiscrepancy(ctx, order, paymentEntity, status.Status, fmt.Sprintf("gateway reports %s but payment is %s", status.Status, paymentEntity.Status))
+	}
+}
+
+func (u *OrderUsecase) reportDiscrepancy(ctx context.Context, order *entity.Order, paymentEntity *entity.Payment, gatewayStatus, reason string) {
+	u.log.Errorf("[Reconciliation] Payment mismatch for order %s: %s", order.OrderNumber, reason)
+
+	if err := u.discrepancyRepo.Create(ctx, &entity.PaymentDiscrepancy{
+		PaymentID:     paymentEntity.ID,
+		OrderID:       order.ID,
+		OrderStatus:   order.Status,
+		LocalStatus:   paymentEntity.Status,
+		GatewayStatus: gatewayStatus,
+		Reason:        reason,
+	}); err != nil {
+		u.log.Errorf("[Reconciliation] Failed to record discrepancy for order %s: %v", order.OrderNumber, err)
+	}
+}
+
+func (u *OrderUsecase) applyPaymentEvent(ctx context.Context, event *entity.PaymentEvent) (string, string, error) {
+	var notification dto.MidtransNotification
+	if err := json.Unmarshal([]byte(event.Payload), &notification); err != nil {
+		return "", "", fmt.Errorf("failed to unmarshal payment event payload: %w", err)
+	}
+
+	// A regenerated VA gets a new gateway order id, notifications for the old charge find nothing
+	paymentEntity, err := u.paymentRepo.FindByGatewayOrderID(ctx, event.OrderNumber)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.PaymentEventStatusIgnored, "no payment for this charge", nil
+		}
+		return "", "", err
+	}
+	order := paymentEntity.Order
+
+	// a pending notification only confirms the charge exists, there is nothing to apply
+	if paymentOutcome(event.TransactionStatus) == paymentOutcomePending {
+		u.log.Infof("Payment still pending for order %s", order.OrderNumber)
+		return entity.PaymentEventStatusProcessed, "", nil
+	}
+
+	var paidAt *time.Time
+	if notification.SettlementTime != "" {
+		if t, err := time.Parse("2006-01-02 15:04:05", notification.SettlementTime); err == nil {
+			paidAt = &t
+		}
+	}
+
+	applied, err := u.transitionPayment(ctx, order.ID, event.TransactionStatus, paidAt)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !applied {
+		u.log.Infof("Ignoring %s notification for order %s, payment is no longer pending", event.TransactionStatus, order.OrderNumber)
+		return entity.PaymentEventStatusIgnored, "payment is no longer pending", nil
+	}
+
+	return entity.PaymentEventStatusProcessed, "", nil
+}
+
+func (u *OrderUsecase) markPaymentEventFailed(ctx context.Context, eventID string, cause error) {
+	event, err := u.eventRepo.FindByID(ctx, eventID)
+	if err != nil {
+		u.log.Errorf("Failed to load payment event %s: %v", eventID, err)
+		return
+	}
+
+	event.Status = entity.PaymentEventStatusFailed
+	event.Error = cause.Error()
+	event.Attempts++
+	if err := u.eventRepo.Update(ctx, event); err != nil {
+		u.log.Errorf("Failed to mark payment event %s as failed: %v", eventID, err)
+	}
+}
+
+func (u *OrderUsecase) enqueuePaymentEvent(event *entity.PaymentEvent) error {
+	task, err := tasks.NewPaymentEventTask(event.ID)
+	if err != nil {
+		return err
+	}
+
+	// The attempt count keeps the id unique once an earlier task for this event has been archived
+	taskID := fmt.Sprintf("payment-event:%s:%d", event.ID, event.Attempts)
+	_, err = u.asynqClient.Enqueue(task, asynq.Queue("critical"), asynq.TaskID(taskID), asynq.MaxRetry(10))
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		u.log.Errorf("Failed to enqueue payment event %s: %v", event.ID, err)
+		return err
+	}
+
+	return nil
+}
+
+// transitionPayment locks the order and its payment and applies a gateway status only while
+// the payment is still pending. A payment only ever leaves pending once, so duplicates and
+// out of order statuses (an expire after a settlement) are reported as not applied.
+func (u *OrderUsecase) transitionPayment(ctx context.Context, orderID, transactionStatus string, paidAt *time.Time) (bool, error) {
+	applied := false
+	var order *entity.Order
+	var paymentEntity *entity.Payment
+	err := u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		order, err = u.orderRepo.FindByIDForUpdate(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		paymentEntity, err = u.paymentRepo.FindByOrderIDForUpdate(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		if paymentEntity.Status != entity.PaymentStatusPending {
+			return nil
+		}
+
+		applied = paymentOutcome(transactionStatus) != paymentOutcomePending
+		return u.applyPaymentStatus(ctx, order, paymentEntity, transactionStatus, paidAt)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if applied {
+		if order.Status == entity.OrderStatusPaid && order.BuyerGroupSessionID != nil {
+			u.groupBuyOrderPaid(ctx, order)
+		}
+
+		data := orderNotificationData(order, paymentEntity)
+		switch order.Status {
+		case entity.OrderStatusPaid:
+			u.notifier.NotifyUser(ctx, order.UserID, mailer.TemplateOrderPaid, data)
+		case entity.OrderStatusExpired:
+			u.notifier.NotifyUser(ctx, order.UserID, mailer.TemplateOrderExpired, data)
+		}
+	}
+
+	return applied, nil
+}
+
+// applyPaymentStatus moves an order and its payment to the state implied by a gateway
+// transaction status. Callers go through transitionPayment so the rows are locked and
+// a missed notification ends in the same state as a delivered one.
+func (u *OrderUsecase) applyPaymentStatus(ctx context.Context, order *entity.Order, paymentEntity *entity.Payment, transactionStatus string, paidAt *time.Time) error {
+	switch transactionStatus {
+	case "settlement", "capture":
+		if paidAt == nil {
+			now := time.Now()
+			paidAt = &now
+		}
+		paymentEntity.Status = entity.PaymentStatusSettlement
+		paymentEntity.PaidAt = paidAt
+		if err := u.paymentRepo.Update(ctx, paymentEntity); err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+
+		if err := u.orderRepo.UpdateStatus(ctx, order.ID, entity.OrderStatusPaid); err != nil {
+			return fmt.Errorf("failed to update order: %w", err)
+		}
+		order.Status = entity.OrderStatusPaid
+
+		if err := u.deductStockOnPayment(ctx, order.ProductVariantID, order.Quantity); err != nil {
+			return fmt.Errorf("failed to deduct stock: %w", err)
+		}
+
+		// a paid member keeps its units claimed after the buyer session expires
+		if order.BuyerGroupSessionID != nil {
+			if err := u.memberRepo.UpdateStatusByUser(ctx, *order.BuyerGroupSessionID, order.UserID, "paid"); err != nil {
+				return fmt.Errorf("failed to update group buy member: %w", err)
+			}
+		}
+
+		u.log.Infof("Payment settled for order: %s", order.OrderNumber)
+
+	case "pending":
+		// Still waiting for payment
+		u.log.Infof("Payment pending for order: %s", order.OrderNumber)
+
+	case "expire":
+		if err := u.paymentRepo.UpdateStatus(ctx, paymentEntity.ID, entity.PaymentStatusExpire); err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+		paymentEntity.Status = entity.PaymentStatusExpire
+
+		if err := u.orderRepo.UpdateStatus(ctx, order.ID, entity.OrderStatusExpired); err != nil {
+			return fmt.Errorf("failed to update order: %w", err)
+		}
+		order.Status = entity.OrderStatusExpired
+
+		// Release reserved stock
+		if err := u.releaseOrderStock(ctx, order); err != nil {
+			return err
+		}
+
+		u.log.Infof("Payment expired for order: %s", order.OrderNumber)
+
+	case "cancel", "deny":
+		if err := u.paymentRepo.UpdateStatus(ctx, paymentEntity.ID, entity.PaymentStatusCancel); err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+		paymentEntity.Status = entity.PaymentStatusCancel
+
+		if err := u.orderRepo.UpdateStatus(ctx, order.ID, entity.OrderStatusCancelled); err != nil {
+			return fmt.Errorf("failed to update order: %w", err)
+		}
+		order.Status = entity.OrderStatusCancelled
+
+		if err := u.releaseOrderStock(ctx, order); err != nil {
+			return err
+		}
+
+		u.log.Infof("Payment cancelled/denied for order: %s", order.OrderNumber)
+	}
+
+	return nil
+}
+
+func (u *OrderUsecase) SettleGroupBuyDiscounts(ctx context.Context, buyerSessionID string) error {
+	session, err := u.buyerSessionRepo.GetSessionByID(ctx, buyerSessionID)
+	if err != nil {
+		return err
+	}
+
+	tiers, err := u.tierRepo.FindBySessionID(ctx, session.GroupBuySessionID)
+	if err != nil {
+		return err
+	}
+	finalTier := entity.ApplicableTier(tiers, session.CurrentParticipants)
+	if finalTier == nil {
+		return nil
+	}
+
+	orders, err := u.orderRepo.FindByBuyerGroupSessionID(ctx, buyerSessionID)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if err := u.settleGroupBuyOrder(ctx, order.ID, finalTier); err != nil {
+			u.log.Errorf("Failed to settle group buy discount for order %s: %v", order.OrderNumber, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// groupBuyOrderPaid tells the group about the payment. An order paid after its buyer
+// session closed missed the settlement, so it is settled here.
+func (u *OrderUsecase) groupBuyOrderPaid(ctx context.Context, order *entity.Order) {
+	session, err := u.buyerSessionRepo.GetSessionByID(ctx, *order.BuyerGroupSessionID)
+	if err != nil {
+		u.log.Errorf("Failed to load buyer session of order %s: %v", order.OrderNumber, err)
+		return
+	}
+
+	u.events.Publish(ctx, session.GroupBuySessionID, session.ID, GroupBuyEventPaymentReceived, map[string]any{
+		"user_id":  order.UserID,
+		"quantity": order.Quantity,
+	})
+
+	if session.Status == "open" {
+		return
+	}
+
+	tiers, err := u.tierRepo.FindBySessionID(ctx, session.GroupBuySessionID)
+	if err != nil {
+		u.log.Errorf("Failed to load tiers of order %s: %v", order.OrderNumber, err)
+		return
+	}
+	if finalTier := entity.ApplicableTier(tiers, session.CurrentParticipants); finalTier != nil {
+		if err := u.settleGroupBuyOrder(ctx, order.ID, finalTier); err != nil {
+			u.log.Errorf("Failed to settle group buy discount for order %s: %v", order.OrderNumber, err)
+		}
+	}
+}
+
+func (u *OrderUsecase) ConfirmGroupBuyOrders(ctx context.Context, buyerSessionID string) error {
+	orders, err := u.closeGroupBuyOrders(ctx, buyerSessionID)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		err := u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			locked, err := u.orderRepo.FindByIDForUpdate(ctx, order.ID)
+			if err != nil {
+				return err
+			}
+			if locked.Status != entity.OrderStatusPaid {
+				return nil
+			}
+			return u.orderRepo.UpdateStatus(ctx, locked.ID, entity.OrderStatusProcessing)
+		})
+		if err != nil {
+			u.log.Errorf("Failed to confirm group buy order %s: %v", order.OrderNumber, err)
+			return err
+		}
+	}
+
+	return u.SettleGroupBuyDiscounts(ctx, buyerSessionID)
+}
+
+// RefundGroupBuyOrders credits the wallet with what the buyer paid, less the discount already
+// paid out to the wallet. Midtrans cannot refund bank transfers, so the wallet is the only way
+// back. The order is locked and marked refunded in the same transaction as the credit.
+func (u *OrderUsecase) RefundGroupBuyOrders(ctx context.Context, buyerSessionID string) error {
+	orders, err := u.closeGroupBuyOrders(ctx, buyerSessionID)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		var refunded *entity.Order
+		var amount float64
+		err := u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+			locked, err := u.orderRepo.FindByIDForUpdate(ctx, order.ID)
+			if err != nil {
+				return err
+			}
+			if locked.Status != entity.OrderStatusPaid && locked.Status != entity.OrderStatusProcessing {
+				return nil
+			}
+
+			granted, err := u.adjustmentRepo.SumByOrderID(ctx, locked.ID, entity.AdjustmentSourceGroupBuy)
+			if err != nil {
+				return err
+			}
+
+			// granted also holds the discount taken off the total, only the rest went to the wallet
+			amount = locked.TotalAmount + locked.DiscountAmount + granted
+			if amount > 0 {
+				if err := u.walletRepo.Credit(ctx, locked.UserID, amount); err != nil {
+					return err
+				}
+			}
+
+			if err := u.orderRepo.UpdateStatus(ctx, locked.ID, entity.OrderStatusRefunded); err != nil {
+				return err
+			}
+			locked.Status = entity.OrderStatusRefunded
+			refunded = locked
+			return nil
+		})
+		if err != nil {
+			u.log.Errorf("Failed to refund group buy order %s: %v", order.OrderNumber, err)
+			return err
+		}
+
+		if refunded != nil {
+			u.log.Infof("Refunded %.0f to wallet for order %s", amount, refunded.OrderNumber)
+			data := orderNotificationData(refunded, nil)
+			data["Amount"] = formatRupiah(amount)
+			u.notifier.NotifyUser(ctx, refunded.UserID, mailer.TemplateOrderRefunded, data)
+		}
+	}
+
+	return nil
+}
+
+func (u *OrderUsecase) CancelGroupBuyMemberOrders(ctx context.Context, buyerSessionID string, userID int64) error {
+	orders, err := u.orderRepo.FindByBuyerGroupSessionID(ctx, buyerSessionID)
+	if err != nil {
+		return err
+	}
+
+	var pending []entity.Order
+	for _, order := range orders {
+		if order.UserID != userID {
+			continue
+		}
+		switch order.Status {
+		case entity.OrderStatusPendingPayment:
+			pending = append(pending, order)
+		case entity.OrderStatusPaid, entity.OrderStatusProcessing, entity.OrderStatusShipped, entity.OrderStatusDelivered:
+			return errorx.ErrMemberAlreadyPaid
+		}
+	}
+
+	for _, order := range pending {
+		if err := u.closeUnpaidOrder(ctx, order.ID, entity.PaymentStatusCancel); err != nil {
+			u.log.Errorf("Failed to cancel group buy order %s: %v", order.OrderNumber, err)
+			return err
+		}
+
+		// the gateway may report the order paid instead
+		closed, err := u.orderRepo.FindByID(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		if closed.Status == entity.OrderStatusPaid {
+			return errorx.ErrMemberAlreadyPaid
+		}
+	}
+
+	return nil
+}
+
+// closeGroupBuyOrders cancels the orders of a buyer session that are still waiting for
+// payment and returns all of its orders
+func (u *OrderUsecase) closeGroupBuyOrders(ctx context.Context, buyerSessionID string) ([]entity.Order, error) {
+	orders, err := u.orderRepo.FindByBuyerGroupSessionID(ctx, buyerSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		if order.Status != entity.OrderStatusPendingPayment {
+			continue
+		}
+		if err := u.closeUnpaidOrder(ctx, order.ID, entity.PaymentStatusCancel); err != nil {
+			u.log.Errorf("Failed to cancel group buy order %s: %v", order.OrderNumber, err)
+			return nil, err
+		}
+	}
+
+	return orders, nil
+}
+
+// settleGroupBuyOrder credits the wallet with the difference between the discount
+// of the final tier and the group buy discount the order already got. The order
+// row is locked and the payout is recorded as an adjustment, so running it again
+// finds nothing left to pay.
+func (u *OrderUsecase) settleGroupBuyOrder(ctx context.Context, orderID string, finalTier *entity.GroupBuyTier) error {
+	return u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		order, err := u.orderRepo.FindByIDForUpdate(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		switch order.Status {
+		case entity.OrderStatusPaid, entity.OrderStatusProcessing, entity.OrderStatusShipped, entity.OrderStatusDelivered:
+		default:
+			return nil
+		}
+
+		granted, err := u.adjustmentRepo.SumByOrderID(ctx, order.ID, entity.AdjustmentSourceGroupBuy)
+		if err != nil {
+			return err
+		}
+
+		// adjustments are negative, a smaller final discount is not clawed back
+		owed := groupBuyDiscount(order.Subtotal, finalTier) + granted
+		if owed < 1 {
+			return nil
+		}
+
+		if err := u.adjustmentRepo.Create(ctx, &entity.OrderAdjustment{
+			OrderID:     order.ID,
+			Description: fmt.Sprintf("Group buy final tier %.0f%% settled to wallet", finalTier.DiscountPercentage),
+			Amount:      -owed,
+			SourceType:  entity.AdjustmentSourceGroupBuy,
+			SourceID:    finalTier.ID,
+		}); err != nil {
+			return err
+		}
+
+		if err := u.walletRepo.Credit(ctx, order.UserID, owed); err != nil {
+			return err
+		}
+
+		u.log.Infof("Settled group buy discount of %.0f to wallet for order %s", owed, order.OrderNumber)
+		return nil
+	})
+}
+
+// groupBuyDiscount is the tier's discount on subtotal in whole rupiah
+func groupBuyDiscount(subtotal float64, tier *entity.GroupBuyTier) float64 {
+	return math.Round(subtotal * tier.DiscountPercentage / 100)
+}
+
+// UpdateOrderStatusBySeller moves a paid order of the seller forward to shipped and then
+// delivered, and emails the buyer at each step.
+func (u *OrderUsecase) UpdateOrderStatusBySeller(ctx context.Context, sellerID int64, orderID string, request *dto.UpdateOrderStatusRequest) (*dto.OrderResponse, error) {
+	allowedFrom := map[string][]string{
+		entity.OrderStatusShipped:   {entity.OrderStatusPaid, entity.OrderStatusProcessing},
+		entity.OrderStatusDelivered: {entity.OrderStatusShipped},
+	}
+
+	from, ok := allowedFrom[request.Status]
+	if !ok {
+		return nil, errorx.NewBadRequestError("Status must be shipped or delivered")
+	}
+
+	var order *entity.Order
+	err := u.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		order, err = u.orderRepo.FindByIDForUpdate(ctx, orderID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errorx.NewNotFoundError("Order not found")
+			}
+			return err
+		}
+
+		if order.SellerID != sellerID {
+			return errorx.NewForbiddenError("Order not found")
+		}
+
+		valid := false
+		for _, status := range from {
+			if order.Status == status {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return errorx.NewBadRequestError(fmt.Sprintf("Cannot change order from %s to %s", order.Status, request.Status))
+		}
+
+		// a group buy order is confirmed once its group reaches the target, until then it may be refunded
+		if order.BuyerGroupSessionID != nil && order.Status == entity.OrderStatusPaid {
+			return errorx.NewBadRequestError("Group buy order cannot ship before the group buy succeeds")
+		}
+
+		if err := u.orderRepo.UpdateStatus(ctx, order.ID, request.Status); err != nil {
+			return err
+		}
+		order.Status = request.Status
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	templateID := mailer.TemplateOrderShipped
+	if order.Status == entity.OrderStatusDelivered {
+		templateID = mailer.TemplateOrderDelivered
+	}
+	u.notifier.NotifyUser(ctx, order.UserID, templateID, orderNotificationData(order, nil))
+
+	u.log.Infof("Order %s marked %s by seller %d", order.OrderNumber, order.Status, sellerID)
+
+	order, err = u.orderRepo.FindByID(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	return u.buildOrderResponse(order, order.Payment, order.ProductVariant, order.ShippingDetail), nil
+}
+
+// ensureEmailVerified blocks checkout until the buyer has confirmed their email address
+func (u *OrderUsecase) ensureEmailVerified(ctx context.Context, userID int64) error {
+	user, err := u.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errorx.NewNotFoundError("User not found")
+		}
+		return err
+	}
+
+	if !user.IsEmailVerified() {
+		return errorx.NewForbiddenError("Please verify your email address before checking out")
+	}
+	return nil
+}
+
+func (u *OrderUsecase) notifyOrderCreated(ctx context.Context, order *entity.Order, paymentEntity *entity.Payment) {
+	u.notifier.NotifyUser(ctx, order.UserID, mailer.TemplateOrderCreated, orderNotificationData(order, paymentEntity))
+}
+
+func orderNotificationData(order *entity.Order, paymentEntity *entity.Payment) map[string]any {
+	data := map[string]any{
+		"OrderNumber": order.OrderNumber,
+		"Amount":      formatRupiah(order.TotalAmount),
+	}
+
+	if paymentEntity != nil {
+		data["Bank"] = strings.ToUpper(paymentEntity.BankCode)
+		data["VANumber"] = paymentEntity.VANumber
+		data["BillKey"] = paymentEntity.BillKey
+		data["BillerCode"] = paymentEntity.BillerCode
+		data["ExpiresAt"] = paymentEntity.ExpiredAt.Format("02 Jan 2006 15:04 MST")
+	}
+
+	return data
+}
+
+func (u *OrderUsecase) generateOrderNumber() string {
+	now := time.Now()
+	randomSuffix := uuid.New().String()[:8]
+	return fmt.Sprintf("ORD-%s-%s", now.Format("20060102"), randomSuffix)
+}
+
+// releaseOrderStock gives back the reservation of an order that will not be paid. Group buy
+// orders reserve nothing of their own, their units stay with the campaign until it ends.
+func (u *OrderUsecase) releaseOrderStock(ctx context.Context, order *entity.Order) error {
+	if order.BuyerGroupSessionID != nil {
+		return nil
+	}
+	return u.releaseStock(ctx, order.ProductVariantID, order.Quantity)
+}
+
+func (u *OrderUsecase) releaseStock(ctx context.Context, variantID string, quantity int) error {
+	stock, err := u.stockRepo.GetStockByVariantID(ctx, variantID)
+	if err != nil {
+		u.log.Warnf("Failed to get stock for release: %v", err)
+		return fmt.Errorf("failed to get stock: %w", err)
+	}
+
+	stock.ReservedStock -= quantity
+	if stock.ReservedStock < 0 {
+		stock.ReservedStock = 0
+	}
+
+	if err := u.stockRepo.UpdateStock(ctx, stock, variantID); err != nil {
+		u.log.Warnf("Failed to release stock: %v", err)
+		return fmt.Errorf("failed to release stock: %w", err)
+	}
+
+	return nil
 }
 
 // deductStockOnPayment decrements current_stock and reserved_stock atomically with optimistic locking.
@@ -517,6 +1671,8 @@ func (u *OrderUsecase) buildOrderResponse(order *entity.Order, payment *entity.P
 		Quantity:       order.Quantity,
 		PriceAtOrder:   order.PriceAtOrder,
 		Subtotal:       order.Subtotal,
+		DiscountAmount: order.DiscountAmount,
+		Adjustments:    order.Adjustments,
 		DeliveryCharge: order.DeliveryCharge,
 		TotalAmount:    order.TotalAmount,
 		CreatedAt:      order.CreatedAt,
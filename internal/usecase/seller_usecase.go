@@ -51,6 +51,11 @@ func (s *SellerUsecase) RegisterSeller(ctx context.Context, request dto.SellerRe
 		return &entity.Seller{}, errorx.ErrUserNotFound
 	}
 
+	if !user.IsEmailVerified() {
+		s.log.Errorf("[SellerUsecase] Email of user %d is not verified", userID)
+		return &entity.Seller{}, errorx.ErrEmailNotVerified
+	}
+
 	checkSeller, err := s.repo.GetSeller(ctx, userID)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		s.log.Errorf("[SellerUsecase] Find seller error")
@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/febry3/gamingin/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+type TokenUsecaseContract interface {
+	// PurgeRevokedTokens deletes revoked refresh tokens that are past the refresh TTL
+	PurgeRevokedTokens(ctx context.Context) error
+}
+
+type TokenUsecase struct {
+	token      repository.TokenRepository
+	refreshTTL time.Duration
+	log        *logrus.Logger
+}
+
+func NewTokenUsecase(token repository.TokenRepository, refreshTTL time.Duration, log *logrus.Logger) TokenUsecaseContract {
+	return &TokenUsecase{
+		token:      token,
+		refreshTTL: refreshTTL,
+		log:        log,
+	}
+}
+
+// PurgeRevokedTokens keeps the rows reuse detection looks up from growing without bound.
+// A token issued more than a refresh TTL ago has expired, presenting it fails either way.
+func (t *TokenUsecase) PurgeRevokedTokens(ctx context.Context) error {
+	deleted, err := t.token.DeleteRevokedBefore(ctx, time.Now().Add(-t.refreshTTL))
+	if err != nil {
+		t.log.Errorf("[TokenUsecase] Failed to purge revoked tokens: %v", err)
+		return err
+	}
+
+	t.log.Infof("[TokenUsecase] Purged %d revoked refresh tokens", deleted)
+	return nil
+}
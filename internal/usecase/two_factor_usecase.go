@@ -0,0 +1,348 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/febry3/gamingin/internal/dto"
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/errorx"
+	"github.com/febry3/gamingin/internal/helpers"
+	"github.com/febry3/gamingin/internal/infra/mailer"
+	"github.com/febry3/gamingin/internal/repository"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type TwoFactorUsecaseContract interface {
+	GetStatus(ctx context.Context, userID int64, role string) (*dto.TwoFactorStatusResponse, error)
+	// Enroll generates a new secret for the user, 2FA stays off until Enable
+	// receives a code from it
+	Enroll(ctx context.Context, userID int64) (*dto.TwoFactorEnrollResponse, error)
+	Enable(ctx context.Context, userID int64, request dto.TwoFactorCodeRequest) (*dto.TwoFactorRecoveryCodesResponse, error)
+	Disable(ctx context.Context, userID int64, role string, request dto.TwoFactorCodeRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, request dto.TwoFactorCodeRequest) (*dto.TwoFactorRecoveryCodesResponse, error)
+
+	IsEnabled(ctx context.Context, userID int64) (bool, error)
+	IsRequired(ctx context.Context, role string) (bool, error)
+	// VerifyCode checks a TOTP code of an enabled user, a code is accepted once
+	VerifyCode(ctx context.Context, userID int64, code string) (bool, error)
+	// VerifyRecoveryCode consumes one of the user's recovery codes
+	VerifyRecoveryCode(ctx context.Context, userID int64, code string) (bool, error)
+
+	GetPolicies(ctx context.Context) ([]entity.TwoFactorPolicy, error)
+	SetPolicy(ctx context.Context, role string, request dto.TwoFactorPolicyRequest) (*entity.TwoFactorPolicy, error)
+}
+
+const (
+	twoFactorIssuer        = "Gamingin"
+	twoFactorRecoveryCodes = 10
+)
+
+type TwoFactorUsecase struct {
+	twoFactor repository.TwoFactorRepository
+	user      repository.UserRepository
+	tx        repository.TxManager
+	notifier  NotificationUsecaseContract
+	log       *logrus.Logger
+	// secretKey encrypts the TOTP secrets at rest
+	secretKey []byte
+}
+
+func NewTwoFactorUsecase(twoFactor repository.TwoFactorRepository, user repository.UserRepository, tx repository.TxManager, notifier NotificationUsecaseContract, log *logrus.Logger, secretKey []byte) TwoFactorUsecaseContract {
+	return &TwoFactorUsecase{
+		twoFactor: twoFactor,
+		user:      user,
+		tx:        tx,
+		notifier:  notifier,
+		log:       log,
+		secretKey: secretKey,
+	}
+}
+
+func (t *TwoFactorUsecase) GetStatus(ctx context.Context, userID int64, role string) (*dto.TwoFactorStatusResponse, error) {
+	required, err := t.IsRequired(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &dto.TwoFactorStatusResponse{Required: required}
+
+	twoFactor, err := t.twoFactor.FindByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return status, nil
+		}
+		t.log.Errorf("[TwoFactorUsecase] Find Two Factor Error: %v", err.Error())
+		return nil, err
+	}
+
+	if twoFactor.IsEnabled() {
+		status.Enabled = true
+		status.EnabledAt = twoFactor.EnabledAt
+		status.RemainingRecoveryCodes, err = t.twoFactor.CountUnusedRecoveryCodes(ctx, userID)
+		if err != nil {
+			t.log.Errorf("[TwoFactorUsecase] Count Recovery Codes Error: %v", err.Error())
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+func (t *TwoFactorUsecase) Enroll(ctx context.Context, userID int64) (*dto.TwoFactorEnrollResponse, error) {
+	existing, err := t.twoFactor.FindByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.log.Errorf("[TwoFactorUsecase] Find Two Factor Error: %v", err.Error())
+		return nil, err
+	}
+	if err == nil && existing.IsEnabled() {
+		return nil, errorx.ErrTwoFactorAlreadyEnabled
+	}
+
+	user, err := t.user.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	secret, err := helpers.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := helpers.Encrypt(t.secretKey, secret)
+	if err != nil {
+		t.log.Errorf("[TwoFactorUsecase] Encrypt Secret Error: %v", err.Error())
+		return nil, err
+	}
+
+	if err := t.twoFactor.Save(ctx, &entity.UserTwoFactor{UserID: userID, Secret: encrypted}); err != nil {
+		t.log.Errorf("[TwoFactorUsecase] Save Two Factor Error: %v", err.Error())
+		return nil, err
+	}
+
+	return &dto.TwoFactorEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: helpers.TOTPProvisioningURI(twoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+func (t *TwoFactorUsecase) Enable(ctx context.Context, userID int64, request dto.TwoFactorCodeRequest) (*dto.TwoFactorRecoveryCodesResponse, error) {
+	if err := validator.New().Struct(request); err != nil {
+		return nil, err
+	}
+
+	twoFactor, err := t.twoFactor.FindByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.ErrTwoFactorNotEnrolled
+		}
+		t.log.Errorf("[TwoFactorUsecase] Find Two Factor Error: %v", err.Error())
+		return nil, err
+	}
+	if twoFactor.IsEnabled() {
+		return nil, errorx.ErrTwoFactorAlreadyEnabled
+	}
+
+	ok, err := t.checkCode(ctx, twoFactor, request.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errorx.ErrTwoFactorCodeInvalid
+	}
+
+	var codes []string
+	err = t.tx.WithTransaction(ctx, func(txCtx context.Context) error {
+		if err := t.twoFactor.Enable(txCtx, userID); err != nil {
+			return err
+		}
+		var err error
+		codes, err = t.replaceRecoveryCodes(txCtx, userID)
+		return err
+	})
+	if err != nil {
+		t.log.Errorf("[TwoFactorUsecase] Enable Two Factor Error: %v", err.Error())
+		return nil, err
+	}
+
+	t.notifier.NotifyUser(ctx, userID, mailer.TemplateTwoFactorChanged, map[string]any{"Action": "enabled"})
+	return &dto.TwoFactorRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (t *TwoFactorUsecase) Disable(ctx context.Context, userID int64, role string, request dto.TwoFactorCodeRequest) error {
+	if err := validator.New().Struct(request); err != nil {
+		return err
+	}
+
+	required, err := t.IsRequired(ctx, role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return errorx.ErrTwoFactorRequired
+	}
+
+	ok, err := t.VerifyCode(ctx, userID, request.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errorx.ErrTwoFactorCodeInvalid
+	}
+
+	if err := t.twoFactor.Delete(ctx, userID); err != nil {
+		t.log.Errorf("[TwoFactorUsecase] Delete Two Factor Error: %v", err.Error())
+		return err
+	}
+
+	t.notifier.NotifyUser(ctx, userID, mailer.TemplateTwoFactorChanged, map[string]any{"Action": "disabled"})
+	return nil
+}
+
+func (t *TwoFactorUsecase) RegenerateRecoveryCodes(ctx context.Context, userID int64, request dto.TwoFactorCodeRequest) (*dto.TwoFactorRecoveryCodesResponse, error) {
+	if err := validator.New().Struct(request); err != nil {
+		return nil, err
+	}
+
+	ok, err := t.VerifyCode(ctx, userID, request.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errorx.ErrTwoFactorCodeInvalid
+	}
+
+	codes, err := t.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		t.log.Errorf("[TwoFactorUsecase] Replace Recovery Codes Error: %v", err.Error())
+		return nil, err
+	}
+	return &dto.TwoFactorRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (t *TwoFactorUsecase) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	twoFactor, err := t.twoFactor.FindByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		t.log.Errorf("[TwoFactorUsecase] Find Two Factor Error: %v", err.Error())
+		return false, err
+	}
+	return twoFactor.IsEnabled(), nil
+}
+
+func (t *TwoFactorUsecase) IsRequired(ctx context.Context, role string) (bool, error) {
+	policy, err := t.twoFactor.FindPolicy(ctx, role)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		t.log.Errorf("[TwoFactorUsecase] Find Policy Error: %v", err.Error())
+		return false, err
+	}
+	return policy.Required, nil
+}
+
+func (t *TwoFactorUsecase) VerifyCode(ctx context.Context, userID int64, code string) (bool, error) {
+	twoFactor, err := t.twoFactor.FindByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, errorx.ErrTwoFactorNotEnabled
+		}
+		t.log.Errorf("[TwoFactorUsecase] Find Two Factor Error: %v", err.Error())
+		return false, err
+	}
+	if !twoFactor.IsEnabled() {
+		return false, errorx.ErrTwoFactorNotEnabled
+	}
+	return t.checkCode(ctx, twoFactor, code)
+}
+
+func (t *TwoFactorUsecase) VerifyRecoveryCode(ctx context.Context, userID int64, code string) (bool, error) {
+	used, err := t.twoFactor.UseRecoveryCode(ctx, userID, helpers.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		t.log.Errorf("[TwoFactorUsecase] Use Recovery Code Error: %v", err.Error())
+		return false, err
+	}
+	return used, nil
+}
+
+func (t *TwoFactorUsecase) GetPolicies(ctx context.Context) ([]entity.TwoFactorPolicy, error) {
+	policies, err := t.twoFactor.FindPolicies(ctx)
+	if err != nil {
+		t.log.Errorf("[TwoFactorUsecase] Find Policies Error: %v", err.Error())
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (t *TwoFactorUsecase) SetPolicy(ctx context.Context, role string, request dto.TwoFactorPolicyRequest) (*entity.TwoFactorPolicy, error) {
+	if err := validator.New().Struct(request); err != nil {
+		return nil, err
+	}
+	if role != "user" && role != "seller" && role != "admin" {
+		return nil, errorx.ErrRoleInvalid
+	}
+
+	policy := &entity.TwoFactorPolicy{Role: role, Required: *request.Required}
+	if err := t.twoFactor.SavePolicy(ctx, policy); err != nil {
+		t.log.Errorf("[TwoFactorUsecase] Save Policy Error: %v", err.Error())
+		return nil, err
+	}
+
+	t.log.WithFields(logrus.Fields{"event": "two_factor_policy_changed", "role": role, "required": policy.Required}).Info("[TwoFactorUsecase] Two factor policy changed")
+	return policy, nil
+}
+
+// checkCode validates code against the stored secret and burns its time step
+func (t *TwoFactorUsecase) checkCode(ctx context.Context, twoFactor entity.UserTwoFactor, code string) (bool, error) {
+	secret, err := helpers.Decrypt(t.secretKey, twoFactor.Secret)
+	if err != nil {
+		t.log.Errorf("[TwoFactorUsecase] Decrypt Secret of User %d Error: %v", twoFactor.UserID, err.Error())
+		return false, err
+	}
+
+	step, ok := helpers.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	fresh, err := t.twoFactor.UseStep(ctx, twoFactor.UserID, step)
+	if err != nil {
+		t.log.Errorf("[TwoFactorUsecase] Use Step Error: %v", err.Error())
+		return false, err
+	}
+	return fresh, nil
+}
+
+// replaceRecoveryCodes issues a new set of recovery codes and returns their plain
+// values, which are shown to the user once
+func (t *TwoFactorUsecase) replaceRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes := make([]string, 0, twoFactorRecoveryCodes)
+	hashes := make([]string, 0, twoFactorRecoveryCodes)
+	for i := 0; i < twoFactorRecoveryCodes; i++ {
+		raw, err := helpers.GenerateToken(5)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, helpers.HashToken(raw))
+	}
+
+	if err := t.twoFactor.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
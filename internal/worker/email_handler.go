@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/febry3/gamingin/internal/infra/mailer"
+	"github.com/febry3/gamingin/internal/worker/tasks"
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+)
+
+type EmailHandler struct {
+	mailer   mailer.Mailer
+	renderer *mailer.Renderer
+	log      *logrus.Logger
+}
+
+func NewEmailHandler(mailer mailer.Mailer, renderer *mailer.Renderer, log *logrus.Logger) *EmailHandler {
+	return &EmailHandler{
+		mailer:   mailer,
+		renderer: renderer,
+		log:      log,
+	}
+}
+
+func (h *EmailHandler) HandleEmailDelivery(ctx context.Context, t *asynq.Task) error {
+	var payload tasks.EmailDeliveryPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w: %w", err, asynq.SkipRetry)
+	}
+
+	message := mailer.Message{
+		To:      payload.Email,
+		Subject: payload.Subject,
+		Body:    payload.Body,
+	}
+
+	if payload.TemplateID != "" {
+		subject, body, err := h.renderer.Render(payload.TemplateID, payload.Data)
+		if err != nil {
+			// A broken template will not fix itself on retry
+			h.log.Errorf("Failed to render email %s for user %d: %v", payload.TemplateID, payload.UserID, err)
+			return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+		}
+		message.Subject = subject
+		message.Body = body
+	}
+
+	if err := h.mailer.Send(ctx, message); err != nil {
+		h.log.Errorf("Failed to send email to %s: %v", payload.Email, err)
+		return err
+	}
+
+	h.log.Infof("Email %s sent successfully to: %s", payload.TemplateID, payload.Email)
+	return nil
+}
@@ -9,21 +9,18 @@ import (
 	"github.com/febry3/gamingin/internal/worker/tasks"
 	"github.com/hibiken/asynq"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/mail.v2"
 )
 
 type GroupBuySessionHandler struct {
 	groupBuyUsecase usecase.GroupBuyUsecaseContract
-	asynqClient     *asynq.Client
-	email           *mail.Dialer
+	orderUsecase    usecase.OrderUsecaseContract
 	log             *logrus.Logger
 }
 
-func NewGroupBuySessionHandler(groupBuyUsecase usecase.GroupBuyUsecaseContract, asynqClient *asynq.Client, email *mail.Dialer, log *logrus.Logger) *GroupBuySessionHandler {
+func NewGroupBuySessionHandler(groupBuyUsecase usecase.GroupBuyUsecaseContract, orderUsecase usecase.OrderUsecaseContract, log *logrus.Logger) *GroupBuySessionHandler {
 	return &GroupBuySessionHandler{
 		groupBuyUsecase: groupBuyUsecase,
-		asynqClient:     asynqClient,
-		email:           email,
+		orderUsecase:    orderUsecase,
 		log:             log,
 	}
 }
@@ -42,59 +39,68 @@ func (h *GroupBuySessionHandler) HandleSessionEnd(ctx context.Context, t *asynq.
 		return fmt.Errorf("failed to end session: %w", err)
 	}
 
-	// Chain: Enqueue email notification after session ends successfully
-	emailTask, err := tasks.NewGroupBuySessionEndMailTask(tasks.GroupBuySessionEndMailPayload{
-		To:      "kadallaut96@gmail.com", // TODO: Get from participants
-		Subject: "Group Buy Session Ended",
-		Body:    fmt.Sprintf("Your group buy session %s has ended!", payload.SessionID),
-	})
-	if err != nil {
-		h.log.Errorf("failed to create email task: %v", err)
-	} else {
-		_, err = h.asynqClient.Enqueue(emailTask, asynq.Queue("low"))
-		if err != nil {
-			h.log.Errorf("failed to enqueue email task: %v", err)
-		} else {
-			h.log.Infof("Enqueued email notification for session %s", payload.SessionID)
-		}
-	}
-
 	return nil
 }
 
-func (h *GroupBuySessionHandler) HandleSessionEndMail(ctx context.Context, t *asynq.Task) error {
-	var payload tasks.GroupBuySessionEndMailPayload
+func (h *GroupBuySessionHandler) HandleBuyerSessionEnd(ctx context.Context, t *asynq.Task) error {
+	var payload tasks.BuyerGroupBuySessionEndPayload
 	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
 		return fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
-	m := mail.NewMessage()
-	m.SetHeader("From", h.email.Username)
-	m.SetHeader("To", payload.To)
-	m.SetHeader("Subject", payload.Subject)
-	m.SetBody("text/html", payload.Body)
+	expired, err := h.groupBuyUsecase.ExpireBuyerSession(ctx, payload.BuyerSessionID)
+	if err != nil {
+		h.log.Errorf("failed to change buyer session status: %v", err)
+		return fmt.Errorf("failed to change buyer session status: %w", err)
+	}
+	if !expired {
+		// the campaign ended first and already decided this session
+		h.log.Infof("Buyer session %s is no longer open, skipping", payload.BuyerSessionID)
+		return nil
+	}
 
-	if err := h.email.DialAndSend(m); err != nil {
-		h.log.Errorf("Failed to send email to %s: %v", payload.To, err)
-		return err
+	// the participant count is final now, pay out any tier reached after ordering
+	if err := h.orderUsecase.SettleGroupBuyDiscounts(ctx, payload.BuyerSessionID); err != nil {
+		h.log.Errorf("failed to settle group buy discounts: %v", err)
+		return fmt.Errorf("failed to settle group buy discounts: %w", err)
 	}
+	return nil
+}
 
-	h.log.Infof("Email sent successfully to: %s", payload.To)
+func (h *GroupBuySessionHandler) HandleMilestone(ctx context.Context, t *asynq.Task) error {
+	var payload tasks.GroupBuyMilestonePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
 
+	if err := h.groupBuyUsecase.NotifyMilestone(ctx, payload.BuyerSessionID, payload.Milestone, payload.Threshold); err != nil {
+		h.log.Errorf("failed to notify milestone: %v", err)
+		return fmt.Errorf("failed to notify milestone: %w", err)
+	}
 	return nil
 }
 
-func (h *GroupBuySessionHandler) HandleBuyerSessionEnd(ctx context.Context, t *asynq.Task) error {
-	var payload tasks.BuyerGroupBuySessionEndPayload
+func (h *GroupBuySessionHandler) HandleActivation(ctx context.Context, t *asynq.Task) error {
+	var payload tasks.GroupBuyActivationPayload
 	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
 		return fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
 
-	err := h.groupBuyUsecase.ChangeBuyerSessionStatus(ctx, payload.BuyerSessionID, "expired")
-	if err != nil {
-		h.log.Errorf("failed to change buyer session status: %v", err)
-		return fmt.Errorf("failed to change buyer session status: %w", err)
+	h.log.Infof("Processing group buy activation: SessionID=%s", payload.SessionID)
+
+	if err := h.groupBuyUsecase.ActivateSession(ctx, payload.SessionID); err != nil {
+		h.log.Errorf("failed to activate session: %v", err)
+		return fmt.Errorf("failed to activate session: %w", err)
 	}
 	return nil
 }
 
+func (h *GroupBuySessionHandler) HandleScheduleSweep(ctx context.Context, t *asynq.Task) error {
+	h.log.Info("Processing group buy schedule sweep")
+
+	if err := h.groupBuyUsecase.SweepSchedules(ctx); err != nil {
+		h.log.Errorf("failed to sweep group buy schedules: %v", err)
+		return err
+	}
+	return nil
+}
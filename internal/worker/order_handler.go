@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/febry3/gamingin/internal/entity"
 	"github.com/febry3/gamingin/internal/usecase"
 	"github.com/febry3/gamingin/internal/worker/tasks"
 	"github.com/hibiken/asynq"
@@ -13,18 +12,14 @@ import (
 )
 
 type OrderHandler struct {
-	orderUsecase      usecase.OrderUsecaseContract
-	groupBuyUsecase   usecase.GroupBuyUsecaseContract
-	userWalletUsecase usecase.UserWalletUsecaseContract
-	log               *logrus.Logger
+	orderUsecase usecase.OrderUsecaseContract
+	log          *logrus.Logger
 }
 
-func NewOrderHandler(orderUsecase usecase.OrderUsecaseContract, groupBuyUsecase usecase.GroupBuyUsecaseContract, userWallet usecase.UserWalletUsecaseContract, log *logrus.Logger) *OrderHandler {
+func NewOrderHandler(orderUsecase usecase.OrderUsecaseContract, log *logrus.Logger) *OrderHandler {
 	return &OrderHandler{
-		orderUsecase:      orderUsecase,
-		groupBuyUsecase:   groupBuyUsecase,
-		userWalletUsecase: userWallet,
-		log:               log,
+		orderUsecase: orderUsecase,
+		log:          log,
 	}
 }
 
@@ -41,30 +36,33 @@ func (h *OrderHandler) HandleOrderExpiration(ctx context.Context, task *asynq.Ta
 		return err
 	}
 
-	order, err := h.orderUsecase.GetOrderByID(ctx, payload.UserID, payload.OrderID)
-	if err != nil {
-		h.log.Errorf("Failed to get order %s: %v", payload.OrderNumber, err)
+	h.log.Infof("Successfully expired order: %s", payload.OrderNumber)
+	return nil
+}
+
+func (h *OrderHandler) HandlePaymentReconciliation(ctx context.Context, task *asynq.Task) error {
+	h.log.Info("Processing payment reconciliation")
+
+	if err := h.orderUsecase.ReconcilePayments(ctx); err != nil {
+		h.log.Errorf("Failed to reconcile payments: %v", err)
 		return err
 	}
 
-	if order.Status == "paid" {
-		var tier *entity.GroupBuyTier
-		if payload.TierID != "" {
-			var err error
-			if tier, err = h.groupBuyUsecase.GetBuyerGroupSessionTier(ctx, payload.TierID); err != nil {
-				h.log.Errorf("Failed to get group buy tier %s: %v", payload.TierID, err)
-				return err
-			}
-		}
-		if tier != nil {
-			err := h.userWalletUsecase.CreateOrUpdateUserWallet(ctx, payload.UserID, (float64(payload.PaidAmount)*tier.DiscountPercentage)/100)
-			if err != nil {
-				h.log.Errorf("Failed to create or update user wallet for order %s: %v", payload.OrderNumber, err)
-				return err
-			}
-		}
+	return nil
+}
+
+func (h *OrderHandler) HandlePaymentEvent(ctx context.Context, task *asynq.Task) error {
+	var payload tasks.PaymentEventPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payment event payload: %w", err)
+	}
+
+	h.log.Infof("Processing payment event: %s", payload.EventID)
+
+	if err := h.orderUsecase.ProcessPaymentEvent(ctx, payload.EventID); err != nil {
+		h.log.Errorf("Failed to process payment event %s: %v", payload.EventID, err)
+		return err
 	}
 
-	h.log.Infof("Successfully expired order: %s", payload.OrderNumber)
 	return nil
 }
@@ -8,8 +8,10 @@ import (
 
 const (
 	TypeGroupBuySessionEnd      = "groupbuy:session_end"
-	TypeGroupBuySessionEndMail  = "groupbuy:session_end:mail"
 	TypeBuyerGroupBuySessionEnd = "groupbuy:buyer_session_end"
+	TypeGroupBuyMilestone       = "groupbuy:milestone"
+	TypeGroupBuyActivation      = "groupbuy:activation"
+	TypeGroupBuyScheduleSweep   = "groupbuy:schedule_sweep"
 )
 
 type GroupBuySessionEndPayload struct {
@@ -18,14 +20,20 @@ type GroupBuySessionEndPayload struct {
 	SellerID         int64  `json:"seller_id"`
 }
 
+type GroupBuyActivationPayload struct {
+	SessionID string `json:"session_id"`
+}
+
 type BuyerGroupBuySessionEndPayload struct {
 	BuyerSessionID string `json:"session_id"`
 }
 
-type GroupBuySessionEndMailPayload struct {
-	To      string `json:"email"`
-	Subject string `json:"subject"`
-	Body    string `json:"body"`
+// GroupBuyMilestonePayload is a tier threshold a buyer session reached, or is one
+// member away from when Milestone is the near type
+type GroupBuyMilestonePayload struct {
+	BuyerSessionID string `json:"session_id"`
+	Milestone      string `json:"milestone"`
+	Threshold      int    `json:"threshold"`
 }
 
 func NewGroupBuySessionEndTask(payload GroupBuySessionEndPayload) (*asynq.Task, error) {
@@ -36,18 +44,32 @@ func NewGroupBuySessionEndTask(payload GroupBuySessionEndPayload) (*asynq.Task,
 	return asynq.NewTask(TypeGroupBuySessionEnd, data), nil
 }
 
-func NewGroupBuySessionEndMailTask(payload GroupBuySessionEndMailPayload) (*asynq.Task, error) {
+func NewBuyerGrupBuySessionEndTask(payload BuyerGroupBuySessionEndPayload) (*asynq.Task, error) {
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
-	return asynq.NewTask(TypeGroupBuySessionEndMail, data), nil
+	return asynq.NewTask(TypeBuyerGroupBuySessionEnd, data), nil
 }
 
-func NewBuyerGrupBuySessionEndTask(payload BuyerGroupBuySessionEndPayload) (*asynq.Task, error) {
+func NewGroupBuyMilestoneTask(payload GroupBuyMilestonePayload) (*asynq.Task, error) {
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
-	return asynq.NewTask(TypeBuyerGroupBuySessionEnd, data), nil
+	return asynq.NewTask(TypeGroupBuyMilestone, data), nil
+}
+
+func NewGroupBuyActivationTask(payload GroupBuyActivationPayload) (*asynq.Task, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeGroupBuyActivation, data), nil
+}
+
+// NewGroupBuyScheduleSweepTask creates the periodic task that activates campaigns whose
+// activation task was lost and announces occurrences a schedule missed
+func NewGroupBuyScheduleSweepTask() (*asynq.Task, error) {
+	return asynq.NewTask(TypeGroupBuyScheduleSweep, nil), nil
 }
@@ -0,0 +1,23 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+)
+
+const TypePaymentEvent = "payment:process_event"
+
+type PaymentEventPayload struct {
+	EventID string `json:"event_id"`
+}
+
+// NewPaymentEventTask creates a task that applies a stored payment notification
+func NewPaymentEventTask(eventID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(PaymentEventPayload{EventID: eventID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payment event payload: %w", err)
+	}
+	return asynq.NewTask(TypePaymentEvent, payload), nil
+}
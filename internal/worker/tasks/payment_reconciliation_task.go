@@ -0,0 +1,13 @@
+package tasks
+
+import (
+	"github.com/hibiken/asynq"
+)
+
+const TypePaymentReconciliation = "payment:reconcile"
+
+// NewPaymentReconciliationTask creates the periodic task that polls the gateway
+// for payments whose webhook may have been missed
+func NewPaymentReconciliationTask() (*asynq.Task, error) {
+	return asynq.NewTask(TypePaymentReconciliation, nil), nil
+}
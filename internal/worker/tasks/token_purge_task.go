@@ -0,0 +1,13 @@
+package tasks
+
+import (
+	"github.com/hibiken/asynq"
+)
+
+const TypeRefreshTokenPurge = "auth:refresh_token_purge"
+
+// NewRefreshTokenPurgeTask creates the periodic task that deletes revoked refresh
+// tokens once they are past the refresh TTL
+func NewRefreshTokenPurgeTask() (*asynq.Task, error) {
+	return asynq.NewTask(TypeRefreshTokenPurge, nil), nil
+}
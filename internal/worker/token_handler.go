@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/febry3/gamingin/internal/usecase"
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+)
+
+type TokenHandler struct {
+	tokenUsecase usecase.TokenUsecaseContract
+	log          *logrus.Logger
+}
+
+func NewTokenHandler(tokenUsecase usecase.TokenUsecaseContract, log *logrus.Logger) *TokenHandler {
+	return &TokenHandler{
+		tokenUsecase: tokenUsecase,
+		log:          log,
+	}
+}
+
+func (h *TokenHandler) HandleRefreshTokenPurge(ctx context.Context, task *asynq.Task) error {
+	h.log.Info("Processing refresh token purge")
+
+	if err := h.tokenUsecase.PurgeRevokedTokens(ctx); err != nil {
+		h.log.Errorf("Failed to purge refresh tokens: %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/errorx"
+	"github.com/febry3/gamingin/internal/repository/pg"
+	"github.com/febry3/gamingin/internal/usecase"
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type silentNotifier struct {
+	usecase.NotificationUsecaseContract
+}
+
+func (silentNotifier) NotifyUser(ctx context.Context, userID int64, templateID string, data map[string]any) {
+}
+
+func (silentNotifier) NotifyUsers(ctx context.Context, userIDs []int64, templateID string, data map[string]any) {
+}
+
+func (silentNotifier) NotifyInbox(ctx context.Context, userIDs []int64, notification entity.Notification, templateID string, data map[string]any) {
+}
+
+type silentEvents struct {
+	usecase.GroupBuyEventUsecaseContract
+}
+
+func (silentEvents) Publish(ctx context.Context, campaignID string, buyerSessionID string, eventType string, data map[string]any) {
+}
+
+// recordingEnqueuer keeps the tasks instead of sending them to Redis
+type recordingEnqueuer struct {
+	mu    sync.Mutex
+	tasks []string
+}
+
+func (r *recordingEnqueuer) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks = append(r.tasks, task.Type())
+	return &asynq.TaskInfo{Type: task.Type()}, nil
+}
+
+// TestConcurrentJoinsDoNotOverfill needs a Postgres database in TEST_DATABASE_URL, CI
+// provides one. Every buyer joins the same session at once, one of them twice.
+func TestConcurrentJoinsDoNotOverfill(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if err := db.AutoMigrate(&entity.User{}, &entity.Address{}, &entity.Seller{}, &entity.Product{}, &entity.ProductVariant{}, &entity.ProductVariantStock{}, &entity.Category{}, &entity.ProductImage{}, &entity.GroupBuySession{}, &entity.GroupBuyTier{}, &entity.BuyerGroupSession{}, &entity.BuyerGroupMember{}, &entity.Order{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	const maxParticipants = 5
+	const buyers = 20
+	ctx := context.Background()
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	users := make([]entity.User, buyers+1)
+	for i := range users {
+		users[i] = entity.User{Email: fmt.Sprintf("join-%s-%d@test.local", suffix, i)}
+	}
+	mustCreate(t, db, &users)
+
+	seller := entity.Seller{UserID: users[0].ID, StoreName: "Join Test", StoreSlug: "join-test-" + suffix}
+	mustCreate(t, db, &seller)
+	product := entity.Product{SellerID: seller.ID, Title: "Join Test", Slug: "join-test-" + suffix}
+	mustCreate(t, db, &product)
+	variant := entity.ProductVariant{ProductID: product.ID, Sku: "JOIN-" + suffix, Name: "Join Test", Price: 100000}
+	mustCreate(t, db, &variant)
+
+	campaign := entity.GroupBuySession{
+		ProductVariantID:     variant.ID,
+		SellerID:             seller.ID,
+		MinParticipants:      2,
+		MaxParticipants:      maxParticipants,
+		Status:               "active",
+		MaxQuantity:          buyers,
+		MaxQuantityPerMember: 1,
+		ExpiresAt:            time.Now().Add(time.Hour),
+	}
+	mustCreate(t, db, &campaign)
+	tier := entity.GroupBuyTier{GroupBuySessionID: campaign.ID, ParticipantThreshold: 3, DiscountPercentage: 10}
+	mustCreate(t, db, &tier)
+
+	organizer := users[0]
+	buyerSession := entity.BuyerGroupSession{
+		GroupBuySessionID:   campaign.ID,
+		SessionCode:         "LBX" + suffix[len(suffix)-8:],
+		OrganizerUserID:     organizer.ID,
+		ProductVariantID:    variant.ID,
+		CurrentParticipants: 1,
+		Status:              "open",
+		ExpiresAt:           time.Now().Add(15 * time.Minute),
+	}
+	mustCreate(t, db, &buyerSession)
+	mustCreate(t, db, &entity.BuyerGroupMember{SessionID: buyerSession.ID, UserID: organizer.ID, Quantity: 1, Status: "joined"})
+
+	t.Cleanup(func() {
+		db.Where("session_id = ?", buyerSession.ID).Delete(&entity.BuyerGroupMember{})
+		db.Delete(&buyerSession)
+		db.Delete(&tier)
+		db.Delete(&campaign)
+		db.Delete(&variant)
+		db.Delete(&product)
+		db.Delete(&seller)
+		db.Delete(&users)
+	})
+
+	enqueuer := &recordingEnqueuer{}
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	groupBuy := usecase.NewGroupBuyUsecase(
+		pg.NewAddressRepositoryPg(db), pg.NewGroupBuySessionRepositoryPg(db), pg.NewGroupBuyTierRepositoryPg(db),
+		pg.NewProductRepositoryPg(db), pg.NewProductVariantRepositoryPg(db), pg.NewBuyerGroupBuySessionRepositoryPg(db),
+		pg.NewBuyerGroupMemberRepositoryPg(db), pg.NewOrderRepositoryPg(db), pg.NewProductVariantStockRepositoryPg(db),
+		pg.NewGroupBuyScheduleRepositoryPg(db), pg.NewGroupBuyReminderRepositoryPg(db), pg.NewTxManager(db),
+		log, enqueuer, silentNotifier{}, silentEvents{}, nil, nil,
+	)
+
+	joiners := append([]entity.User{users[1]}, users[1:]...)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	full := 0
+	for _, user := range joiners {
+		wg.Add(1)
+		go func(userID int64) {
+			defer wg.Done()
+			err := groupBuy.JoinSession(ctx, buyerSession.SessionCode, userID, 1, "")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, errorx.ErrSessionFull):
+				full++
+			case err != nil:
+				t.Errorf("user %d failed to join: %v", userID, err)
+			}
+		}(user.ID)
+	}
+	wg.Wait()
+
+	var members int64
+	db.Model(&entity.BuyerGroupMember{}).Where("session_id = ?", buyerSession.ID).Count(&members)
+	var after entity.BuyerGroupSession
+	db.First(&after, "id = ?", buyerSession.ID)
+
+	if members != maxParticipants || after.CurrentParticipants != maxParticipants {
+		t.Fatalf("expected %d members, got %d rows and a count of %d", maxParticipants, members, after.CurrentParticipants)
+	}
+	if after.Status != "locked" {
+		t.Fatalf("expected the full session to be locked, got %s", after.Status)
+	}
+	if full < buyers-(maxParticipants-1) {
+		t.Fatalf("expected at least %d joins to be refused as full, got %d", buyers-(maxParticipants-1), full)
+	}
+	// the join that left the session one short of the tier and the one that reached it
+	if len(enqueuer.tasks) != 2 {
+		t.Fatalf("expected 2 milestone tasks, got %v", enqueuer.tasks)
+	}
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value any) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("failed to seed %T: %v", value, err)
+	}
+}
@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/febry3/gamingin/internal/entity"
+)
+
+func TestBuyerSessionHasMember(t *testing.T) {
+	session := entity.BuyerGroupSession{
+		Members: []entity.BuyerGroupMember{{UserID: 1}, {UserID: 2}},
+	}
+
+	if !session.HasMember(2) {
+		t.Fatal("expected user 2 to be a member")
+	}
+	if session.HasMember(3) {
+		t.Fatal("expected user 3 not to be a member")
+	}
+}
+
+func TestBuyerSessionIsFull(t *testing.T) {
+	cases := []struct {
+		participants int
+		full         bool
+	}{
+		{participants: 4, full: false},
+		{participants: 5, full: true},
+		{participants: 6, full: true},
+	}
+
+	for _, c := range cases {
+		session := entity.BuyerGroupSession{CurrentParticipants: c.participants}
+		if got := session.IsFull(5); got != c.full {
+			t.Errorf("%d of 5 participants: expected full %v, got %v", c.participants, c.full, got)
+		}
+	}
+}
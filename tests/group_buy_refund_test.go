@@ -0,0 +1,245 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/repository/pg"
+	"github.com/febry3/gamingin/internal/usecase"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestEndingFailedCampaignRefundsAndReturnsStock needs a Postgres database in
+// TEST_DATABASE_URL, CI provides one. A buyer session got a tier payout to the wallet
+// and then missed the campaign minimum, ending the campaign refunds the rest of what
+// each member paid and gives the reservation back. Ending it again changes nothing.
+func TestEndingFailedCampaignRefundsAndReturnsStock(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if err := db.AutoMigrate(&entity.User{}, &entity.Address{}, &entity.Seller{}, &entity.Product{}, &entity.ProductVariant{}, &entity.ProductVariantStock{}, &entity.Category{}, &entity.ProductImage{}, &entity.GroupBuySession{}, &entity.GroupBuyTier{}, &entity.BuyerGroupSession{}, &entity.BuyerGroupMember{}, &entity.Order{}, &entity.Payment{}, &entity.OrderAdjustment{}, &entity.UserWallet{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	ctx := context.Background()
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	users := make([]entity.User, 3)
+	for i := range users {
+		users[i] = entity.User{Email: fmt.Sprintf("refund-%s-%d@test.local", suffix, i)}
+	}
+	mustCreate(t, db, &users)
+	buyerA, buyerB := users[1], users[2]
+
+	seller := entity.Seller{UserID: users[0].ID, StoreName: "Refund Test", StoreSlug: "refund-test-" + suffix}
+	mustCreate(t, db, &seller)
+	product := entity.Product{SellerID: seller.ID, Title: "Refund Test", Slug: "refund-test-" + suffix}
+	mustCreate(t, db, &product)
+	variant := entity.ProductVariant{ProductID: product.ID, Sku: "REFUND-" + suffix, Name: "Refund Test", Price: 100000}
+	mustCreate(t, db, &variant)
+
+	const startCurrent, startReserved = 20, 0
+	mustCreate(t, db, &entity.ProductVariantStock{ProductVariantID: variant.ID, CurrentStock: startCurrent, ReservedStock: startReserved})
+
+	campaign := entity.GroupBuySession{
+		ProductVariantID:     variant.ID,
+		SellerID:             seller.ID,
+		MinParticipants:      3,
+		MaxParticipants:      5,
+		Status:               "active",
+		MaxQuantity:          5,
+		MaxQuantityPerMember: 2,
+		ExpiresAt:            time.Now().Add(-time.Minute),
+	}
+	mustCreate(t, db, &campaign)
+	tiers := []entity.GroupBuyTier{
+		{GroupBuySessionID: campaign.ID, ParticipantThreshold: 1, DiscountPercentage: 5},
+		{GroupBuySessionID: campaign.ID, ParticipantThreshold: 2, DiscountPercentage: 10},
+	}
+	mustCreate(t, db, &tiers)
+
+	buyerSession := entity.BuyerGroupSession{
+		GroupBuySessionID:   campaign.ID,
+		SessionCode:         "RFD" + suffix[len(suffix)-8:],
+		OrganizerUserID:     buyerA.ID,
+		ProductVariantID:    variant.ID,
+		CurrentParticipants: 2,
+		Status:              "expired",
+		ExpiresAt:           time.Now().Add(-time.Minute),
+	}
+	mustCreate(t, db, &buyerSession)
+	mustCreate(t, db, &[]entity.BuyerGroupMember{
+		{SessionID: buyerSession.ID, UserID: buyerA.ID, Quantity: 2, Status: "paid"},
+		{SessionID: buyerSession.ID, UserID: buyerB.ID, Quantity: 1, Status: "paid"},
+	})
+
+	// A ordered alone at the 5% tier, B joined and ordered at the 10% tier
+	orders := []entity.Order{
+		{OrderNumber: "ORD-RFD-A-" + suffix, UserID: buyerA.ID, BuyerGroupSessionID: &buyerSession.ID, SellerID: seller.ID, ProductVariantID: variant.ID, Quantity: 2, PriceAtOrder: 100000, Subtotal: 200000, DiscountAmount: 10000, TotalAmount: 190000, Status: entity.OrderStatusPaid, AddressID: uuid.NewString()},
+		{OrderNumber: "ORD-RFD-B-" + suffix, UserID: buyerB.ID, BuyerGroupSessionID: &buyerSession.ID, SellerID: seller.ID, ProductVariantID: variant.ID, Quantity: 1, PriceAtOrder: 100000, Subtotal: 100000, DiscountAmount: 10000, TotalAmount: 90000, Status: entity.OrderStatusPaid, AddressID: uuid.NewString()},
+	}
+	mustCreate(t, db, &orders)
+	mustCreate(t, db, &[]entity.OrderAdjustment{
+		{OrderID: orders[0].ID, Description: "Group buy discount 5% at 1 participants", Amount: -10000, SourceType: entity.AdjustmentSourceGroupBuy, SourceID: tiers[0].ID},
+		{OrderID: orders[1].ID, Description: "Group buy discount 10% at 2 participants", Amount: -10000, SourceType: entity.AdjustmentSourceGroupBuy, SourceID: tiers[1].ID},
+	})
+
+	t.Cleanup(func() {
+		for _, order := range orders {
+			db.Where("order_id = ?", order.ID).Delete(&entity.OrderAdjustment{})
+		}
+		db.Delete(&orders)
+		db.Where("session_id = ?", buyerSession.ID).Delete(&entity.BuyerGroupMember{})
+		db.Delete(&buyerSession)
+		db.Delete(&tiers)
+		db.Delete(&campaign)
+		db.Where("product_variant_id = ?", variant.ID).Delete(&entity.ProductVariantStock{})
+		db.Delete(&variant)
+		db.Delete(&product)
+		db.Delete(&seller)
+		db.Where("user_id IN ?", []int64{buyerA.ID, buyerB.ID}).Delete(&entity.UserWallet{})
+		db.Delete(&users)
+	})
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	enqueuer := &recordingEnqueuer{}
+	stockRepo := pg.NewProductVariantStockRepositoryPg(db)
+	adjustmentRepo := pg.NewOrderAdjustmentRepositoryPg(db)
+	walletRepo := pg.NewUserWalletRepositoryPg(db)
+	orderUsecase := usecase.NewOrderUsecase(
+		pg.NewOrderRepositoryPg(db), pg.NewPaymentRepositoryPg(db), nil, nil, pg.NewProductVariantRepositoryPg(db), stockRepo,
+		pg.NewBuyerGroupBuySessionRepositoryPg(db), pg.NewBuyerGroupMemberRepositoryPg(db), nil, nil, nil, adjustmentRepo,
+		pg.NewGroupBuyTierRepositoryPg(db), walletRepo, silentNotifier{}, silentEvents{},
+		&stubGateway{statuses: map[string]string{}}, pg.NewTxManager(db), enqueuer, log,
+	)
+	groupBuy := usecase.NewGroupBuyUsecase(
+		pg.NewAddressRepositoryPg(db), pg.NewGroupBuySessionRepositoryPg(db), pg.NewGroupBuyTierRepositoryPg(db),
+		pg.NewProductRepositoryPg(db), pg.NewProductVariantRepositoryPg(db), pg.NewBuyerGroupBuySessionRepositoryPg(db),
+		pg.NewBuyerGroupMemberRepositoryPg(db), pg.NewOrderRepositoryPg(db), stockRepo,
+		pg.NewGroupBuyScheduleRepositoryPg(db), pg.NewGroupBuyReminderRepositoryPg(db), pg.NewTxManager(db),
+		log, enqueuer, silentNotifier{}, silentEvents{}, orderUsecase, nil,
+	)
+
+	// the campaign went active and both orders were paid
+	if reserved, err := stockRepo.ReserveStock(ctx, variant.ID, int(campaign.MaxQuantity)); err != nil || !reserved {
+		t.Fatalf("failed to reserve the campaign quantity: reserved=%v err=%v", reserved, err)
+	}
+	for _, order := range orders {
+		stock, err := stockRepo.GetStockByVariantID(ctx, variant.ID)
+		if err != nil {
+			t.Fatalf("failed to read stock: %v", err)
+		}
+		if err := stockRepo.DeductStockWithVersion(ctx, variant.ID, order.Quantity, stock.Version); err != nil {
+			t.Fatalf("failed to take the paid units: %v", err)
+		}
+	}
+
+	// the buyer session closed at 2 participants, A is owed the other 5% of the 10% tier
+	if err := orderUsecase.SettleGroupBuyDiscounts(ctx, buyerSession.ID); err != nil {
+		t.Fatalf("settle: %v", err)
+	}
+	if balance, _ := walletRepo.GetUserBalanceByUserID(ctx, buyerA.ID); balance != 10000 {
+		t.Fatalf("A's settled discount = %.0f, want 10000", balance)
+	}
+	if balance, _ := walletRepo.GetUserBalanceByUserID(ctx, buyerB.ID); balance != 0 {
+		t.Fatalf("B already got the 10%% tier, wallet = %.0f", balance)
+	}
+
+	wantRefund := make(map[int64]float64)
+	wallets := make(map[int64]float64)
+	for _, order := range orders {
+		granted, err := adjustmentRepo.SumByOrderID(ctx, order.ID, entity.AdjustmentSourceGroupBuy)
+		if err != nil {
+			t.Fatalf("failed to sum adjustments: %v", err)
+		}
+		wantRefund[order.UserID] = order.TotalAmount + order.DiscountAmount + granted
+		wallets[order.UserID], _ = walletRepo.GetUserBalanceByUserID(ctx, order.UserID)
+	}
+
+	endCampaign := func() {
+		t.Helper()
+		if err := groupBuy.EndSession(ctx, campaign.ID, variant.ID, seller.ID); err != nil {
+			t.Fatalf("end session: %v", err)
+		}
+	}
+	endCampaign()
+
+	for _, order := range orders {
+		balance, _ := walletRepo.GetUserBalanceByUserID(ctx, order.UserID)
+		if refund := balance - wallets[order.UserID]; refund != wantRefund[order.UserID] {
+			t.Fatalf("order %s refunded %.0f, want %.0f", order.OrderNumber, refund, wantRefund[order.UserID])
+		}
+		// the payout and the refund add up to what the member paid
+		if balance != order.TotalAmount {
+			t.Fatalf("user %d holds %.0f after the refund, paid %.0f", order.UserID, balance, order.TotalAmount)
+		}
+	}
+
+	assertEnded := func() {
+		t.Helper()
+		var ended entity.GroupBuySession
+		db.First(&ended, "id = ?", campaign.ID)
+		var closed entity.BuyerGroupSession
+		db.First(&closed, "id = ?", buyerSession.ID)
+		if ended.Status != "completed" || closed.Status != "cancelled" {
+			t.Fatalf("campaign %s / buyer session %s, want completed / cancelled", ended.Status, closed.Status)
+		}
+
+		var refunded []entity.Order
+		db.Where("buyer_group_session_id = ?", buyerSession.ID).Find(&refunded)
+		for _, order := range refunded {
+			if order.Status != entity.OrderStatusRefunded {
+				t.Fatalf("order %s is %s, want %s", order.OrderNumber, order.Status, entity.OrderStatusRefunded)
+			}
+		}
+
+		stock, err := stockRepo.GetStockByVariantID(ctx, variant.ID)
+		if err != nil {
+			t.Fatalf("failed to read stock: %v", err)
+		}
+		if stock.CurrentStock != startCurrent || stock.ReservedStock != startReserved {
+			t.Fatalf("stock current=%d reserved=%d, want %d and %d", stock.CurrentStock, stock.ReservedStock, startCurrent, startReserved)
+		}
+	}
+	assertEnded()
+
+	var adjustments int64
+	db.Model(&entity.OrderAdjustment{}).Where("order_id IN ?", []string{orders[0].ID, orders[1].ID}).Count(&adjustments)
+
+	// a retried end task, a late settlement and a repeated refund find nothing left to do
+	endCampaign()
+	if err := orderUsecase.SettleGroupBuyDiscounts(ctx, buyerSession.ID); err != nil {
+		t.Fatalf("second settle: %v", err)
+	}
+	if err := orderUsecase.RefundGroupBuyOrders(ctx, buyerSession.ID); err != nil {
+		t.Fatalf("second refund: %v", err)
+	}
+
+	assertEnded()
+	for _, order := range orders {
+		if balance, _ := walletRepo.GetUserBalanceByUserID(ctx, order.UserID); balance != order.TotalAmount {
+			t.Fatalf("user %d holds %.0f after running again, want %.0f", order.UserID, balance, order.TotalAmount)
+		}
+	}
+	var after int64
+	db.Model(&entity.OrderAdjustment{}).Where("order_id IN ?", []string{orders[0].ID, orders[1].ID}).Count(&after)
+	if after != adjustments {
+		t.Fatalf("running again added %d adjustments", after-adjustments)
+	}
+}
@@ -0,0 +1,29 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/febry3/gamingin/internal/infra/invite"
+)
+
+func TestInviteToken(t *testing.T) {
+	signer := invite.NewSigner([]byte("secret"), "https://shop.example")
+	expiresAt := time.Unix(1_000, 0)
+	token := signer.Token("LBX1a2b3c4d", 42, expiresAt)
+
+	referrer, err := signer.Verify("LBX1a2b3c4d", token, time.Unix(999, 0))
+	if err != nil || referrer != 42 {
+		t.Fatalf("expected referrer 42, got %d err %v", referrer, err)
+	}
+
+	if _, err := signer.Verify("LBXffffffff", token, time.Unix(999, 0)); err == nil {
+		t.Fatal("expected a token of another session to be refused")
+	}
+	if _, err := signer.Verify("LBX1a2b3c4d", token, expiresAt); err == nil {
+		t.Fatal("expected an expired token to be refused")
+	}
+	if _, err := signer.Verify("LBX1a2b3c4d", "7"+token[2:], time.Unix(999, 0)); err == nil {
+		t.Fatal("expected a token with a swapped referrer to be refused")
+	}
+}
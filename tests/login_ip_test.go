@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/febry3/gamingin/internal/config"
+	delivery "github.com/febry3/gamingin/internal/delivery/http"
+	"github.com/febry3/gamingin/internal/dto"
+	"github.com/febry3/gamingin/internal/usecase"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// recordingAuth keeps the login request the handler passed on
+type recordingAuth struct {
+	usecase.AuthUsecaseContract
+	request dto.LoginRequest
+}
+
+func (r *recordingAuth) Login(ctx context.Context, request dto.LoginRequest) (dto.LoginResponse, string, error) {
+	r.request = request
+	return dto.LoginResponse{}, "", nil
+}
+
+// TestLoginRecordsConnectionIP checks that a client cannot pick the IP its failed logins
+// are counted against by sending X-Forwarded-For
+func TestLoginRecordsConnectionIP(t *testing.T) {
+	v := viper.New()
+	v.Set("app.mode", "test")
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	auth := &recordingAuth{}
+	app := config.NewGin(v, log)
+	app.POST("/login", delivery.NewAuthHandler(auth, log, nil).Login)
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"buyer@test.local","password":"secret123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", "198.51.100.9")
+	req.RemoteAddr = "203.0.113.7:1234"
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if auth.request.IPAddress != "203.0.113.7" {
+		t.Fatalf("expected the connection IP to be recorded, got %q", auth.request.IPAddress)
+	}
+}
@@ -0,0 +1,585 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/febry3/gamingin/internal/dto"
+	"github.com/febry3/gamingin/internal/entity"
+	"github.com/febry3/gamingin/internal/infra/payment"
+	"github.com/febry3/gamingin/internal/repository"
+	"github.com/febry3/gamingin/internal/usecase"
+	"github.com/febry3/gamingin/internal/worker/tasks"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// paymentStore holds the rows of the payment flow. The fake repositories hand out copies,
+// so like with the database a change only sticks once it is saved.
+type paymentStore struct {
+	mu            sync.Mutex
+	orders        map[string]entity.Order
+	payments      map[string]entity.Payment // keyed by order id
+	events        map[string]entity.PaymentEvent
+	discrepancies []entity.PaymentDiscrepancy
+	stock         entity.ProductVariantStock
+	deductions    int
+}
+
+func (s *paymentStore) withOrder(p entity.Payment) *entity.Payment {
+	order := s.orders[p.OrderID]
+	p.Order = &order
+	return &p
+}
+
+type storeOrderRepo struct {
+	repository.OrderRepository
+	s *paymentStore
+}
+
+func (r storeOrderRepo) FindByID(ctx context.Context, orderID string) (*entity.Order, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	order, ok := r.s.orders[orderID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if p, ok := r.s.payments[orderID]; ok {
+		order.Payment = &p
+	}
+	return &order, nil
+}
+
+func (r storeOrderRepo) FindByIDForUpdate(ctx context.Context, orderID string) (*entity.Order, error) {
+	return r.FindByID(ctx, orderID)
+}
+
+func (r storeOrderRepo) UpdateStatus(ctx context.Context, orderID, status string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	order := r.s.orders[orderID]
+	order.Status = status
+	order.UpdatedAt = time.Now()
+	r.s.orders[orderID] = order
+	return nil
+}
+
+type storePaymentRepo struct {
+	repository.PaymentRepository
+	s *paymentStore
+}
+
+func (r storePaymentRepo) Create(ctx context.Context, p *entity.Payment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if p.ID == "" {
+		p.ID = uuid.NewString()
+	}
+	stored := *p
+	stored.Order = nil
+	stored.UpdatedAt = time.Now()
+	r.s.payments[p.OrderID] = stored
+	return nil
+}
+
+func (r storePaymentRepo) FindByOrderID(ctx context.Context, orderID string) (*entity.Payment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p, ok := r.s.payments[orderID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &p, nil
+}
+
+func (r storePaymentRepo) FindByOrderIDForUpdate(ctx context.Context, orderID string) (*entity.Payment, error) {
+	return r.FindByOrderID(ctx, orderID)
+}
+
+func (r storePaymentRepo) FindByGatewayOrderID(ctx context.Context, gatewayOrderID string) (*entity.Payment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, p := range r.s.payments {
+		if p.GatewayOrderID == gatewayOrderID {
+			return r.s.withOrder(p), nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r storePaymentRepo) FindPending(ctx context.Context) ([]entity.Payment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var pending []entity.Payment
+	for _, p := range r.s.payments {
+		if p.Status == entity.PaymentStatusPending {
+			pending = append(pending, *r.s.withOrder(p))
+		}
+	}
+	return pending, nil
+}
+
+func (r storePaymentRepo) FindClosedSince(ctx context.Context, since time.Time) ([]entity.Payment, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var closed []entity.Payment
+	for _, p := range r.s.payments {
+		if p.Status != entity.PaymentStatusPending && p.UpdatedAt.After(since) {
+			closed = append(closed, *r.s.withOrder(p))
+		}
+	}
+	return closed, nil
+}
+
+func (r storePaymentRepo) Update(ctx context.Context, p *entity.Payment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stored := *p
+	stored.Order = nil
+	stored.UpdatedAt = time.Now()
+	r.s.payments[p.OrderID] = stored
+	return nil
+}
+
+func (r storePaymentRepo) UpdateStatus(ctx context.Context, paymentID, status string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for orderID, p := range r.s.payments {
+		if p.ID == paymentID {
+			p.Status = status
+			p.UpdatedAt = time.Now()
+			r.s.payments[orderID] = p
+		}
+	}
+	return nil
+}
+
+type storeEventRepo struct {
+	repository.PaymentEventRepository
+	s *paymentStore
+}
+
+func (r storeEventRepo) Create(ctx context.Context, event *entity.PaymentEvent) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, e := range r.s.events {
+		if e.TransactionID == event.TransactionID && e.TransactionStatus == event.TransactionStatus {
+			return false, nil
+		}
+	}
+	event.ID = uuid.NewString()
+	r.s.events[event.ID] = *event
+	return true, nil
+}
+
+func (r storeEventRepo) FindByID(ctx context.Context, eventID string) (*entity.PaymentEvent, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	e, ok := r.s.events[eventID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &e, nil
+}
+
+func (r storeEventRepo) FindByIDForUpdate(ctx context.Context, eventID string) (*entity.PaymentEvent, error) {
+	return r.FindByID(ctx, eventID)
+}
+
+func (r storeEventRepo) FindByTransaction(ctx context.Context, transactionID, transactionStatus string) (*entity.PaymentEvent, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, e := range r.s.events {
+		if e.TransactionID == transactionID && e.TransactionStatus == transactionStatus {
+			return &e, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r storeEventRepo) Update(ctx context.Context, event *entity.PaymentEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.events[event.ID] = *event
+	return nil
+}
+
+type storeDiscrepancyRepo struct {
+	repository.PaymentDiscrepancyRepository
+	s *paymentStore
+}
+
+// Create skips a payment and gateway status that is already stored, like the unique index does
+func (r storeDiscrepancyRepo) Create(ctx context.Context, discrepancy *entity.PaymentDiscrepancy) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, d := range r.s.discrepancies {
+		if d.PaymentID == discrepancy.PaymentID && d.GatewayStatus == discrepancy.GatewayStatus {
+			return nil
+		}
+	}
+	discrepancy.ID = uuid.NewString()
+	r.s.discrepancies = append(r.s.discrepancies, *discrepancy)
+	return nil
+}
+
+type storeStockRepo struct {
+	repository.ProductVariantStockRepository
+	s *paymentStore
+}
+
+func (r storeStockRepo) GetStockByVariantID(ctx context.Context, variantID string) (*entity.ProductVariantStock, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stock := r.s.stock
+	return &stock, nil
+}
+
+func (r storeStockRepo) UpdateStock(ctx context.Context, stock *entity.ProductVariantStock, variantID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.stock = *stock
+	return nil
+}
+
+func (r storeStockRepo) DeductStockWithVersion(ctx context.Context, variantID string, quantity int, expectedVersion int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.stock.Version != expectedVersion {
+		return errors.New("version conflict")
+	}
+	r.s.stock.CurrentStock -= quantity
+	r.s.stock.ReservedStock -= quantity
+	r.s.stock.Version++
+	r.s.deductions++
+	return nil
+}
+
+// inlineTx runs the function without a transaction, the store applies every write at once
+type inlineTx struct{}
+
+func (inlineTx) WithTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return fn(ctx)
+}
+
+// stubGateway answers with the status set for each gateway order id and remembers the
+// charges it was asked to cancel. An order id without a status is unknown to the gateway.
+type stubGateway struct {
+	mu        sync.Mutex
+	statuses  map[string]string
+	charges   int
+	cancelled []string
+}
+
+func (g *stubGateway) ChargeVA(ctx context.Context, orderID string, amount int64, bankCode string, expiresAt *time.Time) (*payment.VAPaymentResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.charges++
+	g.statuses[orderID] = entity.PaymentStatusPending
+	return &payment.VAPaymentResult{
+		TransactionID: "tx-" + orderID,
+		OrderID:       orderID,
+		Bank:          bankCode,
+		VANumber:      fmt.Sprintf("8808%08d", g.charges),
+		GrossAmount:   float64(amount),
+		Status:        entity.PaymentStatusPending,
+	}, nil
+}
+
+func (g *stubGateway) GetTransactionStatus(ctx context.Context, orderID string) (*payment.PaymentStatusResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	status, ok := g.statuses[orderID]
+	if !ok {
+		return nil, payment.ErrTransactionNotFound
+	}
+	return &payment.PaymentStatusResult{TransactionID: "tx-" + orderID, OrderID: orderID, Status: status}, nil
+}
+
+func (g *stubGateway) VerifySignature(orderID, statusCode, grossAmount, signatureKey string) bool {
+	return true
+}
+
+func (g *stubGateway) CancelTransaction(ctx context.Context, orderID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cancelled = append(g.cancelled, orderID)
+	g.statuses[orderID] = entity.PaymentStatusCancel
+	return nil
+}
+
+func (g *stubGateway) setStatus(orderID, status string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if status == "" {
+		delete(g.statuses, orderID)
+		return
+	}
+	g.statuses[orderID] = status
+}
+
+// taskQueue keeps the enqueued tasks so a test can run them in place of the worker
+type taskQueue struct {
+	mu    sync.Mutex
+	tasks []*asynq.Task
+}
+
+func (q *taskQueue) Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.tasks = append(q.tasks, task)
+	return &asynq.TaskInfo{Type: task.Type()}, nil
+}
+
+func (q *taskQueue) drain() []*asynq.Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	queued := q.tasks
+	q.tasks = nil
+	return queued
+}
+
+type paymentPipeline struct {
+	store   *paymentStore
+	gateway *stubGateway
+	queue   *taskQueue
+	orders  usecase.OrderUsecaseContract
+	order   entity.Order
+}
+
+// newPaymentPipeline seeds a direct order of two units waiting on its first VA
+func newPaymentPipeline(t *testing.T) *paymentPipeline {
+	t.Helper()
+
+	variantID := uuid.NewString()
+	order := entity.Order{
+		ID:               uuid.NewString(),
+		OrderNumber:      "ORD-TEST-" + uuid.NewString()[:8],
+		UserID:           7,
+		SellerID:         3,
+		ProductVariantID: variantID,
+		Quantity:         2,
+		PriceAtOrder:     75000,
+		Subtotal:         150000,
+		TotalAmount:      150000,
+		Status:           entity.OrderStatusPendingPayment,
+		CreatedAt:        time.Now(),
+	}
+
+	store := &paymentStore{
+		orders: map[string]entity.Order{order.ID: order},
+		payments: map[string]entity.Payment{order.ID: {
+			ID:                   uuid.NewString(),
+			OrderID:              order.ID,
+			Amount:               order.TotalAmount,
+			Status:               entity.PaymentStatusPending,
+			BankCode:             "bca",
+			VANumber:             "880800000000",
+			GatewayTransactionID: "tx-" + order.OrderNumber,
+			GatewayOrderID:       order.OrderNumber,
+			ExpiredAt:            order.CreatedAt.Add(5 * time.Minute),
+			UpdatedAt:            order.CreatedAt,
+		}},
+		events: map[string]entity.PaymentEvent{},
+		stock:  entity.ProductVariantStock{ProductVariantID: variantID, CurrentStock: 10, ReservedStock: 2, Version: 1},
+	}
+	gateway := &stubGateway{statuses: map[string]string{order.OrderNumber: entity.PaymentStatusPending}}
+	queue := &taskQueue{}
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	orders := usecase.NewOrderUsecase(
+		storeOrderRepo{s: store}, storePaymentRepo{s: store}, nil, nil, nil, storeStockRepo{s: store},
+		nil, nil, nil, storeDiscrepancyRepo{s: store}, storeEventRepo{s: store}, nil, nil, nil,
+		silentNotifier{}, silentEvents{}, gateway, inlineTx{}, queue, log,
+	)
+
+	return &paymentPipeline{store: store, gateway: gateway, queue: queue, orders: orders, order: order}
+}
+
+func (p *paymentPipeline) notify(t *testing.T, gatewayOrderID, status string) {
+	t.Helper()
+	err := p.orders.HandlePaymentNotification(context.Background(), &dto.MidtransNotification{
+		TransactionID:     "tx-" + gatewayOrderID,
+		TransactionStatus: status,
+		OrderID:           gatewayOrderID,
+		StatusCode:        "200",
+		GrossAmount:       "150000.00",
+		SignatureKey:      "signed",
+	})
+	if err != nil {
+		t.Fatalf("notification %s for %s: %v", status, gatewayOrderID, err)
+	}
+}
+
+// runQueued processes every queued payment event the way the worker would and
+// returns how many tasks it ran
+func (p *paymentPipeline) runQueued(t *testing.T) int {
+	t.Helper()
+	queued := p.queue.drain()
+	for _, task := range queued {
+		var payload tasks.PaymentEventPayload
+		if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+			t.Fatalf("bad %s payload: %v", task.Type(), err)
+		}
+		if err := p.orders.ProcessPaymentEvent(context.Background(), payload.EventID); err != nil {
+			t.Fatalf("processing event %s: %v", payload.EventID, err)
+		}
+	}
+	return len(queued)
+}
+
+func (p *paymentPipeline) current() (entity.Order, entity.Payment, entity.ProductVariantStock) {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	return p.store.orders[p.order.ID], p.store.payments[p.order.ID], p.store.stock
+}
+
+// TestDuplicatePaymentNotificationIsAppliedOnce delivers the same settlement before and
+// after it was processed, the stock is deducted only for the first one.
+func TestDuplicatePaymentNotificationIsAppliedOnce(t *testing.T) {
+	p := newPaymentPipeline(t)
+
+	p.notify(t, p.order.OrderNumber, entity.PaymentStatusSettlement)
+	p.notify(t, p.order.OrderNumber, entity.PaymentStatusSettlement)
+	if ran := p.runQueued(t); ran != 2 {
+		t.Fatalf("expected both deliveries to be queued before processing, got %d", ran)
+	}
+
+	p.notify(t, p.order.OrderNumber, entity.PaymentStatusSettlement)
+	if ran := p.runQueued(t); ran != 0 {
+		t.Fatalf("a processed notification was queued again %d times", ran)
+	}
+
+	if len(p.store.events) != 1 {
+		t.Fatalf("expected one stored event, got %d", len(p.store.events))
+	}
+	for id, event := range p.store.events {
+		if event.Status != entity.PaymentEventStatusProcessed {
+			t.Fatalf("event status = %s, want %s", event.Status, entity.PaymentEventStatusProcessed)
+		}
+		if err := p.orders.ProcessPaymentEvent(context.Background(), id); err != nil {
+			t.Fatalf("reprocessing event: %v", err)
+		}
+	}
+
+	order, paid, stock := p.current()
+	if order.Status != entity.OrderStatusPaid || paid.Status != entity.PaymentStatusSettlement {
+		t.Fatalf("order %s / payment %s, want paid / settlement", order.Status, paid.Status)
+	}
+	if p.store.deductions != 1 {
+		t.Fatalf("stock deducted %d times, want once", p.store.deductions)
+	}
+	if stock.CurrentStock != 8 || stock.ReservedStock != 0 {
+		t.Fatalf("stock current=%d reserved=%d, want 8 and 0", stock.CurrentStock, stock.ReservedStock)
+	}
+}
+
+// TestLateSettlementOnExpiredOrderReportsOneDiscrepancy pays the VA right after the order
+// expired. The settlement must not reopen the order and every reconciliation run reports
+// the same single mismatch.
+func TestLateSettlementOnExpiredOrderReportsOneDiscrepancy(t *testing.T) {
+	p := newPaymentPipeline(t)
+	ctx := context.Background()
+
+	if err := p.orders.ExpireOrder(ctx, p.order.ID); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	p.gateway.setStatus(p.order.OrderNumber, entity.PaymentStatusSettlement)
+
+	p.notify(t, p.order.OrderNumber, entity.PaymentStatusSettlement)
+	p.runQueued(t)
+
+	for i := 0; i < 2; i++ {
+		if err := p.orders.ReconcilePayments(ctx); err != nil {
+			t.Fatalf("reconcile run %d: %v", i+1, err)
+		}
+	}
+
+	order, expired, stock := p.current()
+	if order.Status != entity.OrderStatusExpired || expired.Status != entity.PaymentStatusExpire {
+		t.Fatalf("order %s / payment %s, want expired / expire", order.Status, expired.Status)
+	}
+	if p.store.deductions != 0 || stock.CurrentStock != 10 || stock.ReservedStock != 0 {
+		t.Fatalf("stock changed by the late settlement: deductions=%d current=%d reserved=%d", p.store.deductions, stock.CurrentStock, stock.ReservedStock)
+	}
+	for _, event := range p.store.events {
+		if event.Status != entity.PaymentEventStatusIgnored {
+			t.Fatalf("late settlement event is %s, want %s", event.Status, entity.PaymentEventStatusIgnored)
+		}
+	}
+
+	if len(p.store.discrepancies) != 1 {
+		t.Fatalf("expected one discrepancy, got %d", len(p.store.discrepancies))
+	}
+	d := p.store.discrepancies[0]
+	if d.PaymentID != expired.ID || d.GatewayStatus != entity.PaymentStatusSettlement || d.OrderStatus != entity.OrderStatusExpired {
+		t.Fatalf("unexpected discrepancy %+v", d)
+	}
+}
+
+// TestRegeneratePaymentCancelsPreviousCharge issues a new VA and checks the old charge is
+// cancelled at the gateway without its cancel notification closing the order.
+func TestRegeneratePaymentCancelsPreviousCharge(t *testing.T) {
+	p := newPaymentPipeline(t)
+	previous := p.order.OrderNumber
+
+	resp, err := p.orders.RegeneratePayment(context.Background(), p.order.UserID, p.order.ID, &dto.RegeneratePaymentRequest{BankCode: "bni"})
+	if err != nil {
+		t.Fatalf("regenerate: %v", err)
+	}
+
+	if len(p.gateway.cancelled) != 1 || p.gateway.cancelled[0] != previous {
+		t.Fatalf("cancelled charges %v, want [%s]", p.gateway.cancelled, previous)
+	}
+
+	_, regenerated, _ := p.current()
+	if regenerated.GatewayOrderID == previous || regenerated.BankCode != "bni" || regenerated.Status != entity.PaymentStatusPending {
+		t.Fatalf("payment not moved to the new charge: %+v", regenerated)
+	}
+	if resp.Payment == nil || resp.Payment.VANumber != regenerated.VANumber {
+		t.Fatalf("response does not show the new VA")
+	}
+
+	p.notify(t, previous, entity.PaymentStatusCancel)
+	p.runQueued(t)
+
+	order, _, stock := p.current()
+	if order.Status != entity.OrderStatusPendingPayment {
+		t.Fatalf("cancel of the old charge moved the order to %s", order.Status)
+	}
+	if stock.ReservedStock != 2 {
+		t.Fatalf("reserved stock = %d, want the reservation kept", stock.ReservedStock)
+	}
+}
+
+// TestExpireOrderClosesOrderUnknownToGateway expires an order whose charge the gateway has
+// no transaction for. It is closed as unpaid and there is nothing to cancel.
+func TestExpireOrderClosesOrderUnknownToGateway(t *testing.T) {
+	p := newPaymentPipeline(t)
+	p.gateway.setStatus(p.order.OrderNumber, "")
+
+	if err := p.orders.ExpireOrder(context.Background(), p.order.ID); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+
+	order, expired, stock := p.current()
+	if order.Status != entity.OrderStatusExpired || expired.Status != entity.PaymentStatusExpire {
+		t.Fatalf("order %s / payment %s, want expired / expire", order.Status, expired.Status)
+	}
+	if stock.ReservedStock != 0 || stock.CurrentStock != 10 {
+		t.Fatalf("stock current=%d reserved=%d, want 10 and 0", stock.CurrentStock, stock.ReservedStock)
+	}
+	if len(p.gateway.cancelled) != 0 {
+		t.Fatalf("cancelled %v at the gateway, want nothing", p.gateway.cancelled)
+	}
+}
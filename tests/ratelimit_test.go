@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/febry3/gamingin/internal/config"
+	"github.com/febry3/gamingin/internal/delivery/http/middleware"
+	"github.com/febry3/gamingin/internal/infra/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	policy := ratelimit.Policy{Name: "test", Limit: 2, Window: time.Minute, KeyBy: ratelimit.KeyByIP}
+	app.GET("/limited", middleware.RateLimitMiddleware(ratelimit.NewMemoryLimiter(), policy), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := request("10.0.0.1"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := request("10.0.0.1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+	if rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("expected RateLimit-Remaining 0, got %q", rec.Header().Get("RateLimit-Remaining"))
+	}
+
+	if rec := request("10.0.0.2"); rec.Code != http.StatusOK {
+		t.Fatalf("other IP: expected 200, got %d", rec.Code)
+	}
+}
+
+// limitedApp builds the real engine with trustedProxies and a route limited to two
+// requests per IP
+func limitedApp(trustedProxies string) *gin.Engine {
+	v := viper.New()
+	v.Set("app.mode", "test")
+	v.Set("app.trusted_proxies", trustedProxies)
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	app := config.NewGin(v, log)
+	policy := ratelimit.Policy{Name: "test", Limit: 2, Window: time.Minute, KeyBy: ratelimit.KeyByIP}
+	app.GET("/limited", middleware.RateLimitMiddleware(ratelimit.NewMemoryLimiter(), policy), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return app
+}
+
+func forwardedRequest(app *gin.Engine, remoteIP, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+	req.RemoteAddr = remoteIP + ":1234"
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	app := limitedApp("")
+
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		codes = append(codes, forwardedRequest(app, "203.0.113.7", fmt.Sprintf("198.51.100.%d", i+1)))
+	}
+
+	if codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("expected the third request to be limited despite a new X-Forwarded-For, got %v", codes)
+	}
+}
+
+func TestRateLimitUsesForwardedForFromTrustedProxy(t *testing.T) {
+	app := limitedApp("10.0.0.0/8")
+
+	for i := 0; i < 2; i++ {
+		if code := forwardedRequest(app, "10.0.0.5", "198.51.100.1"); code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, code)
+		}
+	}
+	if code := forwardedRequest(app, "10.0.0.5", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", code)
+	}
+	// another client behind the same proxy has its own bucket
+	if code := forwardedRequest(app, "10.0.0.5", "198.51.100.2"); code != http.StatusOK {
+		t.Fatalf("other client: expected 200, got %d", code)
+	}
+}
+
+func TestMemoryLimiterSlidingWindow(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	window := 50 * time.Millisecond
+
+	if res, _ := limiter.Allow(t.Context(), "k", 1, window); !res.Allowed {
+		t.Fatal("first request should be allowed")
+	}
+	if res, _ := limiter.Allow(t.Context(), "k", 1, window); res.Allowed {
+		t.Fatal("second request should be rejected")
+	}
+
+	time.Sleep(window)
+	if res, _ := limiter.Allow(t.Context(), "k", 1, window); !res.Allowed {
+		t.Fatal("request after the window should be allowed")
+	}
+}
@@ -0,0 +1,23 @@
+package tests
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/febry3/gamingin/internal/helpers"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 SHA1 test secret, the 6 digit code at t=59 is 287082
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	step, ok := helpers.ValidateTOTP(secret, "287082", time.Unix(59, 0))
+	if !ok || step != 1 {
+		t.Fatalf("expected the RFC code to match step 1, got step %d ok %v", step, ok)
+	}
+
+	if _, ok := helpers.ValidateTOTP(secret, "287082", time.Unix(59+10*30, 0)); ok {
+		t.Fatal("expected a code from ten periods ago to be refused")
+	}
+}